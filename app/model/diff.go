@@ -0,0 +1,125 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+type AppChange int
+
+const (
+	CHANGE_NONE = iota
+	CHANGE_NEW
+	CHANGE_REMOVED
+	CHANGE_MODIFIED
+)
+
+// const table - change names, keep in sync with CHANGE_xxx constants above
+func getChangeNames() []string {
+	return []string{"-", "New", "Removed", "Changed"}
+}
+
+func (c AppChange) String() string {
+	return getChangeNames()[c]
+}
+
+func (c AppChange) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+type AppFieldChange struct {
+	Field  string `yaml:"field"`
+	Before string `yaml:"before"`
+	After  string `yaml:"after"`
+}
+
+// AppDelta describes how an application changed compared to a baseline (previous) run.
+// Numeric deltas are current minus baseline; they are 0 for new and removed apps.
+type AppDelta struct {
+	Change          AppChange        `yaml:"change"`
+	EfficiencyRate  *int             `yaml:"efficiency_rate"`  // percentage points, nil if unknown in either run
+	ReliabilityRisk int              `yaml:"reliability_risk"` // risk levels, positive is riskier; 0 if unknown in either run
+	Replicas        float64          `yaml:"replicas"`         // average replicas
+	CpuRequest      float64          `yaml:"cpu_request"`      // main container, in cores
+	MemoryRequest   float64          `yaml:"memory_request"`   // main container, in bytes
+	MonthlyCost     float64          `yaml:"monthly_cost"`     // all replicas
+	Fields          []AppFieldChange `yaml:"fields"`           // human-readable list of changed values
+}
+
+func mainContainerRequests(app *App) (cpu float64, mem float64) {
+	if c := app.MainContainerInfo(); c != nil {
+		cpu, mem = c.Cpu.Request, c.Memory.Request
+	}
+	return
+}
+
+func violationsString(violations []PolicyViolation) string {
+	if len(violations) == 0 {
+		return "none"
+	}
+	policies := make([]string, len(violations))
+	for i, v := range violations {
+		policies[i] = v.Policy
+	}
+	return strings.Join(policies, ",")
+}
+
+// DiffApps compares an app from the current run with the same app from the baseline run.
+// Either app may be nil, indicating a new (no baseline) or removed (no current) app.
+func DiffApps(current *App, baseline *App) AppDelta {
+	if current == nil && baseline == nil {
+		return AppDelta{Change: CHANGE_NONE}
+	}
+	if baseline == nil {
+		return AppDelta{Change: CHANGE_NEW}
+	}
+	if current == nil {
+		return AppDelta{Change: CHANGE_REMOVED}
+	}
+
+	var d AppDelta
+
+	// numeric deltas
+	if current.Analysis.EfficiencyRate != nil && baseline.Analysis.EfficiencyRate != nil {
+		rate := *current.Analysis.EfficiencyRate - *baseline.Analysis.EfficiencyRate
+		d.EfficiencyRate = &rate
+	}
+	curRisk, baseRisk := current.Analysis.ReliabilityRisk.SafeRiskLevel(), baseline.Analysis.ReliabilityRisk.SafeRiskLevel()
+	if curRisk != RISK_UNKNOWN && baseRisk != RISK_UNKNOWN {
+		d.ReliabilityRisk = int(curRisk) - int(baseRisk)
+	}
+	d.Replicas = current.Metrics.AverageReplicas - baseline.Metrics.AverageReplicas
+	curCpu, curMem := mainContainerRequests(current)
+	baseCpu, baseMem := mainContainerRequests(baseline)
+	d.CpuRequest = curCpu - baseCpu
+	d.MemoryRequest = curMem - baseMem
+	d.MonthlyCost = current.MonthlyCost() - baseline.MonthlyCost()
+
+	// compare values as displayed, so that insignificant float differences don't register as changes
+	compare := func(field string, before string, after string) {
+		if before != after {
+			d.Fields = append(d.Fields, AppFieldChange{field, before, after})
+		}
+	}
+	compare("Efficiency Rate", Rate2String(baseline.Analysis.EfficiencyRate), Rate2String(current.Analysis.EfficiencyRate))
+	compare("Reliability Risk", Risk2String(baseline.Analysis.ReliabilityRisk), Risk2String(current.Analysis.ReliabilityRisk))
+	compare("Analysis", baseline.Analysis.Conclusion.String(), current.Analysis.Conclusion.String())
+	compare("Pattern", baseline.Analysis.Pattern.String(), current.Analysis.Pattern.String())
+	compare("Replicas", fmt.Sprintf("%.1f", baseline.Metrics.AverageReplicas), fmt.Sprintf("%.1f", current.Metrics.AverageReplicas))
+	compare("CPU Request", fmt.Sprintf("%.3g", baseCpu), fmt.Sprintf("%.3g", curCpu))
+	compare("Memory Request", fmt.Sprintf("%.0fMi", baseMem/(1024*1024)), fmt.Sprintf("%.0fMi", curMem/(1024*1024)))
+	compare("Monthly Cost", fmt.Sprintf("$%.2f", baseline.MonthlyCost()), fmt.Sprintf("$%.2f", current.MonthlyCost()))
+	compare("Policy Violations", violationsString(baseline.Analysis.Violations), violationsString(current.Analysis.Violations))
+
+	if len(d.Fields) > 0 {
+		d.Change = CHANGE_MODIFIED
+	} else {
+		d.Change = CHANGE_NONE
+	}
+	return d
+}
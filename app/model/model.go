@@ -1,6 +1,13 @@
 package model
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
 
 const (
 	QOS_GUARANTEED = "guaranteed"
@@ -9,24 +16,28 @@ const (
 )
 
 type AppMetadata struct {
-	//Cluster string // needed?
+	Cluster string // optional cluster name, as configured by the user
 	//Name               string
 	Namespace          string
 	Workload           string
 	WorkloadKind       string
 	WorkloadApiVersion string
+	NamespaceLabels    map[string]string `yaml:"namespace_labels,omitempty"` // labels of the workload's namespace
+	Annotations        map[string]string `yaml:"annotations,omitempty"`      // annotations of the workload (names as sanitized by kube-state-metrics)
 	//Labels []string  // needed?
 }
 
 type AppSettings struct {
-	Replicas        int    `yaml:"-"`
-	HpaEnabled      bool   `yaml:"-"`
-	VpaEnabled      bool   `yaml:"-"`
-	MpaEnabled      bool   `yaml:"-"`
-	HpaMinReplicas  int    `yaml:"-"`
-	HpaMaxReplicas  int    `yaml:"-"`
-	WriteableVolume bool   `yaml:"writeable_volume"`
-	QosClass        string `yaml:"qos_class"`
+	Replicas        int     `yaml:"-"`
+	HpaEnabled      bool    `yaml:"-"`
+	VpaEnabled      bool    `yaml:"-"`
+	MpaEnabled      bool    `yaml:"-"`
+	HpaName         string  `yaml:"-"`
+	HpaMinReplicas  int     `yaml:"-"`
+	HpaMaxReplicas  int     `yaml:"-"`
+	HpaCpuTarget    float64 `yaml:"-"` // HPA target CPU utilization (%), 0 if not scaling on CPU utilization
+	WriteableVolume bool    `yaml:"writeable_volume"`
+	QosClass        string  `yaml:"qos_class"`
 	// TODO: consider adding replicas stats (min/max/avg/median)
 }
 
@@ -39,8 +50,11 @@ type AppContainerResourceInfo struct {
 }
 
 type AppContainer struct {
-	Name string `yaml:"name"`
-	Cpu  struct {
+	Name    string   `yaml:"name"`
+	Image   string   `yaml:"image,omitempty"`
+	Sidecar string   `yaml:"sidecar,omitempty"` // known sidecar the container runs, if any (e.g., istio-proxy)
+	Ports   []string `yaml:"ports,omitempty"`   // ports of the container targeted by Prometheus scrapes, by endpoint name
+	Cpu     struct {
 		AppContainerResourceInfo `yaml:"resource"`
 		SecondsThrottled         float64 `yaml:"seconds_throttled"` // average rate across instances/time
 		Shares                   float64 `yaml:"shares"`            // alt source for Cpu.Request, in CPU shares (1000-1024 per core)
@@ -48,8 +62,12 @@ type AppContainer struct {
 	Memory struct {
 		AppContainerResourceInfo `yaml:"resource"`
 	} `yaml:"memory"`
-	RestartCount float64 `yaml:"restart_count"` // yaml: don't omit empty, since 0 is a valid value
-	PseudoCost   float64 `yaml:"pseudo_cost"`
+	EphemeralStorage struct {
+		AppContainerResourceInfo `yaml:"resource"`
+	} `yaml:"ephemeral_storage"`
+	RestartCount float64            `yaml:"restart_count"` // yaml: don't omit empty, since 0 is a valid value
+	PseudoCost   float64            `yaml:"pseudo_cost"`
+	Series       AppContainerSeries `yaml:"series,omitempty"`
 }
 
 type AppMetrics struct {
@@ -78,14 +96,25 @@ const (
 	F_MANY_REPLICAS
 )
 
+// const table - flag names, keep in sync with F_xxx constants above
+func getFlagNames() []string {
+	return []string{"C", "I", "W", "R", "L", "G", "U", "B", "T", "S", "M"}
+}
+
 func (f AppFlag) String() string {
-	return []string{"C", "I", "W", "R", "L", "G", "U", "B", "T", "S", "M"}[f]
+	return getFlagNames()[f]
 }
 
 func (f AppFlag) MarshalYAML() (interface{}, error) {
 	return f.String(), nil
 }
 
+func (f *AppFlag) UnmarshalYAML(value *yaml.Node) error {
+	index, err := parseEnumName(value, getFlagNames(), "flag")
+	*f = AppFlag(index)
+	return err
+}
+
 type RiskLevel int
 
 const (
@@ -104,14 +133,25 @@ func (r *RiskLevel) SafeRiskLevel() RiskLevel {
 	return *r
 }
 
+// const table - risk level names, keep in sync with RISK_xxx constants above
+func getRiskLevelNames() []string {
+	return []string{"-", "None", "Low", "Medium", "High", "Critical"}
+}
+
 func (r RiskLevel) String() string {
-	return []string{"-", "None", "Low", "Medium", "High", "Critical"}[r]
+	return getRiskLevelNames()[r]
 }
 
 func (r RiskLevel) MarshalYAML() (interface{}, error) {
 	return r.String(), nil
 }
 
+func (r *RiskLevel) UnmarshalYAML(value *yaml.Node) error {
+	index, err := parseEnumName(value, getRiskLevelNames(), "risk level")
+	*r = RiskLevel(index)
+	return err
+}
+
 type AnalysisConclusion int
 
 const (
@@ -121,38 +161,174 @@ const (
 	CONCLUSION_OK
 )
 
+// const table - conclusion names, keep in sync with CONCLUSION_xxx constants above
+func getConclusionNames() []string {
+	return []string{"(insufficient data)", "Reliability Risk", "Excessive Cost", "Look good!"}
+}
+
 func (c AnalysisConclusion) String() string {
-	return []string{"(insufficient data)", "Reliability Risk", "Excessive Cost", "Look good!"}[c]
+	return getConclusionNames()[c]
 }
 
 func (c AnalysisConclusion) MarshalYAML() (interface{}, error) {
 	return c.String(), nil
 }
 
+func (c *AnalysisConclusion) UnmarshalYAML(value *yaml.Node) error {
+	index, err := parseEnumName(value, getConclusionNames(), "conclusion")
+	*c = AnalysisConclusion(index)
+	return err
+}
+
+type WorkloadPattern int
+
+const (
+	PATTERN_UNKNOWN = iota
+	PATTERN_STEADY
+	PATTERN_DIURNAL
+	PATTERN_WEEKLY
+	PATTERN_BURSTY
+	PATTERN_BATCH
+	PATTERN_IDLE
+)
+
+// const table - workload pattern names, keep in sync with PATTERN_xxx constants above
+func getWorkloadPatternNames() []string {
+	return []string{"-", "Steady", "Diurnal", "Weekly", "Bursty", "Batch", "Idle"}
+}
+
+func (p WorkloadPattern) String() string {
+	return getWorkloadPatternNames()[p]
+}
+
+func (p WorkloadPattern) MarshalYAML() (interface{}, error) {
+	return p.String(), nil
+}
+
+func (p *WorkloadPattern) UnmarshalYAML(value *yaml.Node) error {
+	index, err := parseEnumName(value, getWorkloadPatternNames(), "workload pattern")
+	*p = WorkloadPattern(index)
+	return err
+}
+
+// LoadImbalance measures how unevenly the load is spread across the app's pods (0 if not known)
+type LoadImbalance struct {
+	Pods                    int     `yaml:"pods"`                        // number of pods compared
+	CpuMaxMeanRatio         float64 `yaml:"cpu_max_mean_ratio"`          // busiest pod's CPU use relative to the average pod
+	CpuGini                 float64 `yaml:"cpu_gini"`                    // Gini coefficient of the pods' CPU use
+	RequestRateMaxMeanRatio float64 `yaml:"request_rate_max_mean_ratio"` // busiest pod's request rate relative to the average pod
+	RequestRateGini         float64 `yaml:"request_rate_gini"`           // Gini coefficient of the pods' request rates
+}
+
+// CapacityModel relates the main container's resource use per pod to the request rate per pod, fitted
+// over the analysis time range, and projects the replicas needed for a target request rate
+type CapacityModel struct {
+	Samples           int     `yaml:"samples"`             // number of time steps fitted
+	CpuPerRequest     float64 `yaml:"cpu_per_request"`     // cores per request/sec
+	CpuBaseline       float64 `yaml:"cpu_baseline"`        // cores used with no traffic
+	CpuFit            float64 `yaml:"cpu_fit"`             // R² of the CPU model (confidence), 0-1
+	MemoryPerRequest  float64 `yaml:"memory_per_request"`  // bytes per request/sec
+	MemoryBaseline    float64 `yaml:"memory_baseline"`     // bytes used with no traffic
+	MemoryFit         float64 `yaml:"memory_fit"`          // R² of the memory model (confidence), 0-1
+	TargetRequestRate float64 `yaml:"target_request_rate"` // projected request rate (per second, across all replicas)
+	TargetReplicas    int     `yaml:"target_replicas"`     // replicas needed at the target rate; 0 if larger requests are needed
+	TargetMonthlyCost float64 `yaml:"target_monthly_cost"` // monthly cost of the target replicas
+}
+
+// SidecarOverhead is the share of the app's cost taken by known sidecar containers (e.g., service mesh proxies)
+type SidecarOverhead struct {
+	Containers  []string `yaml:"containers"`   // sidecar containers
+	CostShare   float64  `yaml:"cost_share"`   // fraction of the pod's pseudo cost, 0-1
+	MonthlyCost float64  `yaml:"monthly_cost"` // monthly cost of the sidecars across replicas
+}
+
+// Autoscaling is the horizontal pod autoscaler settings recommended for the app, sized from the main
+// container's CPU demand across replicas over the analysis time range
+type Autoscaling struct {
+	MinReplicas  int      `yaml:"min_replicas"`   // replicas for the lowest demand
+	MaxReplicas  int      `yaml:"max_replicas"`   // replicas for the highest demand, with headroom
+	CpuTarget    float64  `yaml:"cpu_target"`     // target CPU utilization (% of the request)
+	MinCpuDemand float64  `yaml:"min_cpu_demand"` // lowest total CPU use across replicas, in cores
+	MaxCpuDemand float64  `yaml:"max_cpu_demand"` // highest total CPU use across replicas, in cores
+	Samples      int      `yaml:"samples"`        // number of time steps with both CPU use and replica count
+	Advice       []string `yaml:"-"`              // tuning advice for the app's existing HPA (also in the recommendations)
+}
+
+// ScoreContribution records how an analysis rule changed the app's rating and confidence
+type ScoreContribution struct {
+	Rule        string             `yaml:"rule"`             // rule identifier
+	Description string             `yaml:"description"`      // what the rule found
+	Rating      int                `yaml:"rating"`           // rating delta
+	Confidence  int                `yaml:"confidence"`       // confidence delta
+	Inputs      map[string]float64 `yaml:"inputs,omitempty"` // metric values the rule used
+}
+
+// PolicyViolation records an application's match of a policy rule defined in the config file
+type PolicyViolation struct {
+	Policy   string `yaml:"policy"`   // policy rule identifier
+	Message  string `yaml:"message"`  // what the policy requires
+	Severity string `yaml:"severity"` // info/warning/critical
+	Blocking bool   `yaml:"blocking"` // whether the violation blocks optimization
+}
+
 type AppAnalysis struct {
-	Rating          int                `yaml:"rating"`           // how suitable for optimization
-	Confidence      int                `yaml:"confidence"`       // how confident is the rating
-	MainContainer   string             `yaml:"main_container"`   // container to optimize or empty if not identified
-	EfficiencyRate  *int               `yaml:"efficiency_rate"`  // 0-100%
-	ReliabilityRisk *RiskLevel         `yaml:"reliability_risk"` // high/medium/low
-	Conclusion      AnalysisConclusion `yaml:"conclusion"`       // analysis conclusion
-	Flags           map[AppFlag]bool   `yaml:"flags"`            // flags
-	Opportunities   []string           `yaml:"opportunities"`    // list of optimization opportunities
-	Cautions        []string           `yaml:"cautions"`         // list of concerns/cautions
-	Blockers        []string           `yaml:"blockers"`         // list of blockers prevention optimization
-	Recommendations []string           `yaml:"recommendations"`  // list of recommendations for improvement
+	Profile         string              `yaml:"profile,omitempty"`           // analysis profile used
+	Rating          int                 `yaml:"rating"`                      // how suitable for optimization
+	Confidence      int                 `yaml:"confidence"`                  // how confident is the rating
+	MainContainer   string              `yaml:"main_container"`              // container to optimize or empty if not identified
+	MainContainerBy string              `yaml:"main_container_by,omitempty"` // why the main container was chosen (or not)
+	EfficiencyRate  *int                `yaml:"efficiency_rate"`             // 0-100%
+	ReliabilityRisk *RiskLevel          `yaml:"reliability_risk"`            // high/medium/low
+	Conclusion      AnalysisConclusion  `yaml:"conclusion"`                  // analysis conclusion
+	Pattern         WorkloadPattern     `yaml:"pattern"`                     // workload usage pattern
+	LoadImbalance   *LoadImbalance      `yaml:"load_imbalance,omitempty"`    // load spread across pods, if known
+	Capacity        *CapacityModel      `yaml:"capacity,omitempty"`          // resource use by request rate, if known
+	Autoscaling     *Autoscaling        `yaml:"autoscaling,omitempty"`       // recommended HPA settings, if known
+	Sidecars        *SidecarOverhead    `yaml:"sidecars,omitempty"`          // cost of known sidecars, if any
+	Flags           map[AppFlag]bool    `yaml:"flags"`                       // flags
+	Opportunities   []string            `yaml:"opportunities"`               // list of optimization opportunities
+	Cautions        []string            `yaml:"cautions"`                    // list of concerns/cautions
+	Blockers        []string            `yaml:"blockers"`                    // list of blockers prevention optimization
+	Recommendations []string            `yaml:"recommendations"`             // list of recommendations for improvement
+	Contributions   []ScoreContribution `yaml:"contributions,omitempty"`     // how the rating and confidence were arrived at
+	Violations      []PolicyViolation   `yaml:"policy_violations,omitempty"` // policy rules the app violates
+}
+
+type AppKey struct {
+	Namespace    string
+	Workload     string
+	WorkloadKind string
 }
 
 type App struct {
-	Metadata   AppMetadata    `yaml:"metadata"`
-	Settings   AppSettings    `yaml:"settings"`
-	Containers []AppContainer `yaml:"containers"`
-	Metrics    AppMetrics     `yaml:"metrics"`
-	Analysis   AppAnalysis    `yaml:"analysis"`
+	Metadata       AppMetadata    `yaml:"metadata"`
+	Settings       AppSettings    `yaml:"settings"`
+	Containers     []AppContainer `yaml:"containers"`
+	InitContainers []AppContainer `yaml:"init_containers,omitempty"` // run to completion before the containers start
+	Metrics        AppMetrics     `yaml:"metrics"`
+	Series         AppSeries      `yaml:"series,omitempty"`
+	Sources        []MetricSource `yaml:"sources,omitempty"` // how the metrics were obtained
+	Analysis       AppAnalysis    `yaml:"analysis"`
 }
 
 // Utility methods
 
+// HOURS_PER_MONTH converts the (hourly) container pseudo cost into a monthly estimate
+const HOURS_PER_MONTH = 730
+
+func parseEnumName(value *yaml.Node, names []string, label string) (int, error) {
+	for index, name := range names {
+		if strings.EqualFold(value.Value, name) {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognized %v %q (line %v)", label, value.Value, value.Line)
+}
+
+func (app *App) Key() AppKey {
+	return AppKey{app.Metadata.Namespace, app.Metadata.Workload, app.Metadata.WorkloadKind}
+}
+
 func (app *App) ContainerIndexByName(name string) (index int, ok bool) {
 	ok = false
 	for index = range app.Containers {
@@ -164,6 +340,99 @@ func (app *App) ContainerIndexByName(name string) (index int, ok bool) {
 	return
 }
 
+// MarshalJSON encodes the app using the same schema as the yaml encoding; non-finite values
+// (NaN, ±Inf), which JSON cannot represent, are encoded as null
+func (app App) MarshalJSON() ([]byte, error) {
+	buf, err := yaml.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(buf, &generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(finiteValues(generic))
+}
+
+// finiteValues replaces the non-finite floats in a generic (decoded) value with nil
+func finiteValues(v interface{}) interface{} {
+	switch value := v.(type) {
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil
+		}
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = finiteValues(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = finiteValues(item)
+		}
+	}
+	return v
+}
+
+// MainContainerInfo returns the main container, or nil if it is not identified
+func (app *App) MainContainerInfo() *AppContainer {
+	if app.Analysis.MainContainer == "" {
+		return nil
+	}
+	if index, ok := app.ContainerIndexByName(app.Analysis.MainContainer); ok {
+		return &app.Containers[index]
+	}
+	return nil
+}
+
+// PodPseudoCost returns the (hourly) pseudo cost of a single pod, across all its containers. Like the
+// containers' costs, it reflects the resources used (or allocated, without usage data) while the pod runs, so
+// init containers, which only run at pod start, are not included, even when they raise PodEffectiveRequests.
+func (app *App) PodPseudoCost() float64 {
+	cost := 0.0
+	for i := range app.Containers {
+		cost += app.Containers[i].PseudoCost
+	}
+	return cost
+}
+
+// ContainersRequests returns the total requests of the app's containers (excluding init containers) in a pod
+func (app *App) ContainersRequests() (cpu, memory, ephemeralStorage float64) {
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		cpu += c.Cpu.Request
+		memory += c.Memory.Request
+		ephemeralStorage += c.EphemeralStorage.Request
+	}
+	return cpu, memory, ephemeralStorage
+}
+
+// PodEffectiveRequests returns the requests the scheduler reserves for a single pod: for each resource, the
+// larger of the containers' total and the largest init container request (init containers run one at a time)
+func (app *App) PodEffectiveRequests() (cpu, memory, ephemeralStorage float64) {
+	cpu, memory, ephemeralStorage = app.ContainersRequests()
+	for i := range app.InitContainers {
+		c := &app.InitContainers[i]
+		cpu = math.Max(cpu, c.Cpu.Request)
+		memory = math.Max(memory, c.Memory.Request)
+		ephemeralStorage = math.Max(ephemeralStorage, c.EphemeralStorage.Request)
+	}
+	return cpu, memory, ephemeralStorage
+}
+
+// MonthlyCost estimates the monthly cost of all the application's replicas
+func (app *App) MonthlyCost() float64 {
+	return app.PodPseudoCost() * app.Metrics.AverageReplicas * HOURS_PER_MONTH
+}
+
+// MonthlySavingsEstimate estimates the monthly cost that optimization may save: the inefficient part of the
+// cost, i.e., the cost not covered by the efficiency rate. Zero if the efficiency rate is unknown.
+func (app *App) MonthlySavingsEstimate() float64 {
+	if app.Analysis.EfficiencyRate == nil {
+		return 0
+	}
+	return app.MonthlyCost() * float64(100-*app.Analysis.EfficiencyRate) / 100
+}
+
 func Rate2String(s *int) string {
 	if s == nil {
 		return "n/a"
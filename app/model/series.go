@@ -0,0 +1,46 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package model
+
+import "time"
+
+// Sample is a single data point of a metric's time series
+type Sample struct {
+	Time  time.Time `yaml:"t"`
+	Value float64   `yaml:"v"`
+}
+
+// TimeSeries is a metric's values over the analysis time range, in time order
+type TimeSeries []Sample
+
+// Values returns the series' values, without timestamps
+func (s TimeSeries) Values() []float64 {
+	values := make([]float64, len(s))
+	for i := range s {
+		values[i] = s[i].Value
+	}
+	return values
+}
+
+// AppSeries holds the raw pod-level time series collected for the app
+type AppSeries struct {
+	Replicas    TimeSeries `yaml:"replicas,omitempty"`
+	RequestRate TimeSeries `yaml:"request_rate,omitempty"` // approximated by the packet receive rate
+
+	// per-pod series are kept for the analysis only, not written out (one series per pod and metric)
+	RequestRateByPod map[string]TimeSeries `yaml:"-"` // by pod name
+}
+
+// AppContainerSeries holds the raw time series collected for a container (averaged across pods)
+type AppContainerSeries struct {
+	Cpu              TimeSeries `yaml:"cpu,omitempty"`               // usage, in cores
+	Memory           TimeSeries `yaml:"memory,omitempty"`            // usage, in bytes
+	EphemeralStorage TimeSeries `yaml:"ephemeral_storage,omitempty"` // usage, in bytes (fullest pod)
+
+	// by pod: for the analysis only, like AppSeries.RequestRateByPod
+	CpuByPod    map[string]TimeSeries `yaml:"-"` // usage, in cores, by pod name
+	MemoryByPod map[string]TimeSeries `yaml:"-"` // usage, in bytes, by pod name
+}
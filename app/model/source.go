@@ -0,0 +1,32 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package model
+
+import "time"
+
+// ValueStats holds the statistics of a metric's samples
+type ValueStats struct {
+	N       int     `yaml:"n"`
+	Min     float64 `yaml:"min"`
+	Max     float64 `yaml:"max"`
+	Sum     float64 `yaml:"sum"`
+	Average float64 `yaml:"average"`
+	Median  float64 `yaml:"median"`
+	StDev   float64 `yaml:"stdev"`
+}
+
+// MetricSource records how a metric was obtained, so that the values derived from it can be explained
+type MetricSource struct {
+	Metric    string        `yaml:"metric"`              // what was collected, e.g., "replica count"
+	Container string        `yaml:"container,omitempty"` // for container-level metrics, the container the stats are for
+	Query     string        `yaml:"query"`               // the query, as sent to the data source
+	Start     time.Time     `yaml:"start"`               // for instant queries, same as End
+	End       time.Time     `yaml:"end"`
+	Step      time.Duration `yaml:"step,omitempty"`     // zero for instant queries
+	Series    int           `yaml:"series"`             // number of series (or values, for instant queries) returned
+	Stats     *ValueStats   `yaml:"stats,omitempty"`    // statistics of the samples the value was computed from, if any
+	Warnings  []string      `yaml:"warnings,omitempty"` // warnings returned by the data source or from processing the samples
+}
@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"math"
 	"sort"
-	"strings"
 
 	"opsani-ignite/log"
 
@@ -17,49 +16,6 @@ import (
 	opsmath "opsani-ignite/math"
 )
 
-const (
-	CPU_WEIGHT = 0.6
-	MEM_WEIGHT = 0.4
-)
-
-type ResourceUtilizationRating struct {
-	UtilizationFloor float64
-	RatingBump       int
-}
-
-// const table
-func getResourceUtilizationRatingsTable() []ResourceUtilizationRating {
-	return []ResourceUtilizationRating{
-		{100, 30}, // >=100 provides opportunity to improve performance/rightsize
-		{80, 10},  // 80..100 likely not much room to optimize
-		{40, 40},  // 40..80 some optimization room
-		{1, 60},   // 1..40 likely lots to optimize
-		{0, 0},    // no utilization - likely can't optimize
-	}
-}
-
-func utilizationRating(v float64) int {
-	for _, r := range getResourceUtilizationRatingsTable() {
-		if v >= r.UtilizationFloor {
-			return r.RatingBump
-		}
-	}
-	return 0
-}
-
-func utilizationCombinedRating(cpuUtil, memUtil float64) int {
-	// convert resource utilization % to rating bump, for each resource separately
-	cpuBump, memBump := utilizationRating(cpuUtil), utilizationRating(memUtil)
-
-	// if rating is 0 for any resource, use 0
-	if cpuBump == 0 || memBump == 0 {
-		return 0
-	}
-
-	// average bump
-	return (cpuBump + memBump) / 2
-}
-
 // --- Container-level Analysis ----------------------------------------------
 
 func calcSaturation(r *appmodel.AppContainerResourceInfo, app *appmodel.App, container string, resource string) float64 {
@@ -103,6 +59,17 @@ func calcSaturation(r *appmodel.AppContainerResourceInfo, app *appmodel.App, con
 	return sat
 }
 
+// hourly pseudo cost of container resources
+const (
+	CPU_CORE_HOURLY_PSEUDO_COST   = 0.0175
+	MEMORY_GIB_HOURLY_PSEUDO_COST = 0.0125
+)
+
+// resourcesPseudoCost returns the hourly pseudo cost of the given CPU and memory
+func resourcesPseudoCost(cores float64, bytes float64) float64 {
+	return opsmath.MagicRound(cores*CPU_CORE_HOURLY_PSEUDO_COST + bytes/(1024*1024*1024)*MEMORY_GIB_HOURLY_PSEUDO_COST)
+}
+
 func containerResourceCostingValue(r *appmodel.AppContainerResourceInfo) float64 {
 	if r.Usage > 0 {
 		if r.Request > 0 && r.Usage > r.Request {
@@ -116,36 +83,9 @@ func containerResourceCostingValue(r *appmodel.AppContainerResourceInfo) float64
 	return r.Limit
 }
 
-func identifyMainContainer(app *appmodel.App) string {
-	// handle trivial cases
-	if len(app.Containers) < 1 {
-		return ""
-	}
-	if len(app.Containers) == 1 {
-		return app.Containers[0].Name
-	}
-
-	// use name-based heuristics
-	namesMap := make(map[string]bool)
-	for _, c := range app.Containers {
-		namesMap[c.Name] = true
-	}
-	if _, ok := namesMap["main"]; ok {
-		return "main"
-	}
-	if _, ok := namesMap[app.Metadata.Workload]; ok { // TODO: consider heuristic for xxx-deployment (extract xxx)
-		return app.Metadata.Workload
-	}
-
-	// use heuristics based on container's size/use
-	// nb: assume containers are already sorted by pseudo cost
-	if app.Containers[0].PseudoCost > app.Containers[1].PseudoCost {
-		return app.Containers[0].Name
-	}
-	// TODO: add more heuristics (e.g., based on cpu/mem, usage, etc.)
-
-	log.Warnf("Could not identify application's main container for %v", app)
-	return ""
+// containerPseudoCost returns the hourly pseudo cost of a container, based on its use (or allocation)
+func containerPseudoCost(c *appmodel.AppContainer) float64 {
+	return resourcesPseudoCost(containerResourceCostingValue(&c.Cpu.AppContainerResourceInfo), containerResourceCostingValue(&c.Memory.AppContainerResourceInfo))
 }
 
 func analyzeContainers(app *appmodel.App) {
@@ -159,19 +99,23 @@ func analyzeContainers(app *appmodel.App) {
 	// Calculate container pseudo cost
 	for i := range app.Containers {
 		c := &app.Containers[i]
-		cores := containerResourceCostingValue(&c.Cpu.AppContainerResourceInfo)
-		gib := float64(containerResourceCostingValue(&c.Memory.AppContainerResourceInfo)) / float64(1024*1024)
-		c.PseudoCost = opsmath.MagicRound(cores*0.0175 + gib*0.0125)
+		c.PseudoCost = containerPseudoCost(c)
+	}
+
+	// identify known sidecars
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		c.Sidecar = identifySidecar(app, c)
 	}
 
-	// sort containers info
-	sort.Slice(app.Containers, func(i, j int) bool {
-		return app.Containers[i].PseudoCost < app.Containers[i].PseudoCost
+	// sort containers info, most expensive first
+	sort.SliceStable(app.Containers, func(i, j int) bool {
+		return app.Containers[i].PseudoCost > app.Containers[j].PseudoCost
 	})
 
 	// identify main container (if possible)
 	if app.Analysis.MainContainer == "" {
-		app.Analysis.MainContainer = identifyMainContainer(app)
+		app.Analysis.MainContainer, app.Analysis.MainContainerBy = identifyMainContainer(app)
 	}
 
 	// identify QoS
@@ -185,14 +129,17 @@ func analyzeContainers(app *appmodel.App) {
 
 func computePodQoS(app *appmodel.App) string {
 	// following the rules at https://kubernetes.io/docs/tasks/configure-pod-container/quality-service-pod/
-	// note: ignoring the init containers
+	// note: init containers count like the other containers
 	// TODO: use other sources to get the QoS, use this one to (a) populate QoS if no QoS info found and
 	//       (b) validate the QoS found (e.g., degrade)
 
+	containers := make([]appmodel.AppContainer, 0, len(app.Containers)+len(app.InitContainers))
+	containers = append(append(containers, app.Containers...), app.InitContainers...)
+
 	allMatch := true  // assume all containers, all resources match the guaranteed requirements
 	oneMatch := false // track if at least one container, one resource has resources specified
-	for i := range app.Containers {
-		c := &app.Containers[i]
+	for i := range containers {
+		c := &containers[i]
 
 		if c.Cpu.Limit > 0 {
 			if c.Cpu.Request > 0 && c.Cpu.Request != c.Cpu.Limit {
@@ -343,37 +290,27 @@ func bumpRisk(prior *appmodel.RiskLevel, level appmodel.RiskLevel) *appmodel.Ris
 	return prior
 }
 
-func riskAssessment(app *appmodel.App) (*appmodel.RiskLevel, []string) {
-	var risk *appmodel.RiskLevel
-	msg := []string{}
-
-	if app.Settings.QosClass == appmodel.QOS_BESTEFFORT {
-		risk = bumpRisk(risk, appmodel.RISK_HIGH)
-		msg = append(msg, "Pod QoS class is Best Effort")
-	} else if app.Settings.QosClass != appmodel.QOS_GUARANTEED {
-		risk = bumpRisk(risk, appmodel.RISK_MEDIUM)
-		msg = append(msg, fmt.Sprintf("Pod QOS class is %v", strings.Title(app.Settings.QosClass)))
-	}
-
-	if app.Metrics.CpuUtilization >= 200 ||
-		app.Metrics.MemoryUtilization >= 200 ||
-		app.Metrics.CpuSecondsThrottled >= 0.7 {
-		risk = bumpRisk(risk, appmodel.RISK_HIGH)
-		msg = append(msg, "Resource utilization significantly exceeds allocation")
-	} else if app.Metrics.CpuUtilization > 120 ||
-		app.Metrics.MemoryUtilization > 120 ||
-		app.Metrics.CpuSecondsThrottled > 0.25 {
-		risk = bumpRisk(risk, appmodel.RISK_HIGH)
-		msg = append(msg, "Resource utilization exceeds allocation")
-	} else if app.Metrics.CpuUtilization > 90 ||
-		app.Metrics.MemoryUtilization > 90 ||
-		app.Metrics.CpuSecondsThrottled > 0.1 {
-		risk = bumpRisk(risk, appmodel.RISK_MEDIUM)
-		msg = append(msg, "Resource utilization close to allocation")
-	}
-
-	risk = bumpRisk(risk, appmodel.RISK_LOW) // in case not set yet
-	return risk, msg
+func boundInt(v, min, max int) int {
+	if v < min {
+		return min
+	} else if v > max {
+		return max
+	}
+	return v
+}
+
+// contribute applies a rule's rating and confidence deltas to the analysis, recording them so the final
+// rating can be explained
+func contribute(o *appmodel.AppAnalysis, rule, description string, rating, confidence int, inputs map[string]float64) {
+	o.Rating += rating
+	o.Confidence += confidence
+	o.Contributions = append(o.Contributions, appmodel.ScoreContribution{
+		Rule:        rule,
+		Description: description,
+		Rating:      rating,
+		Confidence:  confidence,
+		Inputs:      inputs,
+	})
 }
 
 func analyzeApp(app *appmodel.App) {
@@ -385,66 +322,29 @@ func analyzeApp(app *appmodel.App) {
 	if o.Flags == nil {
 		o.Flags = make(map[appmodel.AppFlag]bool)
 	}
-
-	// check main container
-	if app.Analysis.MainContainer != "" {
-		o.Flags[appmodel.F_MAIN_CONTAINER] = true
-	} else {
-		o.Blockers = append(o.Blockers, "Could not identify main container")
-		o.Flags[appmodel.F_MAIN_CONTAINER] = false
-	}
-
-	// mark multicontainer pods
-	if count := len(app.Containers); count > 0 {
-		o.Flags[appmodel.F_MULTI_CONTAINER] = count > 1 // flag not set if no container info
-	}
-
-	// having a writeable PVC disqualifies the app immediately (stateful)
-	if app.Settings.WriteableVolume {
-		o.Blockers = append(o.Blockers, "Stateful: pods have writeable volumes")
-		o.Flags[appmodel.F_WRITEABLE_VOLUME] = true
-	} else {
-		o.Flags[appmodel.F_WRITEABLE_VOLUME] = false
-	}
-
-	// resource specification flags
-	if app.Settings.QosClass == appmodel.QOS_GUARANTEED {
-		o.Flags[appmodel.F_RESOURCE_GUARANTEED] = true
-	} else {
-		o.Flags[appmodel.F_RESOURCE_GUARANTEED] = false
-	}
-	if resourcesLimited(app) {
-		o.Flags[appmodel.F_RESOURCE_LIMITS] = true
-	} else {
-		o.Flags[appmodel.F_RESOURCE_LIMITS] = false
-	}
-	if resGood, msg := resourcesExplicitlyDefined(app); resGood {
-		o.Flags[appmodel.F_RESOURCE_SPEC] = true
-	} else {
-		o.Flags[appmodel.F_RESOURCE_SPEC] = false
-		o.Blockers = append(o.Blockers, msg)
-		o.Recommendations = append(o.Recommendations, "Define resource levels to improve reliability")
-	}
-
-	// analyze resource utilization
-	o.Flags[appmodel.F_UTILIZATION] = app.Metrics.CpuUtilization > 0 && app.Metrics.MemoryUtilization > 0
-	utilBump := utilizationCombinedRating(app.Metrics.CpuUtilization, app.Metrics.MemoryUtilization)
-	if utilBump != 0 {
-		o.Rating += utilBump
-		o.Confidence += 30
-		if app.Metrics.CpuUtilization >= 100 || app.Metrics.MemoryUtilization >= 100 {
-			o.Opportunities = append(o.Opportunities, "Improve performance/reliability")
-			o.Flags[appmodel.F_BURST] = true
-		} else if utilBump >= 30 {
-			effImpr := efficiencyImprovementEstimate(app)
-			if effImpr != "" {
-				effImpr = " by " + effImpr
+	o.Contributions = nil
+
+	// select the analysis profile
+	profile := analysisProfiles.ForApp(app)
+	o.Profile = profile.Name
+
+	// evaluate the analysis rules (the reliability risk is reassessed by the rules)
+	o.ReliabilityRisk = nil
+	var policyApp map[string]interface{} // app input shared by the policy rules, prepared on first use
+	var policyErr error
+	for _, rule := range profile.rules.Enabled() {
+		policy, ok := rule.(*policyRule)
+		if !ok {
+			rule.Evaluate(app, &o)
+			continue
+		}
+		if policyApp == nil && policyErr == nil {
+			if policyApp, policyErr = policyAppInput(app); policyErr != nil {
+				log.Errorf("Failed to prepare app %v for policy rules: %v", app.Metadata, policyErr)
 			}
-			o.Opportunities = append(o.Opportunities, fmt.Sprintf("Improve efficiency%v", effImpr))
-			o.Flags[appmodel.F_BURST] = false
-		} else if utilBump == 0 {
-			o.Cautions = append(o.Cautions, "Idle application")
-			o.Flags[appmodel.F_BURST] = false
+		}
+		if policyErr == nil {
+			policy.evaluateInput(app, policyApp, &o)
 		}
 	}
 
@@ -459,74 +359,31 @@ func analyzeApp(app *appmodel.App) {
 		cpuSat := opsmath.Min(app.Metrics.CpuUtilization, 100)    // cap utilization for efficiency calc
 		memSat := opsmath.Min(app.Metrics.MemoryUtilization, 100) // " "
 		// rate can be assigned only if the app is not bursting
-		rate := int(math.Round(cpuSat*CPU_WEIGHT + memSat*MEM_WEIGHT))
+		rate := int(math.Round((cpuSat*profile.CpuWeight + memSat*profile.MemoryWeight) / (profile.CpuWeight + profile.MemoryWeight)))
 		o.EfficiencyRate = &rate
 	}
 
-	// analyze request rate
-	if app.Metrics.RequestRate == 0 {
-		o.Blockers = append(o.Blockers, "No requests are being processed")
-		o.Flags[appmodel.F_TRAFFIC] = false
-	} else if app.Metrics.RequestRate < 2 {
-		o.Cautions = append(o.Cautions, "Low request rate")
-		o.Rating -= 10
-		// note: don't set traffic flag
-	} else {
-		o.Flags[appmodel.F_TRAFFIC] = true
-		if app.Metrics.RequestRate > 100 {
-			o.Rating += 10 // low confidence as we don't know if traffic is served or originated
-		}
-	}
-
-	// analyze replica count
-	if app.Metrics.AverageReplicas <= 1 {
-		o.Rating -= 20
-		o.Confidence += 10
-		o.Cautions = append(o.Cautions, "Less than 2 replicas")
-		o.Flags[appmodel.F_SINGLE_REPLICA] = true
-		o.Flags[appmodel.F_MANY_REPLICAS] = false
-	} else if app.Metrics.AverageReplicas >= 7 {
-		o.Rating += 20
-		o.Confidence += 30
-		o.Flags[appmodel.F_SINGLE_REPLICA] = false
-		o.Flags[appmodel.F_MANY_REPLICAS] = true
-	} else {
-		if app.Metrics.AverageReplicas > 3 {
-			o.Rating += 10
-			o.Confidence += 10
-		}
-		o.Flags[appmodel.F_SINGLE_REPLICA] = false
-		o.Flags[appmodel.F_MANY_REPLICAS] = false
-	}
-
-	// perform risk assessment
-	var riskCautions []string
-	o.ReliabilityRisk, riskCautions = riskAssessment(app)
-	o.Cautions = append(o.Cautions, riskCautions...)
+	// finalize risk assessment
+	o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_LOW) // in case not set yet
 
 	// finalize blockers
 	if len(o.Blockers) > 0 {
-		o.Rating = -100
-		o.Confidence = 100
+		contribute(&o, "blockers", "Optimization blocked; rating set to -100, confidence to 100",
+			-100-o.Rating, 100-o.Confidence, map[string]float64{"blockers": float64(len(o.Blockers))})
 	}
 
 	// bound rating and confidence
-	if o.Rating < -100 {
-		o.Rating = -100
-	} else if o.Rating > 100 {
-		o.Rating = 100
-	}
-	if o.Confidence < 0 {
-		o.Confidence = 0
-	} else if o.Confidence > 100 {
-		o.Confidence = 100
+	rating, confidence := boundInt(o.Rating, -100, 100), boundInt(o.Confidence, 0, 100)
+	if rating != o.Rating || confidence != o.Confidence {
+		contribute(&o, "bounds", "Rating bounded to -100..100, confidence to 0..100",
+			rating-o.Rating, confidence-o.Confidence, nil)
 	}
 
 	// derive conclusion
 	o.Conclusion = appmodel.CONCLUSION_INSUFFICIENT_DATA
 	if o.ReliabilityRisk.SafeRiskLevel() >= appmodel.RISK_HIGH {
 		o.Conclusion = appmodel.CONCLUSION_RELIABILITY_RISK
-	} else if o.EfficiencyRate != nil && *o.EfficiencyRate < 60 {
+	} else if o.EfficiencyRate != nil && *o.EfficiencyRate < profile.ExcessiveCostRate {
 		o.Conclusion = appmodel.CONCLUSION_EXCESSIVE_COST
 	} else if o.ReliabilityRisk.SafeRiskLevel() >= appmodel.RISK_NONE && o.ReliabilityRisk.SafeRiskLevel() <= appmodel.RISK_LOW {
 		o.Conclusion = appmodel.CONCLUSION_OK
@@ -536,7 +393,7 @@ func analyzeApp(app *appmodel.App) {
 
 	// add recommendations
 	if !o.Flags[appmodel.F_WRITEABLE_VOLUME] { // if optimization not blocked (except by missing resource defs)
-		if o.EfficiencyRate != nil && *o.EfficiencyRate < 80 {
+		if o.EfficiencyRate != nil && *o.EfficiencyRate < profile.OptimizeRate {
 			o.Recommendations = append(o.Recommendations, "Optimize resource settings improve efficiency/reduce costs")
 		}
 		if o.ReliabilityRisk == nil || *o.ReliabilityRisk > appmodel.RISK_LOW {
@@ -0,0 +1,256 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+var baselineFile string
+
+// baseline comparison state; nil unless --baseline is specified
+var baselineDeltas map[appmodel.AppKey]*appmodel.AppDelta
+var baselineRemoved []*appmodel.App
+
+// loadAppsFile reads apps from a file produced by the yaml output format (a stream of yaml documents,
+// one per app). A single yaml or json list of apps (in the same schema) is also accepted.
+func loadAppsFile(path string) ([]*appmodel.App, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return loadApps(f)
+}
+
+func loadApps(r io.Reader) ([]*appmodel.App, error) {
+	apps := make([]*appmodel.App, 0)
+	decoder := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 {
+			continue // empty document
+		}
+		if doc.Content[0].Kind == yaml.SequenceNode {
+			var list []*appmodel.App
+			if err := doc.Decode(&list); err != nil {
+				return nil, err
+			}
+			apps = append(apps, list...)
+		} else {
+			app := &appmodel.App{}
+			if err := doc.Decode(app); err != nil {
+				return nil, err
+			}
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+// compareWithBaseline loads the baseline file and computes the deltas for the current and baseline apps
+func compareWithBaseline(apps []*appmodel.App, path string) error {
+	baseApps, err := loadAppsFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load baseline from %q: %v", path, err)
+	}
+	log.Infof("Loaded %v application(s) from baseline %q", len(baseApps), path)
+
+	// recompute the baseline costs from its resources, so that cost deltas reflect resource changes only and
+	// not a change in the costing (e.g., baselines saved before memory was priced per GiB rather than per MiB)
+	baseMap := make(map[appmodel.AppKey]*appmodel.App, len(baseApps))
+	for _, app := range baseApps {
+		for i := range app.Containers {
+			app.Containers[i].PseudoCost = containerPseudoCost(&app.Containers[i])
+		}
+		baseMap[app.Key()] = app
+	}
+
+	baselineDeltas = make(map[appmodel.AppKey]*appmodel.AppDelta, len(apps))
+	for _, app := range apps {
+		d := appmodel.DiffApps(app, baseMap[app.Key()])
+		baselineDeltas[app.Key()] = &d
+		delete(baseMap, app.Key())
+	}
+
+	// whatever is left in the baseline is no longer present (in baseline order)
+	baselineRemoved = make([]*appmodel.App, 0, len(baseMap))
+	for _, app := range baseApps {
+		if _, ok := baseMap[app.Key()]; !ok {
+			continue
+		}
+		d := appmodel.DiffApps(nil, app)
+		baselineDeltas[app.Key()] = &d
+		baselineRemoved = append(baselineRemoved, app)
+	}
+
+	return nil
+}
+
+// baselineShown indicates whether the current output format displays baseline deltas and removed apps
+func baselineShown() bool {
+	if baselineDeltas == nil {
+		return false
+	}
+	switch outputFormat {
+	case OUTPUT_INTERACTIVE, OUTPUT_TABLE, OUTPUT_DETAIL, OUTPUT_DIFF:
+		return true
+	}
+	return false
+}
+
+func appDelta(app *appmodel.App) *appmodel.AppDelta {
+	if baselineDeltas == nil {
+		return nil
+	}
+	return baselineDeltas[app.Key()]
+}
+
+// isRemovedApp indicates whether the app is only present in the baseline, i.e., it was removed since
+func isRemovedApp(app *appmodel.App) bool {
+	d := appDelta(app)
+	return d != nil && d.Change == appmodel.CHANGE_REMOVED
+}
+
+// --- Display helpers -------------------------------------------------------
+
+// byDelta sorts apps by a value of their delta; apps without a delta sort as if unchanged
+func byDelta(value func(d *appmodel.AppDelta) float64) func(a, b *appmodel.App) int {
+	return byNumber(func(app *appmodel.App) float64 {
+		if d := appDelta(app); d != nil {
+			return value(d)
+		}
+		return 0
+	})
+}
+
+func getDeltaHeadersInfo() []HeaderInfo {
+	return []HeaderInfo{
+		{"Change", alignLeft, byDelta(func(d *appmodel.AppDelta) float64 { return float64(d.Change) })},
+		{"Δ Efficiency\nRate", alignRight, byDelta(func(d *appmodel.AppDelta) float64 {
+			if d.EfficiencyRate == nil {
+				return 0
+			}
+			return float64(*d.EfficiencyRate)
+		})},
+		{"Δ Risk", alignRight, byDelta(func(d *appmodel.AppDelta) float64 { return float64(d.ReliabilityRisk) })},
+		{"Δ Replicas", alignRight, byDelta(func(d *appmodel.AppDelta) float64 { return d.Replicas })},
+		{"Δ CPU\nRequest", alignRight, byDelta(func(d *appmodel.AppDelta) float64 { return d.CpuRequest })},
+		{"Δ Mem\nRequest", alignRight, byDelta(func(d *appmodel.AppDelta) float64 { return d.MemoryRequest })},
+		{"Δ Monthly\nCost", alignRight, byDelta(func(d *appmodel.AppDelta) float64 { return d.MonthlyCost })},
+	}
+}
+
+func signedString(v float64, format string) string {
+	if fmt.Sprintf(format, v) == fmt.Sprintf(format, 0.0) {
+		return "" // no visible change
+	}
+	return fmt.Sprintf("%+"+format[1:], v)
+}
+
+// higher values are better if upGood, worse otherwise
+func deltaColor(v float64, upGood bool) int {
+	if v == 0 {
+		return colorNone
+	}
+	if (v > 0) == upGood {
+		return colorGreen
+	}
+	return colorYellow
+}
+
+type deltaCell struct {
+	Value string
+	Color int
+}
+
+// deltaCells returns the cells for the delta columns (see getDeltaHeadersInfo)
+func deltaCells(app *appmodel.App) []deltaCell {
+	d := appDelta(app)
+	cells := make([]deltaCell, len(getDeltaHeadersInfo()))
+	if d == nil {
+		return cells
+	}
+
+	changeColor := colorNone
+	switch d.Change {
+	case appmodel.CHANGE_NEW:
+		changeColor = colorCyan
+	case appmodel.CHANGE_REMOVED:
+		changeColor = colorOrange
+	}
+	cells[0] = deltaCell{d.Change.String(), changeColor}
+	if d.Change != appmodel.CHANGE_MODIFIED {
+		return cells
+	}
+
+	if d.EfficiencyRate != nil {
+		cells[1] = deltaCell{signedString(float64(*d.EfficiencyRate), "%.0f"), deltaColor(float64(*d.EfficiencyRate), true)}
+	}
+	riskColor := colorNone
+	if d.ReliabilityRisk > 0 {
+		riskColor = colorRed
+	} else if d.ReliabilityRisk < 0 {
+		riskColor = colorGreen
+	}
+	cells[2] = deltaCell{signedString(float64(d.ReliabilityRisk), "%.0f"), riskColor}
+	cells[3] = deltaCell{signedString(d.Replicas, "%.1f"), colorNone}
+	cells[4] = deltaCell{signedString(d.CpuRequest, "%.3f"), colorNone}
+	cells[5] = deltaCell{signedString(d.MemoryRequest/(1024*1024), "%.0f"), colorNone}
+	if cells[5].Value != "" {
+		cells[5].Value += "Mi"
+	}
+	cells[6] = deltaCell{signedString(d.MonthlyCost, "%.2f"), deltaColor(d.MonthlyCost, false)}
+
+	return cells
+}
+
+// --- Diff output format ----------------------------------------------------
+
+func (table *AppTable) outputDiffHeader() {
+	table.t.SetHeader([]string{"Namespace", "Deployment", "Change", "Field", "Before", "After"})
+	table.t.SetFooter([]string{})
+	table.t.SetCenterSeparator("")
+	table.t.SetColumnSeparator("")
+	table.t.SetRowSeparator("")
+	table.t.SetHeaderLine(false)
+	table.t.SetBorder(false)
+	table.t.SetAlignment(tablewriter.ALIGN_LEFT)
+}
+
+func (table *AppTable) outputDiffApp(app *appmodel.App) {
+	d := appDelta(app)
+	if d == nil || d.Change == appmodel.CHANGE_NONE {
+		return
+	}
+	if d.Change != appmodel.CHANGE_MODIFIED {
+		table.t.Append([]string{app.Metadata.Namespace, app.Metadata.Workload, d.Change.String(), "", "", ""})
+		return
+	}
+	for i, f := range d.Fields {
+		ns, name, change := app.Metadata.Namespace, app.Metadata.Workload, d.Change.String()
+		if i > 0 {
+			ns, name, change = "", "", "" // don't repeat the app for each field
+		}
+		table.t.Append([]string{ns, name, change, f.Field, f.Before, f.After})
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func baselineApp(namespace, workload string, replicas float64, cpuRequest float64) *appmodel.App {
+	app := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: namespace, Workload: workload, WorkloadKind: "Deployment"}}
+	c := appmodel.AppContainer{Name: workload}
+	c.Cpu.Request, c.Cpu.Limit = cpuRequest, cpuRequest
+	c.Memory.Request, c.Memory.Limit = 1024*1024*1024, 1024*1024*1024
+	c.PseudoCost = containerPseudoCost(&c)
+	app.Containers = []appmodel.AppContainer{c}
+	app.Analysis.MainContainer = workload
+	app.Metrics.AverageReplicas = replicas
+	return app
+}
+
+func TestDiffApps(t *testing.T) {
+	base := baselineApp("shop", "web", 2, 1)
+	if d := appmodel.DiffApps(base, nil); d.Change != appmodel.CHANGE_NEW || len(d.Fields) != 0 || d.MonthlyCost != 0 {
+		t.Errorf("expected a new app, got %+v", d)
+	}
+	if d := appmodel.DiffApps(nil, base); d.Change != appmodel.CHANGE_REMOVED || len(d.Fields) != 0 || d.MonthlyCost != 0 {
+		t.Errorf("expected a removed app, got %+v", d)
+	}
+	if d := appmodel.DiffApps(base, base); d.Change != appmodel.CHANGE_NONE || len(d.Fields) != 0 {
+		t.Errorf("expected no change, got %+v", d)
+	}
+
+	current := baselineApp("shop", "web", 3, 0.5)
+	d := appmodel.DiffApps(current, base)
+	if d.Change != appmodel.CHANGE_MODIFIED || d.Replicas != 1 || d.CpuRequest != -0.5 || d.MemoryRequest != 0 {
+		t.Errorf("unexpected delta %+v", d)
+	}
+	if cost := current.MonthlyCost() - base.MonthlyCost(); math.Abs(d.MonthlyCost-cost) > 1e-9 {
+		t.Errorf("expected cost delta %v, got %v", cost, d.MonthlyCost)
+	}
+	fields := make(map[string]bool)
+	for _, f := range d.Fields {
+		fields[f.Field] = true
+	}
+	for _, field := range []string{"Replicas", "CPU Request", "Monthly Cost"} {
+		if !fields[field] {
+			t.Errorf("expected %v to be listed as changed, got %+v", field, d.Fields)
+		}
+	}
+	if fields["Memory Request"] {
+		t.Errorf("expected unchanged memory request not to be listed, got %+v", d.Fields)
+	}
+}
+
+func TestCompareWithBaseline(t *testing.T) {
+	defer func() { baselineDeltas, baselineRemoved = nil, nil }()
+
+	// baseline saved by a release that priced memory per MiB: its costs are recomputed
+	web := baselineApp("shop", "web", 2, 1)
+	web.Containers[0].PseudoCost *= 1024
+	old := baselineApp("shop", "old", 1, 1)
+	buf, err := yaml.Marshal([]*appmodel.App{web, old})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "baseline.yaml")
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	current := baselineApp("shop", "web", 2, 1)
+	added := baselineApp("shop", "new", 1, 1)
+	if err := compareWithBaseline([]*appmodel.App{current, added}, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := baselineDeltas[current.Key()]; d == nil || d.Change != appmodel.CHANGE_NONE || d.MonthlyCost != 0 {
+		t.Errorf("expected the unchanged app to have no cost delta, got %+v", d)
+	}
+	if d := baselineDeltas[added.Key()]; d == nil || d.Change != appmodel.CHANGE_NEW {
+		t.Errorf("expected the added app to be new, got %+v", d)
+	}
+	if d := baselineDeltas[old.Key()]; d == nil || d.Change != appmodel.CHANGE_REMOVED {
+		t.Errorf("expected the missing app to be removed, got %+v", d)
+	}
+	if len(baselineRemoved) != 1 || baselineRemoved[0].Key() != old.Key() {
+		t.Errorf("expected shop/old to be listed as removed, got %v", baselineRemoved)
+	}
+
+	if err := compareWithBaseline(nil, filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing baseline file")
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+
+	appmodel "opsani-ignite/app/model"
+	opsmath "opsani-ignite/math"
+)
+
+// targetRequestRate is the request rate (per second, across all replicas) to project capacity for; 0 for
+// the traffic multiplier of the capacity-model rule applied to the current rate
+var targetRequestRate float64
+
+// alignSeries returns the values of the two series at the times present in both
+func alignSeries(a, b appmodel.TimeSeries) (x, y []float64) {
+	bValues := make(map[int64]float64, len(b))
+	for _, s := range b {
+		bValues[s.Time.Unix()] = s.Value
+	}
+	for _, s := range a {
+		if v, ok := bValues[s.Time.Unix()]; ok {
+			x = append(x, s.Value)
+			y = append(y, v)
+		}
+	}
+	return x, y
+}
+
+// fitOrZero returns the fit's slope, intercept and R², with 0 for values that could not be fitted
+func fitOrZero(fit opsmath.LinearFit) (slope, intercept, r2 float64) {
+	zero := func(v float64) float64 {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0
+		}
+		return v
+	}
+	return zero(fit.Slope), zero(fit.Intercept), zero(fit.R2)
+}
+
+// replicasFor returns the replicas needed to serve the total request rate with each pod using at most
+// allocation of a resource that grows by perRequest (per request/sec, per pod) over a baseline;
+// 0 if not possible at any replica count
+func replicasFor(rate float64, perRequest float64, baseline float64, allocation float64) int {
+	if perRequest <= 0 || allocation <= 0 {
+		return 1 // not limited by this resource
+	}
+	if allocation <= baseline {
+		return 0
+	}
+	return int(math.Max(1, math.Ceil(perRequest*rate/(allocation-baseline))))
+}
+
+type capacityModelRule struct {
+	MinSamples        int     `yaml:"min_samples"`        // minimum time steps with both traffic and usage to fit the model
+	MinTrafficCv      float64 `yaml:"min_traffic_cv"`     // minimum variation of traffic (coefficient of variation) to relate it to usage
+	MinFit            float64 `yaml:"min_fit"`            // R² below which CPU use is considered not to scale linearly with traffic
+	TargetUtilization float64 `yaml:"target_utilization"` // utilization (%) of the requests to size replicas for
+	TrafficMultiplier float64 `yaml:"traffic_multiplier"` // target request rate relative to the current one, unless --target-rps is set
+}
+
+func (r *capacityModelRule) Id() string             { return "capacity-model" }
+func (r *capacityModelRule) Category() string       { return RULE_CATEGORY_TRAFFIC }
+func (r *capacityModelRule) Severity() RuleSeverity { return SEVERITY_INFO }
+func (r *capacityModelRule) Description() string {
+	return "Relates resource use to the request rate and projects the replicas needed for a target rate"
+}
+
+func (r *capacityModelRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	index, ok := app.ContainerIndexByName(app.Analysis.MainContainer)
+	if !ok {
+		return
+	}
+	c := &app.Containers[index]
+	rps, cpu := alignSeries(app.Series.RequestRate, c.Series.Cpu)
+	if len(rps) < r.MinSamples || !(opsmath.CoefficientOfVariation(rps...) >= r.MinTrafficCv) {
+		return // not enough data, or traffic too flat to tell what it drives
+	}
+
+	model := &appmodel.CapacityModel{Samples: len(rps)}
+	model.CpuPerRequest, model.CpuBaseline, model.CpuFit = fitOrZero(opsmath.LinearRegression(rps, cpu))
+	if rpsMem, mem := alignSeries(app.Series.RequestRate, c.Series.Memory); len(rpsMem) >= r.MinSamples {
+		model.MemoryPerRequest, model.MemoryBaseline, model.MemoryFit = fitOrZero(opsmath.LinearRegression(rpsMem, mem))
+	}
+	o.Capacity = model
+
+	if model.CpuFit < r.MinFit {
+		o.Cautions = append(o.Cautions, fmt.Sprintf("CPU use does not scale linearly with traffic (R² %.2f); capacity projections are unreliable", model.CpuFit))
+		return
+	}
+
+	// project replicas and cost for the target rate (the model is per pod; the rate is across replicas)
+	model.TargetRequestRate = targetRequestRate
+	if model.TargetRequestRate <= 0 {
+		model.TargetRequestRate = opsmath.MagicRound(app.Metrics.RequestRate * app.Metrics.AverageReplicas * r.TrafficMultiplier)
+	}
+	utilization := r.TargetUtilization / 100
+	cpuAllocation := c.Cpu.Request
+	if cpuAllocation == 0 {
+		cpuAllocation = c.Cpu.Limit
+	}
+	memAllocation := c.Memory.Request
+	if memAllocation == 0 {
+		memAllocation = c.Memory.Limit
+	}
+	cpuReplicas := replicasFor(model.TargetRequestRate, model.CpuPerRequest, model.CpuBaseline, cpuAllocation*utilization)
+	memReplicas := 1
+	if model.MemoryFit >= r.MinFit {
+		memReplicas = replicasFor(model.TargetRequestRate, model.MemoryPerRequest, model.MemoryBaseline, memAllocation*utilization)
+	}
+	if cpuReplicas > 0 && memReplicas > 0 {
+		model.TargetReplicas = int(math.Max(float64(cpuReplicas), float64(memReplicas)))
+		model.TargetMonthlyCost = app.PodPseudoCost() * float64(model.TargetReplicas) * appmodel.HOURS_PER_MONTH
+	}
+}
+
+// capacityString describes the app's capacity model
+func capacityString(m *appmodel.CapacityModel) string {
+	if m == nil {
+		return "-"
+	}
+	s := fmt.Sprintf("CPU %.3g cores/req + %.3g cores (R² %.2f)", m.CpuPerRequest, m.CpuBaseline, m.CpuFit)
+	if m.MemoryFit > 0 {
+		s += fmt.Sprintf("; memory %v/req + %v (R² %.2f)", memoryString(m.MemoryPerRequest), memoryString(m.MemoryBaseline), m.MemoryFit)
+	}
+	return s + fmt.Sprintf(" over %v samples", m.Samples)
+}
+
+// whatIfString describes the capacity projection for the target request rate; empty if there is none
+func whatIfString(m *appmodel.CapacityModel) string {
+	if m == nil || m.TargetRequestRate == 0 {
+		return ""
+	}
+	if m.TargetReplicas == 0 {
+		return fmt.Sprintf("At %.4g req/sec: requests too small to serve the traffic at any replica count", m.TargetRequestRate)
+	}
+	return fmt.Sprintf("At %.4g req/sec: %v replicas, $%.2f/month", m.TargetRequestRate, m.TargetReplicas, m.TargetMonthlyCost)
+}
+
+func init() {
+	rootCmd.PersistentFlags().Float64Var(&targetRequestRate, "target-rps", 0, "Request rate (per second, across replicas) to project capacity for (default 2x the current rate)")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestCapacityModelRule(t *testing.T) {
+	rps := func(h int) float64 { return 12 + 5*math.Sin(2*math.Pi*float64(h)/24) + float64(h%7) }
+	linearCpu := func(h int) float64 { return 0.05 + 0.01*rps(h) }
+
+	tests := []struct {
+		name       string
+		rps        func(h int) float64
+		cpu        func(h int) float64
+		cpuRequest float64
+		target     float64
+		modeled    bool
+		cautions   int
+		replicas   int
+	}{
+		{"linear", rps, linearCpu, 1, 0, true, 0, 2},
+		{"linear, target rate", rps, linearCpu, 1, 650, true, 0, 10},
+		{"requests too small", rps, linearCpu, 0.06, 0, true, 0, 0},
+		{"not linear", rps, func(h int) float64 { return 0.2 + 0.01*float64(h*7919%11) }, 1, 0, true, 1, 0},
+		{"flat traffic", func(h int) float64 { return 12 }, linearCpu, 1, 0, false, 0, 0},
+	}
+	defer func() { targetRequestRate = 0 }()
+	for _, tt := range tests {
+		targetRequestRate = tt.target
+		c := containerWithResources("web", tt.cpuRequest, tt.cpuRequest, 256*1024*1024, 256*1024*1024)
+		c.PseudoCost = 0.03
+		c.Series.Cpu = hourlySeries(48, tt.cpu)
+		c.Series.Memory = hourlySeries(48, func(h int) float64 { return 200 * 1024 * 1024 })
+		app := &appmodel.App{
+			Containers: []appmodel.AppContainer{c},
+			Metrics:    appmodel.AppMetrics{AverageReplicas: 4, RequestRate: 12},
+			Series:     appmodel.AppSeries{RequestRate: hourlySeries(48, tt.rps)},
+			Analysis:   appmodel.AppAnalysis{MainContainer: "web"},
+		}
+		o := evaluateRule(t, "capacity-model", app)
+		if (o.Capacity != nil) != tt.modeled || len(o.Cautions) != tt.cautions {
+			t.Errorf("%v: expected modeled %v and %v caution(s), got %+v and %v", tt.name, tt.modeled, tt.cautions, o.Capacity, o.Cautions)
+			continue
+		}
+		if o.Capacity == nil {
+			continue
+		}
+		if o.Capacity.TargetReplicas != tt.replicas {
+			t.Errorf("%v: expected %v replicas, got %+v", tt.name, tt.replicas, o.Capacity)
+		}
+		if tt.cautions == 0 && (math.Abs(o.Capacity.CpuPerRequest-0.01) > 1e-9 || math.Abs(o.Capacity.CpuBaseline-0.05) > 1e-9 || o.Capacity.CpuFit < 0.99) {
+			t.Errorf("%v: expected 0.01 cores/req over 0.05 cores, got %+v", tt.name, o.Capacity)
+		}
+		if cost := 0.03 * float64(tt.replicas) * appmodel.HOURS_PER_MONTH; math.Abs(o.Capacity.TargetMonthlyCost-cost) > 1e-9 {
+			t.Errorf("%v: expected monthly cost %v, got %v", tt.name, cost, o.Capacity.TargetMonthlyCost)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	appmodel "opsani-ignite/app/model"
+	opsmath "opsani-ignite/math"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+const timeLabelFormat = "Jan 2 15:04"
+
+// finiteValues replaces missing (NaN) and infinite values with 0, so they can be charted
+func finiteValues(values []float64) []float64 {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// resample maps values onto n points, averaging when there are more values than points and
+// interpolating linearly when there are fewer
+func resample(values []float64, n int) []float64 {
+	out := make([]float64, n)
+	if len(values) == 0 || n == 0 {
+		return out
+	}
+	if len(values) == 1 || n == 1 {
+		for i := range out {
+			out[i] = opsmath.Avg(values...)
+		}
+		return out
+	}
+	if len(values) > n {
+		for i := range out {
+			from := i * len(values) / n
+			to := (i + 1) * len(values) / n
+			out[i] = opsmath.Avg(values[from:to]...)
+		}
+		return out
+	}
+	for i := range out {
+		pos := float64(i) * float64(len(values)-1) / float64(n-1)
+		lo := int(math.Floor(pos))
+		hi := int(math.Ceil(pos))
+		out[i] = values[lo] + (values[hi]-values[lo])*(pos-float64(lo))
+	}
+	return out
+}
+
+// sparkline draws values as a single line of block characters, at most width characters wide
+func sparkline(values []float64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+	values = finiteValues(values)
+	if len(values) > width {
+		values = resample(values, width)
+	}
+	min, max := opsmath.Min(values...), opsmath.Max(values...)
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if max > min {
+			level = int(math.Round((v - min) / (max - min) * float64(len(sparkBlocks)-1)))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// chartLine is a horizontal reference line overlaid on a chart (e.g., resource request or limit)
+type chartLine struct {
+	Value float64
+	Color string // tview color name
+}
+
+// brailleChart draws values as a line chart of braille characters, width x height characters, with
+// the reference lines overlaid as dotted lines; the chart's scale starts at 0 and ends at top.
+// Returns the chart's rows, top to bottom, with tview color tags
+func brailleChart(values []float64, lines []chartLine, width, height int, color string) (rows []string, top float64) {
+	if len(values) == 0 || width <= 0 || height <= 0 {
+		return nil, 0
+	}
+
+	values = finiteValues(values)
+
+	// determine the scale, so that the data and all reference lines fit
+	top = opsmath.Max(values...)
+	for _, l := range lines {
+		top = math.Max(top, l.Value)
+	}
+	if top <= 0 {
+		top = 1
+	}
+	dotsX, dotsY := width*2, height*4
+	dotRow := func(v float64) int {
+		y := int(math.Round((1 - v/top) * float64(dotsY-1)))
+		if y < 0 {
+			return 0
+		} else if y >= dotsY {
+			return dotsY - 1
+		}
+		return y
+	}
+
+	// braille dot bit for each (column, row) within a character cell
+	dotBits := [2][4]rune{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+	cells := make([][]rune, height)
+	colors := make([][]string, height) // color of the cell; data takes precedence over lines
+	for r := range cells {
+		cells[r] = make([]rune, width)
+		colors[r] = make([]string, width)
+	}
+	setDot := func(x, y int, c string, data bool) {
+		r, col := y/4, x/2
+		cells[r][col] |= dotBits[x%2][y%4]
+		if data || colors[r][col] == "" {
+			colors[r][col] = c
+		}
+	}
+
+	for _, l := range lines {
+		y := dotRow(l.Value)
+		for x := 0; x < dotsX; x += 2 {
+			setDot(x, y, l.Color, false)
+		}
+	}
+	prev := -1
+	for x, v := range resample(values, dotsX) {
+		y := dotRow(v)
+		from, to := y, y
+		if prev >= 0 { // connect to the previous point
+			if prev < from {
+				from = prev
+			} else if prev > to {
+				to = prev
+			}
+		}
+		for dy := from; dy <= to; dy++ {
+			setDot(x, dy, color, true)
+		}
+		prev = y
+	}
+
+	rows = make([]string, height)
+	for r := range cells {
+		var b strings.Builder
+		current := ""
+		for col, bits := range cells[r] {
+			if c := colors[r][col]; c != current && c != "" {
+				b.WriteString("[" + c + "]")
+				current = c
+			}
+			b.WriteRune(0x2800 + bits)
+		}
+		b.WriteString("[-]")
+		rows[r] = b.String()
+	}
+	return rows, top
+}
+
+// seriesRangeText describes a sparkline's values, e.g., "min 2 / avg 3.1 / max 5"
+func seriesRangeText(values []float64, format func(float64) string) string {
+	return "min " + format(opsmath.Min(values...)) + " / avg " + format(opsmath.Avg(values...)) + " / max " + format(opsmath.Max(values...))
+}
+
+// appChartsText draws the app's pod-level series as sparklines and the container's usage as line
+// charts, with the container's request and limit overlaid; width is the chart width, in characters
+func appChartsText(app *appmodel.App, c *appmodel.AppContainer, width int) string {
+	const label = "%-14s"
+	const chartHeight = 4
+	var b strings.Builder
+	count := func(v float64) string { return fmt.Sprintf("%.1f", v) }
+
+	if r := app.Series.Replicas.Values(); len(r) > 0 {
+		fmt.Fprintf(&b, label+"%v  %v\n", "Replicas", sparkline(r, width), seriesRangeText(r, count))
+	}
+	if r := app.Series.RequestRate.Values(); len(r) > 0 {
+		fmt.Fprintf(&b, label+"%v  %v\n", "Requests/sec", sparkline(r, width), seriesRangeText(r, count))
+	}
+	if c == nil {
+		return b.String()
+	}
+
+	charts := []struct {
+		title    string
+		series   appmodel.TimeSeries
+		resource *appmodel.AppContainerResourceInfo
+		format   func(float64) string
+	}{
+		{"CPU", c.Series.Cpu, &c.Cpu.AppContainerResourceInfo, cpuString},
+		{"Memory", c.Series.Memory, &c.Memory.AppContainerResourceInfo, memoryString},
+	}
+	for _, chart := range charts {
+		if len(chart.series) == 0 {
+			continue
+		}
+		lines := []chartLine{}
+		if chart.resource.Request > 0 {
+			lines = append(lines, chartLine{chart.resource.Request, "yellow"})
+		}
+		if chart.resource.Limit > 0 {
+			lines = append(lines, chartLine{chart.resource.Limit, "red"})
+		}
+		rows, top := brailleChart(chart.series.Values(), lines, width, chartHeight, "green")
+		fmt.Fprintf(&b, "\n%v of %q: [green]usage[-], [yellow]request %v[-], [red]limit %v[-]\n",
+			chart.title, c.Name, chart.format(chart.resource.Request), chart.format(chart.resource.Limit))
+		for i, row := range rows {
+			axis := ""
+			switch i {
+			case 0:
+				axis = chart.format(top)
+			case len(rows) - 1:
+				axis = "0"
+			}
+			fmt.Fprintf(&b, "%12s ┤%v\n", axis, row)
+		}
+		first, last := chart.series[0].Time, chart.series[len(chart.series)-1].Time
+		fmt.Fprintf(&b, "%12s  %-*s%s\n", "", width-len(timeLabelFormat), first.Format(timeLabelFormat), last.Format(timeLabelFormat))
+	}
+	return b.String()
+}
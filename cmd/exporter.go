@@ -0,0 +1,136 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+
+	"opsani-ignite/exporter"
+	"opsani-ignite/log"
+	prom "opsani-ignite/sources/prometheus"
+)
+
+var exporterAddress string
+var exporterInterval time.Duration
+
+// exporterCmd represents the exporter command
+var exporterCmd = &cobra.Command{
+	Use:   "exporter [<namespace> [<deployment>]]",
+	Short: "Publish the analysis findings as Prometheus metrics",
+	Long: `Runs Opsani Ignite as a Prometheus exporter: the analysis is re-run on an interval
+and its findings are served on /metrics, labelled with cluster, namespace and workload:
+
+  ignite_app_efficiency_rate     efficiency rate, in percent
+  ignite_app_reliability_risk    reliability risk (1=none .. 5=critical)
+  ignite_app_rating              optimization rating
+  ignite_app_confidence          confidence in the rating, in percent
+  ignite_app_monthly_cost        estimated monthly cost
+  ignite_app_flag{flag="W"}      Opsani flags (1=set, 0=not set)
+  ignite_app_policy_violation    policy rules violated (labelled with policy, severity)
+
+The analysis time range (--start, --end) is re-evaluated on each run.`,
+	Args: cobra.MaximumNArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if exporterInterval < time.Minute {
+			return fmt.Errorf("--interval must be at least 1 minute (found %v)", exporterInterval)
+		}
+		return nil
+	},
+	Run: runExporter,
+}
+
+func init() {
+	exporterCmd.Flags().StringVar(&exporterAddress, "listen", ":9115", "Address to serve metrics on")
+	exporterCmd.Flags().DurationVar(&exporterInterval, "interval", time.Hour, "How often to re-run the analysis")
+	rootCmd.AddCommand(exporterCmd)
+}
+
+// runExporterAnalysis performs a single analysis run and updates the collector with its results
+func runExporterAnalysis(ctx context.Context, collector *exporter.Collector, namespace, deployment string) {
+	started := time.Now()
+	start, end, step, err := parseTimeRange(timeStartString, timeEndString, timeStepString)
+	if err != nil {
+		collector.RecordFailure()
+		log.Errorf("Analysis run failed: %v", err)
+		return
+	}
+	apps, err := collectAndAnalyze(ctx, promUri, clusterName, namespace, deployment, start, end, step, nil)
+	if err != nil {
+		collector.RecordFailure()
+		log.Errorf("Analysis run failed: %v", err)
+		return
+	}
+	duration := time.Since(started)
+	collector.Update(apps, time.Now(), duration)
+	log.Infof("Analysis run completed in %v: %v application(s)", duration.Round(time.Millisecond), len(apps))
+}
+
+func runExporter(cmd *cobra.Command, args []string) {
+	logFile := setupLogFile()
+	defer logFile.Close()
+
+	namespace := ""
+	deployment := ""
+	if len(args) >= 1 {
+		namespace = args[0]
+	}
+	if len(args) >= 2 {
+		deployment = args[1]
+	}
+
+	prom.Init()
+	collector := exporter.NewCollector()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	httpServer := &http.Server{Addr: exporterAddress, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// re-run the analysis on the interval, until stopped
+	go func() {
+		ticker := time.NewTicker(exporterInterval)
+		defer ticker.Stop()
+		for {
+			runExporterAnalysis(ctx, collector, namespace, deployment)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		log.Infof("Shutting down exporter")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	msg := fmt.Sprintf("Serving metrics on %v/metrics, analyzing every %v using Prometheus API at %q", exporterAddress, exporterInterval, promUri)
+	log.Print(msg)
+	fmt.Fprintln(os.Stderr, msg)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Errorf("Exporter failed: %v", err)
+		fmt.Fprintf(os.Stderr, "Exporter failed: %v\n", err)
+		os.Exit(1)
+	}
+}
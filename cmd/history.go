@@ -0,0 +1,253 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/karrick/tparse/v2"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/history"
+	"opsani-ignite/log"
+)
+
+var saveHistory bool
+var historyDir string
+var historyMaxAgeString string
+var historyMaxRuns int
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history [<namespace> [<deployment>]]",
+	Short: "List recorded runs and show application trends",
+	Long: `Lists the runs recorded with --save-history.
+
+When a namespace (and, optionally, a deployment) is specified, shows the trend of
+efficiency rate, reliability risk and monthly cost for each matching application
+across the recorded runs.`,
+	PersistentPreRunE: validateHistoryFlags,
+	Args:              cobra.MaximumNArgs(2),
+	RunE:              runHistory,
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove recorded runs according to the retention policy",
+	Args:  cobra.NoArgs,
+	RunE:  runHistoryPrune,
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&saveHistory, "save-history", false, "Record the results of this run in the history store")
+	rootCmd.PersistentFlags().StringVar(&historyDir, "history-dir", "", "History store directory (default is $HOME/.opsani-ignite/history)")
+	rootCmd.PersistentFlags().StringVar(&historyMaxAgeString, "history-max-age", "", "Remove recorded runs older than this, in relative form (e.g., 90d)")
+	rootCmd.PersistentFlags().IntVar(&historyMaxRuns, "history-max-runs", 0, "Keep at most this many recorded runs (0 for no limit)")
+	viper.BindPFlag("history-dir", rootCmd.PersistentFlags().Lookup("history-dir"))
+	viper.BindPFlag("history-max-age", rootCmd.PersistentFlags().Lookup("history-max-age"))
+	viper.BindPFlag("history-max-runs", rootCmd.PersistentFlags().Lookup("history-max-runs"))
+
+	historyCmd.AddCommand(historyPruneCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func validateHistoryFlags(cmd *cobra.Command, args []string) error {
+	_, err := historyRetentionPolicy()
+	return err
+}
+
+func historyRetentionPolicy() (policy history.RetentionPolicy, err error) {
+	if maxAge := viper.GetString("history-max-age"); maxAge != "" {
+		policy.MaxAge, err = tparse.AbsoluteDuration(time.Now(), maxAge)
+		if err != nil {
+			return policy, fmt.Errorf("Could not parse --history-max-age: %v", err)
+		}
+	}
+	policy.MaxRuns = viper.GetInt("history-max-runs")
+	if policy.MaxRuns < 0 {
+		return policy, fmt.Errorf("--history-max-runs cannot be negative")
+	}
+	return
+}
+
+func openHistoryStore() (*history.Store, error) {
+	dir := viper.GetString("history-dir")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, ".opsani-ignite", "history")
+	}
+	return history.Open(dir)
+}
+
+// recordRun saves the analyzed apps in the history store and applies the retention policy
+func recordRun(apps []*appmodel.App, namespace, deployment string) error {
+	store, err := openHistoryStore()
+	if err != nil {
+		return err
+	}
+	run := &history.Run{
+		Info: history.RunInfo{
+			Prometheus: promUri.String(),
+			Namespace:  namespace,
+			Workload:   deployment,
+			Start:      timeStart,
+			End:        timeEnd,
+			Step:       timeStep,
+		},
+		Apps: apps,
+	}
+	if err := store.Save(run); err != nil {
+		return fmt.Errorf("failed to record run in %q: %v", store.Dir(), err)
+	}
+	log.Infof("Recorded run %v in history store %q", run.Info.Id, store.Dir())
+
+	policy, err := historyRetentionPolicy()
+	if err != nil {
+		return err
+	}
+	if removed, err := store.Prune(policy, time.Now()); err != nil {
+		return fmt.Errorf("failed to apply history retention policy: %v", err)
+	} else if removed > 0 {
+		log.Infof("Removed %v run(s) from history store per retention policy", removed)
+	}
+	return nil
+}
+
+func newHistoryTable(headers []string) *tablewriter.Table {
+	t := tablewriter.NewWriter(os.Stdout)
+	t.SetHeader(headers)
+	t.SetCenterSeparator("")
+	t.SetColumnSeparator("")
+	t.SetRowSeparator("")
+	t.SetHeaderLine(false)
+	t.SetBorder(false)
+	t.SetAlignment(tablewriter.ALIGN_LEFT)
+	return t
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	store, err := openHistoryStore()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return listHistoryRuns(store)
+	}
+	namespace := args[0]
+	deployment := ""
+	if len(args) >= 2 {
+		deployment = args[1]
+	}
+	return showHistoryTrends(store, namespace, deployment)
+}
+
+func listHistoryRuns(store *history.Store) error {
+	runs, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Fprintf(os.Stderr, "No runs recorded in %q. Use --save-history to record runs\n", store.Dir())
+		return nil
+	}
+
+	t := newHistoryTable([]string{"Run", "Time", "Prometheus", "Scope", "Range", "Apps"})
+	for _, r := range runs {
+		scope := "all namespaces"
+		if r.Namespace != "" {
+			scope = r.Namespace
+			if r.Workload != "" {
+				scope += "/" + r.Workload
+			}
+		}
+		t.Append([]string{
+			r.Id,
+			r.Timestamp.Local().Format(time.RFC3339),
+			r.Prometheus,
+			scope,
+			fmt.Sprintf("%v (step %v)", r.End.Sub(r.Start).Round(time.Minute), r.Step),
+			fmt.Sprintf("%v", r.AppCount),
+		})
+	}
+	fmt.Println("")
+	t.Render()
+	fmt.Println("")
+	return nil
+}
+
+func showHistoryTrends(store *history.Store, namespace, deployment string) error {
+	trends, err := store.AppTrends(func(key appmodel.AppKey) bool {
+		return key.Namespace == namespace && (deployment == "" || key.Workload == deployment)
+	})
+	if err != nil {
+		return err
+	}
+	if len(trends) == 0 {
+		fmt.Fprintf(os.Stderr, "No recorded runs include the requested application(s)\n")
+		return nil
+	}
+
+	keys := make([]appmodel.AppKey, 0, len(trends))
+	for k := range trends {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Workload != keys[j].Workload {
+			return keys[i].Workload < keys[j].Workload
+		}
+		if keys[i].Namespace != keys[j].Namespace {
+			return keys[i].Namespace < keys[j].Namespace
+		}
+		return keys[i].WorkloadKind < keys[j].WorkloadKind
+	})
+
+	for _, k := range keys {
+		fmt.Printf("\n%v/%v (%v)\n", k.Namespace, k.Workload, k.WorkloadKind)
+		t := newHistoryTable([]string{"Run", "Time", "Efficiency Rate", "Reliability Risk", "Monthly Cost"})
+		for _, p := range trends[k] {
+			t.Append([]string{
+				p.Run.Id,
+				p.Run.Timestamp.Local().Format(time.RFC3339),
+				appmodel.Rate2String(p.EfficiencyRate),
+				appmodel.Risk2String(p.ReliabilityRisk),
+				fmt.Sprintf("$%.2f", p.MonthlyCost),
+			})
+		}
+		t.Render()
+	}
+	fmt.Println("")
+	return nil
+}
+
+func runHistoryPrune(cmd *cobra.Command, args []string) error {
+	store, err := openHistoryStore()
+	if err != nil {
+		return err
+	}
+	policy, err := historyRetentionPolicy()
+	if err != nil {
+		return err
+	}
+	if policy.MaxAge == 0 && policy.MaxRuns == 0 {
+		return fmt.Errorf("no retention policy specified (use --history-max-age and/or --history-max-runs)")
+	}
+	removed, err := store.Prune(policy, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Removed %v run(s) from %q\n", removed, store.Dir())
+	return nil
+}
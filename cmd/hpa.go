@@ -0,0 +1,224 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	opsmath "opsani-ignite/math"
+)
+
+type autoscalingRule struct {
+	MinSamples      int     `yaml:"min_samples"`       // minimum time steps with both CPU use and replica count to size an HPA
+	CpuTarget       float64 `yaml:"cpu_target"`        // target CPU utilization (%) to recommend
+	BurstyCpuTarget float64 `yaml:"bursty_cpu_target"` // target CPU utilization (%) to recommend for bursty apps, leaving room for spikes
+	MinReplicas     int     `yaml:"min_replicas"`      // lowest minReplicas to recommend, for availability
+	Headroom        float64 `yaml:"headroom"`          // multiplier of the highest observed demand to size maxReplicas for
+	BoundFraction   float64 `yaml:"bound_fraction"`    // fraction of time at an existing HPA's replica bound above which it is worth moving
+	TargetTolerance float64 `yaml:"target_tolerance"`  // difference (percentage points) from an existing HPA's CPU target worth changing
+}
+
+func (r *autoscalingRule) Id() string             { return "autoscaling" }
+func (r *autoscalingRule) Category() string       { return RULE_CATEGORY_SCALING }
+func (r *autoscalingRule) Severity() RuleSeverity { return SEVERITY_INFO }
+func (r *autoscalingRule) Description() string {
+	return "Recommends horizontal pod autoscaler settings from the replica and CPU use history"
+}
+
+// fractionAt returns the fraction of the replica counts at or beyond the bound (above it if above is set, below otherwise)
+func fractionAt(replicas []float64, bound int, above bool) float64 {
+	count := 0
+	for _, n := range replicas {
+		if above && n >= float64(bound) || !above && n <= float64(bound) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(replicas))
+}
+
+func (r *autoscalingRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	index, ok := app.ContainerIndexByName(app.Analysis.MainContainer)
+	if !ok {
+		return
+	}
+	c := &app.Containers[index]
+	if c.Cpu.Request == 0 {
+		return // CPU utilization is relative to the request
+	}
+	replicas, cpu := alignSeries(app.Series.Replicas, c.Series.Cpu)
+	if len(replicas) < r.MinSamples {
+		return
+	}
+
+	// size the replica range for the total CPU demand, with each pod at the target utilization
+	demand := make([]float64, len(replicas))
+	for i := range replicas {
+		demand[i] = cpu[i] * replicas[i]
+	}
+	a := &appmodel.Autoscaling{
+		CpuTarget:    r.CpuTarget,
+		MinCpuDemand: opsmath.Min(demand...),
+		MaxCpuDemand: opsmath.Max(demand...),
+		Samples:      len(replicas),
+	}
+	if o.Pattern == appmodel.PATTERN_BURSTY {
+		a.CpuTarget = r.BurstyCpuTarget
+	}
+	perPod := c.Cpu.Request * a.CpuTarget / 100
+	a.MinReplicas = int(math.Max(float64(r.MinReplicas), math.Ceil(a.MinCpuDemand/perPod)))
+	a.MaxReplicas = int(math.Max(float64(a.MinReplicas+1), math.Ceil(a.MaxCpuDemand*r.Headroom/perPod)))
+	if o.Capacity != nil && o.Capacity.TargetReplicas > a.MaxReplicas {
+		a.MaxReplicas = o.Capacity.TargetReplicas // room for the projected traffic
+	}
+	o.Autoscaling = a
+
+	if !app.Settings.HpaEnabled {
+		if o.Flags[appmodel.F_MANY_REPLICAS] || seasonal(o.Pattern) || o.Pattern == appmodel.PATTERN_BURSTY {
+			o.Recommendations = append(o.Recommendations, fmt.Sprintf("Add a horizontal pod autoscaler (HPA) for %v-%v replicas at %.0f%% CPU utilization",
+				a.MinReplicas, a.MaxReplicas, a.CpuTarget))
+		}
+		return
+	}
+
+	// tune the existing HPA
+	s := &app.Settings
+	if s.HpaCpuTarget == 0 {
+		a.Advice = append(a.Advice, fmt.Sprintf("Scale the HPA on CPU utilization, targeting %.0f%%", a.CpuTarget))
+	} else if math.Abs(s.HpaCpuTarget-a.CpuTarget) >= r.TargetTolerance {
+		a.Advice = append(a.Advice, fmt.Sprintf("Change the HPA's CPU utilization target from %.0f%% to %.0f%%", s.HpaCpuTarget, a.CpuTarget))
+	}
+	if s.HpaMaxReplicas > 0 && a.MaxReplicas > s.HpaMaxReplicas {
+		if atMax := fractionAt(replicas, s.HpaMaxReplicas, true); atMax >= r.BoundFraction {
+			a.Advice = append(a.Advice, fmt.Sprintf("The HPA is at maxReplicas (%v) %.0f%% of the time; raise it to %v", s.HpaMaxReplicas, atMax*100, a.MaxReplicas))
+		}
+	}
+	if s.HpaMinReplicas > a.MinReplicas {
+		if atMin := fractionAt(replicas, s.HpaMinReplicas, false); atMin >= r.BoundFraction {
+			a.Advice = append(a.Advice, fmt.Sprintf("The HPA is at minReplicas (%v) %.0f%% of the time; lower it to %v", s.HpaMinReplicas, atMin*100, a.MinReplicas))
+		}
+	}
+	o.Recommendations = append(o.Recommendations, a.Advice...)
+}
+
+// autoscalingString describes the recommended HPA settings, and the existing HPA's, if any
+func autoscalingString(app *appmodel.App) string {
+	a := app.Analysis.Autoscaling
+	if a == nil {
+		return "-"
+	}
+	str := fmt.Sprintf("%v-%v replicas at %.0f%% CPU", a.MinReplicas, a.MaxReplicas, a.CpuTarget)
+	if s := app.Settings; s.HpaEnabled {
+		str += fmt.Sprintf(" (HPA %v: %v-%v replicas at %.0f%% CPU)", s.HpaName, s.HpaMinReplicas, s.HpaMaxReplicas, s.HpaCpuTarget)
+	}
+	return str
+}
+
+type hpaResourceMetric struct {
+	Name      string `yaml:"name"`
+	Container string `yaml:"container,omitempty"` // ContainerResource metrics only
+	Target    struct {
+		Type               string `yaml:"type"`
+		AverageUtilization int    `yaml:"averageUtilization"`
+	} `yaml:"target"`
+}
+
+type hpaMetric struct {
+	Type              string             `yaml:"type"`
+	Resource          *hpaResourceMetric `yaml:"resource,omitempty"`
+	ContainerResource *hpaResourceMetric `yaml:"containerResource,omitempty"`
+}
+
+// hpaManifest is an autoscaling/v2 HorizontalPodAutoscaler manifest
+type hpaManifest struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		ScaleTargetRef struct {
+			ApiVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Name       string `yaml:"name"`
+		} `yaml:"scaleTargetRef"`
+		MinReplicas int         `yaml:"minReplicas"`
+		MaxReplicas int         `yaml:"maxReplicas"`
+		Metrics     []hpaMetric `yaml:"metrics"`
+	} `yaml:"spec"`
+}
+
+// buildHpa builds the manifest of the recommended HPA, named after the existing HPA if there is one.
+// The recommendation is sized from the main container's CPU, so in a pod with several containers the
+// HPA scales on that container's utilization (a ContainerResource metric) rather than the pod's.
+// Returns nil if there is no recommendation.
+func buildHpa(app *appmodel.App) *hpaManifest {
+	a := app.Analysis.Autoscaling
+	if a == nil {
+		return nil
+	}
+	h := &hpaManifest{ApiVersion: "autoscaling/v2", Kind: "HorizontalPodAutoscaler"}
+	h.Metadata.Name = app.Metadata.Workload
+	if app.Settings.HpaName != "" {
+		h.Metadata.Name = app.Settings.HpaName
+	}
+	h.Metadata.Namespace = app.Metadata.Namespace
+	h.Spec.ScaleTargetRef.ApiVersion = app.Metadata.WorkloadApiVersion
+	h.Spec.ScaleTargetRef.Kind = app.Metadata.WorkloadKind
+	h.Spec.ScaleTargetRef.Name = app.Metadata.Workload
+	h.Spec.MinReplicas = a.MinReplicas
+	h.Spec.MaxReplicas = a.MaxReplicas
+	r := &hpaResourceMetric{Name: "cpu"}
+	r.Target.Type = "Utilization"
+	r.Target.AverageUtilization = int(math.Round(a.CpuTarget))
+	m := hpaMetric{Type: "Resource", Resource: r}
+	if len(app.Containers) > 1 && app.Analysis.MainContainer != "" {
+		r.Container = app.Analysis.MainContainer
+		m = hpaMetric{Type: "ContainerResource", ContainerResource: r}
+	}
+	h.Spec.Metrics = []hpaMetric{m}
+	return h
+}
+
+func (table *AppTable) outputHpaApp(app *appmodel.App) {
+	h := buildHpa(app)
+	if h == nil {
+		log.Warnf("No HPA produced for application %v: no CPU request, use or replica history for the main container", app.Metadata)
+		return
+	}
+
+	var node yaml.Node
+	if err := node.Encode(h); err != nil {
+		log.Errorf("Failed to marshal HPA for app %v to yaml: %v", app.Metadata, err)
+		return
+	}
+	a := app.Analysis.Autoscaling
+	comment := []string{
+		fmt.Sprintf("Recommended horizontal pod autoscaler for %v/%v", app.Metadata.Namespace, app.Metadata.Workload),
+		fmt.Sprintf("CPU demand of container %v: %.3g-%.3g cores across replicas over %v samples", app.Analysis.MainContainer, a.MinCpuDemand, a.MaxCpuDemand, a.Samples),
+	}
+	if app.Settings.HpaEnabled {
+		comment = append(comment, fmt.Sprintf("Replaces the settings and metrics of the existing HPA %v (%v-%v replicas, %.0f%% CPU target):",
+			app.Settings.HpaName, app.Settings.HpaMinReplicas, app.Settings.HpaMaxReplicas, app.Settings.HpaCpuTarget))
+		for _, advice := range a.Advice {
+			comment = append(comment, "  - "+advice)
+		}
+	}
+	comment = append(comment, "Review, then apply with: kubectl apply -f <file>")
+	node.HeadComment = strings.Join(comment, "\n")
+	if violations := policyViolationsComment(app); violations != "" {
+		node.HeadComment += "\n" + violations
+	}
+	if err := table.yaml.Encode(&node); err != nil {
+		log.Errorf("Failed to write HPA for app %v to yaml: %v", app.Metadata, err)
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestAutoscalingRule(t *testing.T) {
+	// 4 replicas at night, 8 during the day, each using half a core: 2-4 cores of demand
+	replicas := hourlySeries(24, func(h int) float64 {
+		if h < 12 {
+			return 4
+		}
+		return 8
+	})
+	cpu := hourlySeries(24, func(h int) float64 { return 0.5 })
+
+	tests := []struct {
+		name       string
+		pattern    appmodel.WorkloadPattern
+		cpuRequest float64
+		hpa        *appmodel.AppSettings
+		min, max   int
+		target     float64
+		advice     int
+		recs       int
+	}{
+		{"no HPA, steady", appmodel.PATTERN_STEADY, 1, nil, 3, 9, 70, 0, 0},
+		{"no HPA, diurnal", appmodel.PATTERN_DIURNAL, 1, nil, 3, 9, 70, 0, 1},
+		{"no HPA, bursty", appmodel.PATTERN_BURSTY, 1, nil, 4, 12, 50, 0, 1},
+		{"HPA as recommended", appmodel.PATTERN_DIURNAL, 1, &appmodel.AppSettings{HpaMinReplicas: 3, HpaMaxReplicas: 9, HpaCpuTarget: 70}, 3, 9, 70, 0, 0},
+		{"HPA at max, high target", appmodel.PATTERN_DIURNAL, 1, &appmodel.AppSettings{HpaMinReplicas: 2, HpaMaxReplicas: 8, HpaCpuTarget: 90}, 3, 9, 70, 2, 2},
+		{"HPA at min, no CPU target", appmodel.PATTERN_DIURNAL, 1, &appmodel.AppSettings{HpaMinReplicas: 6, HpaMaxReplicas: 20}, 3, 9, 70, 2, 2},
+		{"no CPU request", appmodel.PATTERN_DIURNAL, 0, nil, 0, 0, 0, 0, 0},
+	}
+	rule := newRuleRegistry(getBuiltinRules()...).Lookup("autoscaling")
+	for _, tt := range tests {
+		c := containerWithResources("web", tt.cpuRequest, 0, 0, 0)
+		c.Series.Cpu = cpu
+		app := &appmodel.App{
+			Containers: []appmodel.AppContainer{c},
+			Series:     appmodel.AppSeries{Replicas: replicas},
+			Analysis:   appmodel.AppAnalysis{MainContainer: "web"},
+		}
+		if tt.hpa != nil {
+			app.Settings = *tt.hpa
+			app.Settings.HpaEnabled, app.Settings.HpaName = true, "web-hpa"
+		}
+		o := &appmodel.AppAnalysis{Flags: make(map[appmodel.AppFlag]bool), Pattern: tt.pattern}
+		rule.Evaluate(app, o)
+		a := o.Autoscaling
+		if tt.max == 0 {
+			if a != nil {
+				t.Errorf("%v: expected no recommendation, got %+v", tt.name, a)
+			}
+			continue
+		}
+		if a == nil {
+			t.Errorf("%v: expected a recommendation", tt.name)
+			continue
+		}
+		if a.MinReplicas != tt.min || a.MaxReplicas != tt.max || a.CpuTarget != tt.target || a.MinCpuDemand != 2 || a.MaxCpuDemand != 4 || a.Samples != 24 {
+			t.Errorf("%v: expected %v-%v replicas at %v%%, got %+v", tt.name, tt.min, tt.max, tt.target, a)
+		}
+		if len(a.Advice) != tt.advice || len(o.Recommendations) != tt.recs {
+			t.Errorf("%v: expected %v advice and %v recommendation(s), got %v and %v", tt.name, tt.advice, tt.recs, a.Advice, o.Recommendations)
+		}
+	}
+}
+
+func TestBuildHpa(t *testing.T) {
+	app := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "web", WorkloadKind: "Deployment", WorkloadApiVersion: "apps/v1"}}
+	if buildHpa(app) != nil {
+		t.Error("expected no HPA without a recommendation")
+	}
+
+	app.Analysis.Autoscaling = &appmodel.Autoscaling{MinReplicas: 3, MaxReplicas: 9, CpuTarget: 70}
+	h := buildHpa(app)
+	if h.ApiVersion != "autoscaling/v2" || h.Kind != "HorizontalPodAutoscaler" || h.Metadata.Name != "web" || h.Metadata.Namespace != "shop" {
+		t.Errorf("unexpected HPA identity: %+v", h)
+	}
+	ref := h.Spec.ScaleTargetRef
+	if ref.ApiVersion != "apps/v1" || ref.Kind != "Deployment" || ref.Name != "web" {
+		t.Errorf("unexpected scale target: %+v", ref)
+	}
+	if h.Spec.MinReplicas != 3 || h.Spec.MaxReplicas != 9 || len(h.Spec.Metrics) != 1 {
+		t.Fatalf("unexpected HPA spec: %+v", h.Spec)
+	}
+	if m := h.Spec.Metrics[0]; m.Type != "Resource" || m.Resource == nil || m.Resource.Name != "cpu" || m.Resource.Target.Type != "Utilization" || m.Resource.Target.AverageUtilization != 70 {
+		t.Errorf("unexpected metric: %+v", m)
+	}
+
+	// sized from the main container: scale on its utilization, not the pod's
+	app.Containers = []appmodel.AppContainer{{Name: "web"}, {Name: "istio-proxy", Sidecar: "istio-proxy"}}
+	app.Analysis.MainContainer = "web"
+	m := buildHpa(app).Spec.Metrics[0]
+	if m.Type != "ContainerResource" || m.Resource != nil || m.ContainerResource == nil {
+		t.Fatalf("expected a container resource metric for a multi-container app, got %+v", m)
+	}
+	if r := m.ContainerResource; r.Name != "cpu" || r.Container != "web" || r.Target.AverageUtilization != 70 {
+		t.Errorf("unexpected container resource metric: %+v", r)
+	}
+
+	app.Settings.HpaEnabled, app.Settings.HpaName = true, "web-hpa"
+	if h := buildHpa(app); h.Metadata.Name != "web-hpa" {
+		t.Errorf("expected the existing HPA's name, got %q", h.Metadata.Name)
+	}
+}
@@ -8,6 +8,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"sort"
 	"time"
@@ -80,9 +81,36 @@ func displayConfig(namespace, deployment string) {
 	fmt.Fprintln(os.Stderr, "")
 }
 
-func displayResults(apps []*appmodel.App, targetedApps bool) {
+// analysisScope identifies the apps to analyze and the time range to analyze them over
+type analysisScope struct {
+	Namespace  string // empty for all namespaces
+	Deployment string // empty for all deployments in the namespace
+	Start      string // as specified on the command line, possibly relative
+	End        string
+	Step       string
+}
+
+// displayedApps returns the apps to display (including apps removed since the baseline, if shown) and
+// the number of apps skipped because they don't meet optimization prerequisites
+func displayedApps(apps []*appmodel.App) (shown []*appmodel.App, skipped int) {
+	candidates := apps
+	if baselineShown() {
+		// list apps that are no longer present at the end
+		candidates = append(append([]*appmodel.App{}, apps...), baselineRemoved...)
+	}
+	for _, app := range candidates {
+		if hideBlocked && !isQualifiedApp(app) {
+			skipped += 1
+			continue
+		}
+		shown = append(shown, app)
+	}
+	return
+}
+
+func displayResults(apps []*appmodel.App, scope analysisScope) {
 	// auto-enable show-all-apps in case no apps meet requirements
-	if targetedApps {
+	if scope.Deployment != "" {
 		hideBlocked = false // ignore hideBlocked when namespace+deployment are explicitly specified
 	} else if hideBlocked {
 		qualified := 0
@@ -100,14 +128,11 @@ func displayResults(apps []*appmodel.App, targetedApps bool) {
 
 	// build table & display (stream, yaml or interactive)
 	table := newAppTable(os.Stdout)
-	skipped := 0
+	table.scope = scope
 	display := getDisplayMethods()[outputFormat]
 	display.WriteHeader(table)
-	for _, app := range apps {
-		if hideBlocked && !isQualifiedApp(app) {
-			skipped += 1
-			continue
-		}
+	shown, skipped := displayedApps(apps)
+	for _, app := range shown {
 		display.WriteApp(table, app)
 	}
 	display.WriteOut(table)
@@ -117,14 +142,54 @@ func displayResults(apps []*appmodel.App, targetedApps bool) {
 	}
 }
 
-func runIgnite(cmd *cobra.Command, args []string) {
+// setupLogFile directs logging to the log file; the caller should close the returned file when done
+func setupLogFile() *os.File {
 	logFile, err := os.OpenFile(LOG_FILE, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		log.Fatalf("error opening log file: %v", err)
 	}
-	defer logFile.Close()
 	log.SetOutput(logFile)
 	log.SetupLogLevel(showDebug, suppressWarnings)
+	return logFile
+}
+
+// collectAndAnalyze gets the applications from Prometheus, analyzes them and sorts them by opportunity
+func collectAndAnalyze(
+	ctx context.Context,
+	promUri *url.URL,
+	cluster string,
+	namespace string,
+	deployment string,
+	timeStart time.Time,
+	timeEnd time.Time,
+	timeStep time.Duration,
+	progressCallback log.ProgressUpdateFunc,
+) ([]*appmodel.App, error) {
+	warnPatternStep(timeStep)
+
+	// get applications from the cluster
+	apps, err := prom.PromGetAll(ctx, promUri, namespace, deployment, "apps/v1", "Deployment", timeStart, timeEnd, timeStep, progressCallback)
+	if err != nil {
+		return nil, err
+	}
+
+	// analyze apps, assign rating and confidence (updates in place)
+	for _, app := range apps {
+		app.Metadata.Cluster = cluster
+		analyzeApp(app)
+	}
+
+	// sort table by opportunity
+	sort.Slice(apps, func(i, j int) bool {
+		return opportunitySorter(apps, i, j)
+	})
+
+	return apps, nil
+}
+
+func runIgnite(cmd *cobra.Command, args []string) {
+	logFile := setupLogFile()
+	defer logFile.Close()
 
 	// determine namespace & deployment selection
 	namespace := ""
@@ -140,12 +205,12 @@ func runIgnite(cmd *cobra.Command, args []string) {
 	// Create root context
 	ctx := context.Background()
 
-	// get applications from the cluster
+	// get applications from the cluster and analyze them
 	prom.Init()
 	apps := make([]*appmodel.App, 0)
-	err = log.GoWithProgress(func (progressCallback log.ProgressUpdateFunc) error {
+	err := log.GoWithProgress(func(progressCallback log.ProgressUpdateFunc) error {
 		var innerErr error
-		apps, innerErr = prom.PromGetAll(ctx, promUri, namespace, deployment, "apps/v1", "Deployment", timeStart, timeEnd, timeStep, progressCallback)
+		apps, innerErr = collectAndAnalyze(ctx, promUri, clusterName, namespace, deployment, timeStart, timeEnd, timeStep, progressCallback)
 		return innerErr
 	})
 	if err != nil {
@@ -161,18 +226,24 @@ func runIgnite(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	// analyze apps, assign rating and confidence (updates in place)
-	for _, app := range apps {
-		analyzeApp(app)
+	// record results for trend analysis, if requested
+	if saveHistory {
+		if err := recordRun(apps, namespace, deployment); err != nil {
+			log.Errorf("%v", err)
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
 	}
 
-	// sort table by opportunity
-	sort.Slice(apps, func(i, j int) bool {
-		return opportunitySorter(apps, i, j)
-	})
+	// compare with previous results, if requested
+	if baselineFile != "" {
+		if err := compareWithBaseline(apps, baselineFile); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// display results
-	displayResults(apps, deployment != "")
+	displayResults(apps, analysisScope{namespace, deployment, timeStartString, timeEndString, timeStepString})
 
 	fmt.Fprint(os.Stderr, "To optimize your application, sign up for a free trial account at https://console.opsani.com/signup\n")
 }
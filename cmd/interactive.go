@@ -7,6 +7,8 @@ package cmd
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
@@ -15,11 +17,41 @@ import (
 	appmodel "opsani-ignite/app/model"
 )
 
+const (
+	qualifiedFilterAll = iota
+	qualifiedFilterQualified
+	qualifiedFilterBlocked
+)
+
+const noConclusionFilter = -1
+
 type interactiveState struct {
+	app       *tview.Application
 	pages     *tview.Pages
 	table     *tview.Table
+	frame     *tview.Frame
+	layout    *tview.Flex
+	search    *tview.InputField
+	status    *tview.TextView // status bar: analysis scope or query progress
+	headers   []HeaderInfo
 	aligns    []int // alignment for each column
 	titleRows int   // how many title (header) rows in the table
+
+	apps   []*appmodel.App          // all apps, in the order added (i.e., by opportunity)
+	marked map[appmodel.AppKey]bool // apps marked for export
+
+	// sort & filter settings
+	sortColumn       int  // column index to sort by; -1 for the default (opportunity) order
+	sortDescending   bool // sort direction
+	searchText       string
+	searchRegexp     *regexp.Regexp     // nil if no search or if searchText is not a valid regexp (substring match used)
+	riskFilter       appmodel.RiskLevel // show apps at or above this risk level; RISK_UNKNOWN shows all
+	conclusionFilter int                // show apps with this conclusion only; noConclusionFilter shows all
+	qualifiedFilter  int                // qualifiedFilterXxx
+
+	// background re-query, if running
+	queryCancel func() // cancels the running query; nil if none is running
+	queryId     int    // identifies the latest query, so that results of superseded queries are dropped
 }
 
 func (table *AppTable) updateRow(row int, cells []*tview.TableCell) {
@@ -53,50 +85,67 @@ func tviewColor(color int) tcell.Color {
 	}[color]
 }
 
-func (table *AppTable) outputInteractiveInit() {
-	// create a header row & data column alignments
-	headers := getHeadersInfo()
-	aligns := make([]int, len(headers))
-	cells0 := make([]*tview.TableCell, len(headers))
-	cells1 := make([]*tview.TableCell, len(headers))
+// titleCells builds the title row(s), marking the sort column with the sort direction
+func (table *AppTable) titleCells() (cells0 []*tview.TableCell, cells1 []*tview.TableCell) {
+	headers := table.i.headers
+	cells0 = make([]*tview.TableCell, len(headers))
+	cells1 = make([]*tview.TableCell, len(headers))
 	for i, h := range headers {
 		titleRows := strings.Split(h.Title, "\n")
+		if i == table.i.sortColumn {
+			marker := " ▲"
+			if table.i.sortDescending {
+				marker = " ▼"
+			}
+			titleRows[len(titleRows)-1] += marker
+		}
 		if len(titleRows) < 2 {
-			cells0[i] = tview.NewTableCell(h.Title)
+			cells0[i] = tview.NewTableCell(titleRows[0])
 			cells1[i] = tview.NewTableCell("")
 		} else {
 			cells0[i] = tview.NewTableCell(titleRows[0])
 			cells1[i] = tview.NewTableCell(titleRows[1]) // any further lines will be ignored
 		}
-		cells0[i].SetTextColor(tcell.ColorAqua)
-		cells1[i].SetTextColor(tcell.ColorAqua)
-		aligns[i] = tviewAlign(h.Alignment)
+		cells0[i].SetTextColor(tcell.ColorAqua).SetSelectable(false)
+		cells1[i].SetTextColor(tcell.ColorAqua).SetSelectable(false)
 	}
+	return
+}
 
-	// determine the number of title rows (1 or 2)
-	titleRowCount := 1
-	for _, c := range cells1 {
-		if c.Text != "" {
+func (table *AppTable) outputInteractiveInit() {
+	// create a header row & data column alignments
+	headers := getHeadersInfo()
+	aligns := make([]int, len(headers))
+	titleRowCount := 1 // determine the number of title rows (1 or 2)
+	for i, h := range headers {
+		aligns[i] = tviewAlign(h.Alignment)
+		if strings.Contains(h.Title, "\n") {
 			titleRowCount = 2
-			break
 		}
 	}
 
 	table.i = interactiveState{
-		table:     tview.NewTable().SetSelectable(true, false).SetFixed(titleRowCount, 0).SetEvaluateAllRows(true),
-		aligns:    aligns,
-		titleRows: titleRowCount,
+		table:            tview.NewTable().SetSelectable(true, false).SetFixed(titleRowCount, 0).SetEvaluateAllRows(true),
+		headers:          headers,
+		aligns:           aligns,
+		titleRows:        titleRowCount,
+		sortColumn:       -1,
+		conclusionFilter: noConclusionFilter,
+		marked:           make(map[appmodel.AppKey]bool),
 	}
 
+	table.updateTitleRows()
+}
+
+func (table *AppTable) updateTitleRows() {
+	cells0, cells1 := table.titleCells()
 	table.updateRow(0, cells0)
-	if titleRowCount > 1 {
+	if table.i.titleRows > 1 {
 		table.updateRow(1, cells1)
 	}
 }
 
-func (table *AppTable) outputInteractiveAddApp(app *appmodel.App) {
-	t := table.i.table
-
+func appRowCells(app *appmodel.App) []*tview.TableCell {
 	efficiencyColor := tviewColor(appEfficiencyColor(app))
 	riskColor := tviewColor(riskColor(app.Analysis.ReliabilityRisk))
 	conclusionColor := tviewColor(conclusionColor(app.Analysis.Conclusion))
@@ -109,14 +158,279 @@ func (table *AppTable) outputInteractiveAddApp(app *appmodel.App) {
 		tview.NewTableCell(fmt.Sprintf("%.0f", app.Metrics.AverageReplicas)),
 		tview.NewTableCell(fmt.Sprintf("%.0f%%", app.Metrics.CpuUtilization)),
 		tview.NewTableCell(fmt.Sprintf("%.0f%%", app.Metrics.MemoryUtilization)),
+		tview.NewTableCell(app.Analysis.Pattern.String()),
 		tview.NewTableCell(app.Analysis.Conclusion.String()).SetTextColor(conclusionColor),
 	}
+	if policiesShown() {
+		policy, color := policyCell(app)
+		cells = append(cells, tview.NewTableCell(policy).SetTextColor(tviewColor(color)))
+	}
+	if baselineShown() {
+		for _, c := range deltaCells(app) {
+			cells = append(cells, tview.NewTableCell(c.Value).SetTextColor(tviewColor(c.Color)))
+		}
+	}
 	cells[0].SetReference(app) // backlink to app in column 0
-	table.updateRow(t.GetRowCount(), cells)
+	return cells
+}
+
+// rowCells returns the app's row cells, highlighting the row if the app is marked
+func (table *AppTable) rowCells(app *appmodel.App) []*tview.TableCell {
+	cells := appRowCells(app)
+	if table.i.marked[app.Key()] {
+		for _, c := range cells {
+			c.SetBackgroundColor(tcell.ColorDarkBlue)
+		}
+		cells[0].SetText("+ " + cells[0].Text)
+	}
+	return cells
+}
+
+func (table *AppTable) outputInteractiveAddApp(app *appmodel.App) {
+	table.i.apps = append(table.i.apps, app)
+	table.updateRow(table.i.table.GetRowCount(), table.rowCells(app))
+}
+
+// --- Sorting and filtering -------------------------------------------------
+
+func (table *AppTable) appMatchesFilters(app *appmodel.App) bool {
+	s := &table.i
+	if s.searchText != "" {
+		target := app.Metadata.Namespace + "/" + app.Metadata.Workload
+		if s.searchRegexp != nil {
+			if !s.searchRegexp.MatchString(target) {
+				return false
+			}
+		} else if !strings.Contains(strings.ToLower(target), strings.ToLower(s.searchText)) {
+			return false
+		}
+	}
+	if s.riskFilter != appmodel.RISK_UNKNOWN && app.Analysis.ReliabilityRisk.SafeRiskLevel() < s.riskFilter {
+		return false
+	}
+	if s.conclusionFilter != noConclusionFilter && int(app.Analysis.Conclusion) != s.conclusionFilter {
+		return false
+	}
+	switch s.qualifiedFilter {
+	case qualifiedFilterQualified:
+		return isQualifiedApp(app)
+	case qualifiedFilterBlocked:
+		return !isQualifiedApp(app)
+	}
+	return true
+}
+
+// visibleApps returns the apps that match the filters, in the selected sort order
+func (table *AppTable) visibleApps() []*appmodel.App {
+	apps := make([]*appmodel.App, 0, len(table.i.apps))
+	for _, app := range table.i.apps {
+		if table.appMatchesFilters(app) {
+			apps = append(apps, app)
+		}
+	}
+	if table.i.sortColumn >= 0 {
+		compare := table.i.headers[table.i.sortColumn].Compare
+		sort.SliceStable(apps, func(i, j int) bool {
+			if table.i.sortDescending {
+				return compare(apps[j], apps[i]) < 0
+			}
+			return compare(apps[i], apps[j]) < 0
+		})
+	}
+	return apps
+}
+
+// filterDescription describes the active sort order and filters, for the frame header
+func (table *AppTable) filterDescription() string {
+	s := &table.i
+	parts := []string{}
+	if s.sortColumn >= 0 {
+		direction := "ascending"
+		if s.sortDescending {
+			direction = "descending"
+		}
+		parts = append(parts, fmt.Sprintf("Sort: %v (%v)", strings.ReplaceAll(s.headers[s.sortColumn].Title, "\n", " "), direction))
+	}
+	if s.searchText != "" {
+		parts = append(parts, fmt.Sprintf("Search: %q", s.searchText))
+	}
+	if s.riskFilter != appmodel.RISK_UNKNOWN {
+		parts = append(parts, fmt.Sprintf("Risk: %v+", s.riskFilter))
+	}
+	if s.conclusionFilter != noConclusionFilter {
+		parts = append(parts, fmt.Sprintf("Analysis: %v", appmodel.AnalysisConclusion(s.conclusionFilter)))
+	}
+	switch s.qualifiedFilter {
+	case qualifiedFilterQualified:
+		parts = append(parts, "Qualified only")
+	case qualifiedFilterBlocked:
+		parts = append(parts, "Blocked only")
+	}
+	if len(s.marked) > 0 {
+		parts = append(parts, fmt.Sprintf("%v marked", len(s.marked)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " | ")
+}
+
+func (table *AppTable) updateFrame() {
+	f := table.i.frame
+	f.Clear()
+	shown := table.i.table.GetRowCount() - table.i.titleRows
+	if desc := table.filterDescription(); desc != "" {
+		f.AddText(fmt.Sprintf("%v  [%v of %v apps]", desc, shown, len(table.i.apps)), true /*header*/, tview.AlignLeft, tcell.ColorYellow)
+	} else {
+		f.AddText(fmt.Sprintf("%v apps", len(table.i.apps)), true /*header*/, tview.AlignLeft, tcell.ColorAqua)
+	}
+	f.AddText("1-9,<,> sort  0 default order  / search  r risk  c analysis  b blocked/qualified  x clear filters  t time range/scope  Ctrl-X cancel  Space mark  e export  v tree view  Enter details  Esc quit", false /*header*/, tview.AlignCenter, tcell.ColorGray)
+	f.AddText("To optimize your application, sign up for a free trial account at https://console.opsani.com/signup", false /*header*/, tview.AlignCenter, 0 /*color*/)
+}
+
+// refreshRows re-renders the app rows with the current sort order and filters, keeping the selected app if still shown
+func (table *AppTable) refreshRows() {
+	t := table.i.table
+	var selected *appmodel.AppKey
+	if row, _ := t.GetSelection(); row >= table.i.titleRows {
+		if ref := t.GetCell(row, 0).GetReference(); ref != nil {
+			key := ref.(*appmodel.App).Key()
+			selected = &key
+		}
+	}
+
+	t.Clear()
+	table.updateTitleRows()
+	selectRow := table.i.titleRows
+	for _, app := range table.visibleApps() {
+		row := t.GetRowCount()
+		if selected != nil && app.Key() == *selected { // by key, since the apps are replaced on re-query
+			selectRow = row
+		}
+		table.updateRow(row, table.rowCells(app))
+	}
+	t.Select(selectRow, 0)
+	table.updateFrame()
+}
+
+func (table *AppTable) sortBy(column int) {
+	if column >= len(table.i.headers) {
+		return
+	}
+	if column == table.i.sortColumn {
+		table.i.sortDescending = !table.i.sortDescending
+	} else {
+		table.i.sortColumn = column
+		table.i.sortDescending = false
+	}
+	table.refreshRows()
+}
+
+func (table *AppTable) setSearch(text string) {
+	table.i.searchText = text
+	table.i.searchRegexp = nil
+	if text != "" {
+		if re, err := regexp.Compile("(?i)" + text); err == nil {
+			table.i.searchRegexp = re
+		}
+	}
+	table.refreshRows()
+}
+
+func (table *AppTable) cycleRiskFilter() {
+	switch table.i.riskFilter {
+	case appmodel.RISK_UNKNOWN:
+		table.i.riskFilter = appmodel.RISK_MEDIUM
+	case appmodel.RISK_MEDIUM:
+		table.i.riskFilter = appmodel.RISK_HIGH
+	default:
+		table.i.riskFilter = appmodel.RISK_UNKNOWN
+	}
+	table.refreshRows()
+}
+
+func (table *AppTable) cycleConclusionFilter() {
+	table.i.conclusionFilter++
+	if table.i.conclusionFilter > appmodel.CONCLUSION_OK {
+		table.i.conclusionFilter = noConclusionFilter
+	}
+	table.refreshRows()
+}
+
+func (table *AppTable) cycleQualifiedFilter() {
+	table.i.qualifiedFilter = (table.i.qualifiedFilter + 1) % (qualifiedFilterBlocked + 1)
+	table.refreshRows()
+}
+
+func (table *AppTable) clearFilters() {
+	table.i.search.SetText("")
+	table.i.searchText = ""
+	table.i.searchRegexp = nil
+	table.i.riskFilter = appmodel.RISK_UNKNOWN
+	table.i.conclusionFilter = noConclusionFilter
+	table.i.qualifiedFilter = qualifiedFilterAll
+	table.refreshRows()
+}
+
+func (table *AppTable) openSearch() {
+	table.i.layout.ResizeItem(table.i.search, 1, 0)
+	table.i.app.SetFocus(table.i.search)
+}
+
+func (table *AppTable) closeSearch() {
+	table.i.layout.ResizeItem(table.i.search, 0, 0)
+	table.i.app.SetFocus(table.i.table)
+}
+
+// handleListKey processes the sort & filter keys on the app list; returns nil if the key was consumed
+func (table *AppTable) handleListKey(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() != tcell.KeyRune {
+		return event
+	}
+	switch r := event.Rune(); {
+	case r >= '1' && r <= '9':
+		table.sortBy(int(r - '1'))
+	case r == '0':
+		table.i.sortColumn = -1
+		table.refreshRows()
+	case r == '<':
+		column := table.i.sortColumn - 1
+		if column < 0 {
+			column = len(table.i.headers) - 1
+		}
+		table.i.sortColumn = -1 // pick the new column in ascending order
+		table.sortBy(column)
+	case r == '>':
+		column := (table.i.sortColumn + 1) % len(table.i.headers)
+		table.i.sortColumn = -1
+		table.sortBy(column)
+	case r == '/':
+		table.openSearch()
+	case r == 'r':
+		table.cycleRiskFilter()
+	case r == 'c':
+		table.cycleConclusionFilter()
+	case r == 'b':
+		table.cycleQualifiedFilter()
+	case r == 'x':
+		table.clearFilters()
+	case r == 't':
+		table.popupQueryForm()
+	case r == ' ':
+		table.toggleMark()
+	case r == 'e':
+		table.popupExportForm()
+	case r == 'v':
+		table.showTree()
+	default:
+		return event
+	}
+	return nil
 }
 
 func (table *AppTable) outputInteractiveRun() {
 	app := tview.NewApplication()
+	table.i.app = app
 
 	// construct table
 	t := table.i.table
@@ -143,23 +457,44 @@ func (table *AppTable) outputInteractiveRun() {
 			t.Select(table.i.titleRows, 0)
 		}
 	})
+	t.SetInputCapture(table.handleListKey)
+
+	// create search box (hidden until '/' is pressed); filters live as the text changes
+	search := tview.NewInputField().SetLabel("Search (namespace/deployment, regexp): ")
+	search.SetChangedFunc(table.setSearch)
+	search.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			search.SetText("") // cancel search
+		}
+		table.closeSearch()
+	})
+	table.i.search = search
 
 	// create frame
 	f := tview.NewFrame(t)
-	f.AddText("To optimize your application, sign up for a free trial account at https://console.opsani.com/signup", false /*header*/, tview.AlignCenter, 0 /*color*/)
 	f.SetBorders(0 /*top*/, 0 /*bottom*/, 0 /*header*/, 1 /*footer*/, 0 /*left*/, 0 /*right*/)
+	table.i.frame = f
+	table.updateFrame()
+
+	// create status bar
+	table.i.status = tview.NewTextView().SetDynamicColors(true)
+	table.updateStatus("")
+
+	table.i.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(f, 0, 1, true).
+		AddItem(search, 0, 0, false).
+		AddItem(table.i.status, 1, 0, false)
 
 	// create pages and run
 	table.i.pages = tview.NewPages()
-	table.i.pages.AddPage("applist", f, true, true)
+	table.i.pages.AddPage("applist", table.i.layout, true, true)
+	app.SetInputCapture(table.handleQueryKey)
 	if err := app.SetRoot(table.i.pages, true).SetFocus(table.i.pages).Run(); err != nil {
 		panic(err)
 	}
 }
 
-func (table *AppTable) popupAppDetail(app *appmodel.App) {
-	entries := buildDetailEntries(app)
-
+func newDetailTable(entries []detailEntry) *tview.Table {
 	t := tview.NewTable().SetEvaluateAllRows(true)
 	row := 0
 	for _, e := range entries {
@@ -177,18 +512,144 @@ func (table *AppTable) popupAppDetail(app *appmodel.App) {
 		}
 		row += len(values)
 	}
+	return t
+}
 
-	t.SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEscape {
-			table.i.pages.SwitchToPage("applist")
+func getContainerHeadersInfo() []HeaderInfo {
+	return []HeaderInfo{
+		{"Container", alignLeft, nil},
+		{"CPU\nRequest", alignRight, nil},
+		{"CPU\nLimit", alignRight, nil},
+		{"CPU\nUsage", alignRight, nil},
+		{"CPU\nSaturation", alignRight, nil},
+		{"CPU\nThrottled", alignRight, nil},
+		{"Mem\nRequest", alignRight, nil},
+		{"Mem\nLimit", alignRight, nil},
+		{"Mem\nUsage", alignRight, nil},
+		{"Mem\nSaturation", alignRight, nil},
+		{"Restarts", alignRight, nil},
+		{"Pseudo\nCost", alignRight, nil},
+	}
+}
+
+func containerRowCells(c *appmodel.AppContainer, main bool) []*tview.TableCell {
+	saturationColor := func(saturation float64) tcell.Color {
+		if saturation > 1 {
+			return tcell.ColorRed
+		}
+		return 0
+	}
+	nonZeroColor := func(v float64) tcell.Color {
+		if v > 0 {
+			return tcell.ColorYellow
+		}
+		return 0
+	}
+
+	name := tview.NewTableCell(c.Name)
+	if main {
+		name.SetText("* " + c.Name).SetTextColor(tcell.ColorAqua).SetAttributes(tcell.AttrBold)
+	}
+	return []*tview.TableCell{
+		name,
+		tview.NewTableCell(cpuString(c.Cpu.Request)),
+		tview.NewTableCell(cpuString(c.Cpu.Limit)),
+		tview.NewTableCell(cpuString(c.Cpu.Usage)),
+		tview.NewTableCell(saturationString(c.Cpu.Saturation)).SetTextColor(saturationColor(c.Cpu.Saturation)),
+		tview.NewTableCell(fmt.Sprintf("%.2fs/s", c.Cpu.SecondsThrottled)).SetTextColor(nonZeroColor(c.Cpu.SecondsThrottled)),
+		tview.NewTableCell(memoryString(c.Memory.Request)),
+		tview.NewTableCell(memoryString(c.Memory.Limit)),
+		tview.NewTableCell(memoryString(c.Memory.Usage)),
+		tview.NewTableCell(saturationString(c.Memory.Saturation)).SetTextColor(saturationColor(c.Memory.Saturation)),
+		tview.NewTableCell(fmt.Sprintf("%.0f", c.RestartCount)).SetTextColor(nonZeroColor(c.RestartCount)),
+		tview.NewTableCell(fmt.Sprintf("%.3f", c.PseudoCost)),
+	}
+}
+
+// newContainerTable builds a table listing the app's containers, one per row, with the main container highlighted
+func newContainerTable(app *appmodel.App) *tview.Table {
+	t := tview.NewTable().SetEvaluateAllRows(true).SetSelectable(true, false).SetFixed(2, 0)
+	headers := getContainerHeadersInfo()
+	for col, h := range headers {
+		titles := append(strings.Split(h.Title, "\n"), "")
+		for row := 0; row < 2; row++ {
+			t.SetCell(row, col, tview.NewTableCell(titles[row]).SetTextColor(tcell.ColorAqua).SetAlign(tview.AlignCenter).SetSelectable(false))
+		}
+	}
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		for col, cell := range containerRowCells(c, c.Name == app.Analysis.MainContainer) {
+			t.SetCell(2+i, col, cell.SetAlign(tviewAlign(headers[col].Alignment)).SetReference(c))
 		}
+	}
+	return t
+}
+
+func (table *AppTable) popupAppDetail(app *appmodel.App) {
+	details := newDetailTable(buildDetailEntries(app))
+	containers := newContainerTable(app)
+	containers.SetBorder(true).SetTitle(" Containers ")
+
+	// charts follow the selected container
+	charts := tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+	charts.SetBorder(true).SetTitle(" Usage Over Time ")
+	chartWidth := 60
+	if _, _, w, _ := table.i.pages.GetRect(); w-40 > chartWidth {
+		chartWidth = w - 40
+	}
+	containers.SetSelectionChangedFunc(func(row, column int) {
+		var c *appmodel.AppContainer
+		if ref := containers.GetCell(row, 0).GetReference(); ref != nil {
+			c = ref.(*appmodel.AppContainer)
+		}
+		charts.SetText(appChartsText(app, c, chartWidth))
 	})
+	if mainIndex, ok := app.ContainerIndexByName(app.Analysis.MainContainer); ok {
+		containers.Select(2+mainIndex, 0)
+	} else {
+		containers.Select(2, 0)
+	}
+	chartRows := strings.Count(charts.GetText(false), "\n") + 2 // borders
 
-	// --- Emulate modal pop up with the details table inside
+	// Esc returns to the list
+	done := func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			table.i.pages.RemovePage("details") // return to the list or tree below
+		}
+	}
+	details.SetDoneFunc(done)
+	containers.SetDoneFunc(done)
+
+	// Tab moves the focus between the details and the containers; 'w' shows how the metrics were obtained
+	keys := func(other tview.Primitive) func(event *tcell.EventKey) *tcell.EventKey {
+		return func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyTab || event.Key() == tcell.KeyBacktab {
+				table.i.app.SetFocus(other)
+				return nil
+			}
+			if event.Key() == tcell.KeyRune && event.Rune() == 'w' {
+				table.popupWhy(app)
+				return nil
+			}
+			return event
+		}
+	}
+	details.SetInputCapture(keys(containers))
+	containers.SetInputCapture(keys(details))
+
+	// --- Emulate modal pop up with the details and containers tables inside
+
+	containerRows := containers.GetRowCount() + 2 // borders
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(details, 0, 1, true).
+		AddItem(containers, containerRows, 0, false).
+		AddItem(charts, chartRows, 0, false)
+	layout.SetBorder(true)
+	layout.SetTitle(fmt.Sprintf(" Details (%v) ", app.Metadata.Workload))
 
 	// prepare frame
-	f := tview.NewFrame(t)
-	f.AddText("Press ESC to return to list", false /*header*/, tview.AlignCenter, 0)
+	f := tview.NewFrame(layout)
+	f.AddText("Press TAB to switch between details and containers, W to see how the metrics were obtained, ESC to return", false /*header*/, tview.AlignCenter, 0)
 	f.SetBorders(1 /*top*/, 1 /*bottom*/, 0 /*header*/, 0 /*footer*/, 1 /*left*/, 1 /*right*/)
 	_, top := table.i.pages.GetFrontPage()
 	x, y, w, h := top.GetRect()
@@ -199,15 +660,12 @@ func (table *AppTable) popupAppDetail(app *appmodel.App) {
 			y += 1
 			h -= 3
 		}
-		rows := t.GetRowCount() + 5 // borders, spacing, footer
+		rows := details.GetRowCount() + containerRows + chartRows + 5 // borders, spacing, footer
 		if h > rows {
 			h = rows
 		}
 	}
 	f.SetRect(x, y, w, h) // always set size, since "resize" is false in AddPage()
 
-	//table.i.pages.AddAndSwitchToPage("details", t, true)
-	t.SetBorder(true)
-	t.SetTitle(fmt.Sprintf(" Details (%v) ", app.Metadata.Workload))
 	table.i.pages.AddPage("details", f, false /*resize*/, true /*visivble*/)
 }
@@ -0,0 +1,151 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+// const table - formats available for export from the interactive view (file-oriented formats only)
+func getExportFormats() []string {
+	return []string{OUTPUT_YAML, OUTPUT_SERVO, OUTPUT_PATCH, OUTPUT_HPA, OUTPUT_VPA, OUTPUT_MARKDOWN}
+}
+
+func exportFileName(format string) string {
+	if format == OUTPUT_MARKDOWN {
+		return "ignite-report.md"
+	}
+	return fmt.Sprintf("ignite-%v.yaml", strings.TrimSuffix(format, ".yaml"))
+}
+
+// selectedApp returns the app in the selected row, or nil
+func (table *AppTable) selectedApp() *appmodel.App {
+	row, _ := table.i.table.GetSelection()
+	if row < table.i.titleRows {
+		return nil
+	}
+	if ref := table.i.table.GetCell(row, 0).GetReference(); ref != nil {
+		return ref.(*appmodel.App)
+	}
+	return nil
+}
+
+// toggleMark marks or unmarks the selected app for export and moves to the next row
+func (table *AppTable) toggleMark() {
+	app := table.selectedApp()
+	if app == nil {
+		return
+	}
+	key := app.Key()
+	if table.i.marked[key] {
+		delete(table.i.marked, key)
+	} else {
+		table.i.marked[key] = true
+	}
+	row, _ := table.i.table.GetSelection()
+	table.updateRow(row, table.rowCells(app))
+	if row+1 < table.i.table.GetRowCount() {
+		table.i.table.Select(row+1, 0)
+	}
+	table.updateFrame()
+}
+
+// exportedApps returns the marked apps, in the displayed order; if none are marked, the selected app
+func (table *AppTable) exportedApps() []*appmodel.App {
+	apps := []*appmodel.App{}
+	if len(table.i.marked) > 0 {
+		for _, app := range table.visibleApps() {
+			if table.i.marked[app.Key()] {
+				apps = append(apps, app)
+			}
+		}
+		for _, app := range table.i.apps { // marked apps hidden by the filters come last
+			if table.i.marked[app.Key()] && !table.appMatchesFilters(app) {
+				apps = append(apps, app)
+			}
+		}
+	} else if app := table.selectedApp(); app != nil {
+		apps = append(apps, app)
+	}
+	return apps
+}
+
+// exportApps writes the apps to the file in the given output format
+func (table *AppTable) exportApps(apps []*appmodel.App, format string, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %v", err)
+	}
+	defer f.Close()
+
+	export := newAppTable(f)
+	export.scope = table.scope
+	display := getDisplayMethods()[format]
+	display.WriteHeader(export)
+	for _, app := range apps {
+		display.WriteApp(export, app)
+	}
+	display.WriteOut(export)
+	return f.Close()
+}
+
+// popupExportForm shows a dialog to export the marked apps (or the selected app) to a file
+func (table *AppTable) popupExportForm() {
+	apps := table.exportedApps()
+	if len(apps) == 0 {
+		return
+	}
+
+	formats := getExportFormats()
+	format := formats[0]
+	form := tview.NewForm()
+	form.AddDropDown("Format", formats, 0, nil)
+	form.AddInputField("File", exportFileName(format), 50, nil, nil)
+	path := form.GetFormItemByLabel("File").(*tview.InputField)
+	form.GetFormItemByLabel("Format").(*tview.DropDown).SetSelectedFunc(func(text string, index int) {
+		// follow the format with the file name, unless it was changed by the user
+		if path.GetText() == exportFileName(format) {
+			path.SetText(exportFileName(text))
+		}
+		format = text
+	})
+
+	closeForm := func() {
+		table.i.pages.RemovePage("export")
+		table.i.app.SetFocus(table.i.table)
+	}
+	form.AddButton("Export", func() {
+		file := path.GetText()
+		if file == "" {
+			form.SetTitle(" [red]File name is required[-] ")
+			return
+		}
+		if err := table.exportApps(apps, format, file); err != nil {
+			log.Errorf("%v", err)
+			form.SetTitle(fmt.Sprintf(" [red]%v[-] ", err))
+			return
+		}
+		closeForm()
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		log.Infof("Exported %v application(s) as %v to %q", len(apps), format, file)
+		table.updateStatus(fmt.Sprintf("[green]Exported %v application(s) as %v to %v[-]", len(apps), format, file))
+	})
+	form.AddButton("Cancel", closeForm)
+	form.SetCancelFunc(closeForm)
+	form.SetBorder(true).SetTitle(fmt.Sprintf(" Export %v Application(s) ", len(apps)))
+
+	table.i.pages.AddPage("export", centered(form, 70, 9), true /*resize*/, true /*visible*/)
+}
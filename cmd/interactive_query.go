@@ -0,0 +1,183 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+const queryProgressInterval = 250 * time.Millisecond
+
+func (s analysisScope) String() string {
+	target := "all namespaces"
+	if s.Namespace != "" {
+		target = "namespace " + s.Namespace
+		if s.Deployment != "" {
+			target += ", deployment " + s.Deployment
+		}
+	}
+	return fmt.Sprintf("%v, from %v to %v, step %v", target, s.Start, s.End, s.Step)
+}
+
+// updateStatus shows a message in the status bar; an empty message shows the current analysis scope
+func (table *AppTable) updateStatus(msg string) {
+	if msg == "" {
+		msg = fmt.Sprintf("[aqua]Analyzed:[-] %v", table.scope)
+	}
+	table.i.status.SetText(msg)
+}
+
+// centered returns a primitive that places p in the middle of the screen, at the given size
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
+
+// popupQueryForm shows a dialog to change the analysis time range and scope and re-run the analysis
+func (table *AppTable) popupQueryForm() {
+	scope := table.scope
+	form := tview.NewForm().
+		AddInputField("Start", scope.Start, 30, nil, func(text string) { scope.Start = text }).
+		AddInputField("End", scope.End, 30, nil, func(text string) { scope.End = text }).
+		AddInputField("Step", scope.Step, 30, nil, func(text string) { scope.Step = text }).
+		AddInputField("Namespace", scope.Namespace, 30, nil, func(text string) { scope.Namespace = text }).
+		AddInputField("Deployment", scope.Deployment, 30, nil, func(text string) { scope.Deployment = text })
+
+	closeForm := func() {
+		table.i.pages.RemovePage("query")
+		table.i.app.SetFocus(table.i.table)
+	}
+	form.AddButton("Run", func() {
+		if scope.Deployment != "" && scope.Namespace == "" {
+			form.SetTitle(" [red]Deployment requires a namespace[-] ")
+			return
+		}
+		if _, _, _, err := parseTimeRange(scope.Start, scope.End, scope.Step); err != nil {
+			form.SetTitle(fmt.Sprintf(" [red]%v[-] ", err))
+			return
+		}
+		closeForm()
+		table.startQuery(scope)
+	})
+	form.AddButton("Cancel", closeForm)
+	form.SetCancelFunc(closeForm)
+	form.SetBorder(true).SetTitle(" Re-run Analysis ")
+
+	table.i.pages.AddPage("query", centered(form, 60, 15), true /*resize*/, true /*visible*/)
+}
+
+// startQuery re-runs collection and analysis in the background, showing progress in the status bar;
+// the app list is updated in place when done. A running query is cancelled.
+func (table *AppTable) startQuery(scope analysisScope) {
+	// relative times are evaluated when the query starts (the form has validated them already)
+	start, end, step, err := parseTimeRange(scope.Start, scope.End, scope.Step)
+	if err != nil {
+		table.updateStatus(fmt.Sprintf("[red]%v[-]", err))
+		return
+	}
+
+	if table.i.queryCancel != nil {
+		table.i.queryCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	table.i.queryCancel = cancel
+	table.i.queryId++
+	queryId := table.i.queryId
+	log.Infof("Re-running analysis for %v", scope)
+
+	progress := &log.ProgressTracker{}
+	started := time.Now()
+	showProgress := func() {
+		info := progress.Info()
+		table.updateStatus(fmt.Sprintf("[yellow]Collecting data (%.1fs): %v of %v namespace(s) and %v of %v application(s) completed...[-] (%v)",
+			time.Since(started).Seconds(), info.NamespacesDone, info.NamespacesTotal, info.WorkloadsDone, info.WorkloadsTotal, scope))
+	}
+	showProgress()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(queryProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				table.i.app.QueueUpdateDraw(func() {
+					if table.i.queryId == queryId {
+						showProgress()
+					}
+				})
+			}
+		}
+	}()
+
+	go func() {
+		apps, err := collectAndAnalyze(ctx, promUri, clusterName, scope.Namespace, scope.Deployment, start, end, step, progress.Update)
+		close(done)
+		if err == nil && saveHistory {
+			if err := recordRun(apps, scope.Namespace, scope.Deployment); err != nil {
+				log.Errorf("%v", err)
+			}
+		}
+		table.i.app.QueueUpdateDraw(func() {
+			if table.i.queryId != queryId {
+				return // superseded by a later query
+			}
+			table.i.queryCancel = nil
+			cancel()
+			table.finishQuery(scope, apps, err)
+		})
+	}()
+}
+
+// finishQuery replaces the displayed apps with the results of a query; runs on the UI goroutine
+func (table *AppTable) finishQuery(scope analysisScope, apps []*appmodel.App, err error) {
+	if err != nil {
+		log.Errorf("Analysis failed for %v: %v", scope, err)
+		table.updateStatus(fmt.Sprintf("[red]Failed to obtain data from Prometheus: %v[-] (still showing %v)", err, table.scope))
+		return
+	}
+	if len(apps) == 0 {
+		table.updateStatus(fmt.Sprintf("[red]No applications found for %v[-] (still showing %v)", scope, table.scope))
+		return
+	}
+	if baselineFile != "" {
+		if err := compareWithBaseline(apps, baselineFile); err != nil {
+			log.Errorf("%v", err)
+		}
+	}
+
+	table.scope = scope
+	table.i.apps, _ = displayedApps(apps)
+	table.refreshRows()
+	table.updateStatus("")
+}
+
+// handleQueryKey lets Ctrl-X cancel a running query
+func (table *AppTable) handleQueryKey(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyCtrlX && table.i.queryCancel != nil {
+		table.i.queryCancel()
+		table.i.queryCancel = nil
+		table.i.queryId++ // drop the results
+		table.updateStatus("[yellow]Analysis cancelled[-]")
+		return nil
+	}
+	return event
+}
@@ -0,0 +1,210 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+// appGroupSummary aggregates the analysis of a group of apps (e.g., a namespace or a cluster)
+type appGroupSummary struct {
+	Count          int
+	Conclusions    map[appmodel.AnalysisConclusion]int
+	rateSum        int
+	rateCount      int
+	WorstRisk      appmodel.RiskLevel
+	MonthlyCost    float64
+	MonthlySavings float64
+}
+
+func newAppGroupSummary(apps []*appmodel.App) *appGroupSummary {
+	s := &appGroupSummary{Conclusions: make(map[appmodel.AnalysisConclusion]int)}
+	for _, app := range apps {
+		s.Count++
+		s.Conclusions[app.Analysis.Conclusion]++
+		if app.Analysis.EfficiencyRate != nil {
+			s.rateSum += *app.Analysis.EfficiencyRate
+			s.rateCount++
+		}
+		if risk := app.Analysis.ReliabilityRisk.SafeRiskLevel(); risk > s.WorstRisk {
+			s.WorstRisk = risk
+		}
+		s.MonthlyCost += app.MonthlyCost()
+		s.MonthlySavings += app.MonthlySavingsEstimate()
+	}
+	return s
+}
+
+// AverageEfficiencyRate returns the average efficiency rate of the apps for which it is known, or nil if none
+func (s *appGroupSummary) AverageEfficiencyRate() *int {
+	if s.rateCount == 0 {
+		return nil
+	}
+	rate := s.rateSum / s.rateCount
+	return &rate
+}
+
+// text describes the summary for a tree node, with tview color tags
+func (s *appGroupSummary) text() string {
+	conclusions := []string{}
+	for c := appmodel.AnalysisConclusion(0); c <= appmodel.CONCLUSION_OK; c++ {
+		if n := s.Conclusions[c]; n > 0 {
+			conclusions = append(conclusions, fmt.Sprintf("[%v]%v %v[-]", colorName(tviewColor(conclusionColor(c))), n, c))
+		}
+	}
+	worstRisk := s.WorstRisk
+	return fmt.Sprintf("%v app(s): %v | efficiency %v%% | worst risk [%v]%v[-] | cost $%.2f/mo, savings $%.2f/mo",
+		s.Count, strings.Join(conclusions, ", "), appmodel.Rate2String(s.AverageEfficiencyRate()),
+		colorName(tviewColor(riskColor(&worstRisk))), appmodel.Risk2String(&worstRisk), s.MonthlyCost, s.MonthlySavings)
+}
+
+// colorName returns the tview color tag name for a color; "-" (default) for no color
+func colorName(color tcell.Color) string {
+	if color == 0 {
+		return "-"
+	}
+	for name, c := range tcell.ColorNames {
+		if c == color {
+			return name
+		}
+	}
+	return "-"
+}
+
+// groupApps groups the apps by key, keeping the order of the apps within each group; the groups are
+// ordered by their estimated savings, largest first
+func groupApps(apps []*appmodel.App, key func(app *appmodel.App) string) (keys []string, groups map[string][]*appmodel.App) {
+	groups = make(map[string][]*appmodel.App)
+	for _, app := range apps {
+		k := key(app)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], app)
+	}
+	savings := make(map[string]float64, len(keys))
+	for _, k := range keys {
+		savings[k] = newAppGroupSummary(groups[k]).MonthlySavings
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return savings[keys[i]] > savings[keys[j]] })
+	return
+}
+
+func groupNode(label string, apps []*appmodel.App) *tview.TreeNode {
+	return tview.NewTreeNode(fmt.Sprintf("[::b]%v[::-]  %v", tview.Escape(label), newAppGroupSummary(apps).text())).
+		SetSelectable(true)
+}
+
+func namespaceNodes(apps []*appmodel.App) []*tview.TreeNode {
+	nodes := []*tview.TreeNode{}
+	namespaces, byNamespace := groupApps(apps, func(app *appmodel.App) string { return app.Metadata.Namespace })
+	for _, ns := range namespaces {
+		nsNode := groupNode(ns, byNamespace[ns]).SetExpanded(false)
+		for _, app := range byNamespace[ns] {
+			text := fmt.Sprintf("%v  [%v]%v%%[-] efficiency, [%v]%v[-] risk, [%v]%v[-]",
+				tview.Escape(app.Metadata.Workload),
+				colorName(tviewColor(appEfficiencyColor(app))), appmodel.Rate2String(app.Analysis.EfficiencyRate),
+				colorName(tviewColor(riskColor(app.Analysis.ReliabilityRisk))), appmodel.Risk2String(app.Analysis.ReliabilityRisk),
+				colorName(tviewColor(conclusionColor(app.Analysis.Conclusion))), app.Analysis.Conclusion)
+			nsNode.AddChild(tview.NewTreeNode(text).SetReference(app))
+		}
+		nodes = append(nodes, nsNode)
+	}
+	return nodes
+}
+
+// removedNode lists the apps removed since the baseline; they are not counted in the group summaries
+func removedNode(removed []*appmodel.App) *tview.TreeNode {
+	node := tview.NewTreeNode(fmt.Sprintf("[::b]Removed since the baseline[::-]  %v app(s)", len(removed))).
+		SetSelectable(true).SetExpanded(false)
+	for _, app := range removed {
+		text := fmt.Sprintf("%v/%v  [%v]%v[-]", tview.Escape(app.Metadata.Namespace), tview.Escape(app.Metadata.Workload),
+			colorName(tviewColor(colorOrange)), appmodel.CHANGE_REMOVED)
+		node.AddChild(tview.NewTreeNode(text).SetReference(app))
+	}
+	return node
+}
+
+// buildAppTree builds a tree of the apps grouped by namespace and, if there are several, by cluster;
+// apps removed since the baseline are listed separately
+func buildAppTree(apps []*appmodel.App) *tview.TreeNode {
+	current := make([]*appmodel.App, 0, len(apps))
+	removed := []*appmodel.App{}
+	for _, app := range apps {
+		if isRemovedApp(app) {
+			removed = append(removed, app)
+		} else {
+			current = append(current, app)
+		}
+	}
+
+	root := groupNode("All applications", current)
+	clusters, byCluster := groupApps(current, func(app *appmodel.App) string { return app.Metadata.Cluster })
+	if len(clusters) > 1 {
+		for _, cluster := range clusters {
+			label := cluster
+			if label == "" {
+				label = "(unnamed cluster)"
+			}
+			root.AddChild(groupNode(label, byCluster[cluster]).SetChildren(namespaceNodes(byCluster[cluster])))
+		}
+	} else {
+		root.SetChildren(namespaceNodes(current))
+	}
+	if len(removed) > 0 {
+		root.AddChild(removedNode(removed))
+	}
+	return root
+}
+
+// showTree switches to the tree view of the apps currently shown in the list (i.e., matching the filters)
+func (table *AppTable) showTree() {
+	root := buildAppTree(table.visibleApps())
+	tree := tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		if ref := node.GetReference(); ref != nil {
+			table.popupAppDetail(ref.(*appmodel.App))
+			return
+		}
+		node.SetExpanded(!node.IsExpanded())
+	})
+	tree.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			table.showList()
+		}
+	})
+	tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'v' {
+			table.showList()
+			return nil
+		}
+		return event
+	})
+
+	f := tview.NewFrame(tree)
+	f.SetBorders(0 /*top*/, 0 /*bottom*/, 0 /*header*/, 1 /*footer*/, 0 /*left*/, 0 /*right*/)
+	header := "Applications by namespace, largest savings first"
+	if desc := table.filterDescription(); desc != "" {
+		header += " | " + desc
+	}
+	f.AddText(header, true /*header*/, tview.AlignLeft, tcell.ColorAqua)
+	f.AddText("Enter expand/collapse or details  v list view  Esc return to list", false /*header*/, tview.AlignCenter, tcell.ColorGray)
+
+	table.i.pages.AddAndSwitchToPage("apptree", f, true /*resize*/)
+}
+
+func (table *AppTable) showList() {
+	table.i.pages.SwitchToPage("applist")
+	table.i.pages.RemovePage("apptree")
+	table.i.app.SetFocus(table.i.table)
+}
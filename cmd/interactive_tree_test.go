@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestBuildAppTreeRemovedApps(t *testing.T) {
+	defer func() { baselineDeltas, baselineRemoved = nil, nil }()
+
+	web := baselineApp("shop", "web", 2, 1)
+	old := baselineApp("legacy", "old", 8, 4)
+	baselineDeltas = map[appmodel.AppKey]*appmodel.AppDelta{
+		web.Key(): {Change: appmodel.CHANGE_NONE},
+		old.Key(): {Change: appmodel.CHANGE_REMOVED},
+	}
+
+	root := buildAppTree([]*appmodel.App{web, old})
+	if text := root.GetText(); !strings.Contains(text, "1 app(s)") || !strings.Contains(text, fmt.Sprintf("cost $%.2f/mo", web.MonthlyCost())) {
+		t.Errorf("expected the summary to count only the current app, got %q", text)
+	}
+	children := root.GetChildren()
+	if len(children) != 2 || !strings.Contains(children[0].GetText(), "shop") {
+		t.Fatalf("expected the shop namespace and a removed apps node, got %v node(s)", len(children))
+	}
+	removed := children[1]
+	if !strings.Contains(removed.GetText(), "Removed") || len(removed.GetChildren()) != 1 || removed.GetChildren()[0].GetReference() != old {
+		t.Errorf("expected legacy/old under the removed apps node, got %q", removed.GetText())
+	}
+}
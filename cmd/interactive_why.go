@@ -0,0 +1,186 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+const QUERY_FILE = "ignite-queries.promql"
+
+// const table - clipboard commands to try, in order
+func getClipboardCommands() [][]string {
+	return [][]string{
+		{"pbcopy"},
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"clip.exe"},
+	}
+}
+
+// copyToClipboard copies the text to the system clipboard, using the first clipboard command available
+func copyToClipboard(text string) error {
+	for _, command := range getClipboardCommands() {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	return fmt.Errorf("no clipboard command found")
+}
+
+// saveQuery appends the metric's query to the query file, with a comment identifying it
+func saveQuery(app *appmodel.App, s *appmodel.MetricSource) error {
+	f, err := os.OpenFile(QUERY_FILE, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "# %v/%v: %v (%v)\n%v\n\n", app.Metadata.Namespace, app.Metadata.Workload, sourceLabel(s), sourceTimeRange(s), s.Query)
+	return f.Close()
+}
+
+func sourceLabel(s *appmodel.MetricSource) string {
+	if s.Container != "" {
+		return fmt.Sprintf("%v, container %q", s.Metric, s.Container)
+	}
+	return s.Metric
+}
+
+func sourceTimeRange(s *appmodel.MetricSource) string {
+	if s.Step == 0 {
+		return fmt.Sprintf("instant query at %v", s.End.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("from %v to %v, step %v", s.Start.Format(time.RFC3339), s.End.Format(time.RFC3339), s.Step)
+}
+
+// sourceText describes a metric source in detail, with tview color tags
+func sourceText(s *appmodel.MetricSource) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[aqua]Metric:[-]     %v\n", tview.Escape(sourceLabel(s)))
+	fmt.Fprintf(&b, "[aqua]Time range:[-] %v\n", sourceTimeRange(s))
+	fmt.Fprintf(&b, "[aqua]Series:[-]     %v\n", s.Series)
+	if st := s.Stats; st != nil {
+		fmt.Fprintf(&b, "[aqua]Samples:[-]    %v: min %g, max %g, average %g, median %g, stdev %g, sum %g\n",
+			st.N, st.Min, st.Max, st.Average, st.Median, st.StDev, st.Sum)
+	} else {
+		fmt.Fprintf(&b, "[aqua]Samples:[-]    none\n")
+	}
+	for i, w := range s.Warnings {
+		label := "          "
+		if i == 0 {
+			label = "Warnings:"
+		}
+		fmt.Fprintf(&b, "[yellow]%v[-]   %v\n", label, tview.Escape(w))
+	}
+	fmt.Fprintf(&b, "[aqua]Query:[-]\n%v\n", tview.Escape(s.Query))
+	return b.String()
+}
+
+// popupWhy shows how each of the app's metrics was obtained: the query, time range, samples and warnings
+func (table *AppTable) popupWhy(app *appmodel.App) {
+	t := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	titles := []string{"Metric", "Container", "Series", "Samples", "Min", "Average", "Median", "Max", "StDev", "Warnings"}
+	for col, title := range titles {
+		t.SetCell(0, col, tview.NewTableCell(title).SetTextColor(tcell.ColorAqua).SetSelectable(false))
+	}
+	for i := range app.Sources {
+		s := &app.Sources[i]
+		values := []string{s.Metric, s.Container, fmt.Sprintf("%v", s.Series), "-", "", "", "", "", "", ""}
+		if st := s.Stats; st != nil {
+			values[3] = fmt.Sprintf("%v", st.N)
+			for j, v := range []float64{st.Min, st.Average, st.Median, st.Max, st.StDev} {
+				values[4+j] = fmt.Sprintf("%.4g", v)
+			}
+		}
+		if len(s.Warnings) > 0 {
+			values[9] = fmt.Sprintf("%v", len(s.Warnings))
+		}
+		for col, v := range values {
+			c := tview.NewTableCell(v).SetReference(s)
+			if col >= 2 {
+				c.SetAlign(tview.AlignRight)
+			}
+			if col == 9 && v != "" {
+				c.SetTextColor(tcell.ColorYellow)
+			}
+			t.SetCell(1+i, col, c)
+		}
+	}
+
+	details := tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	details.SetBorder(true)
+	status := tview.NewTextView().SetDynamicColors(true)
+	selected := func() *appmodel.MetricSource {
+		row, _ := t.GetSelection()
+		if ref := t.GetCell(row, 0).GetReference(); ref != nil {
+			return ref.(*appmodel.MetricSource)
+		}
+		return nil
+	}
+	t.SetSelectionChangedFunc(func(row, column int) {
+		if s := selected(); s != nil {
+			details.SetText(sourceText(s)).ScrollToBeginning()
+		}
+	})
+	t.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			table.i.pages.RemovePage("why")
+		}
+	})
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		s := selected()
+		if event.Key() != tcell.KeyRune || s == nil {
+			return event
+		}
+		switch event.Rune() {
+		case 'y':
+			if err := copyToClipboard(s.Query); err != nil {
+				status.SetText(fmt.Sprintf("[red]Failed to copy query to clipboard: %v; use 's' to save it to a file instead[-]", err))
+			} else {
+				status.SetText("[green]Query copied to clipboard[-]")
+			}
+		case 's':
+			if err := saveQuery(app, s); err != nil {
+				log.Errorf("Failed to save query to %q: %v", QUERY_FILE, err)
+				status.SetText(fmt.Sprintf("[red]Failed to save query: %v[-]", err))
+			} else {
+				status.SetText(fmt.Sprintf("[green]Query appended to %v[-]", QUERY_FILE))
+			}
+		default:
+			return event
+		}
+		return nil
+	})
+	if len(app.Sources) > 0 {
+		t.Select(1, 0)
+	} else {
+		details.SetText("No metric sources recorded for this application.")
+	}
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t, 0, 1, true).
+		AddItem(details, 12, 0, false).
+		AddItem(status, 1, 0, false).
+		AddItem(tview.NewTextView().SetText("y copy query to clipboard  s save query to file  Esc return to details").SetTextAlign(tview.AlignCenter).SetTextColor(tcell.ColorGray), 1, 0, false)
+	layout.SetBorder(true)
+	layout.SetTitle(fmt.Sprintf(" Why? Metric sources (%v) ", app.Metadata.Workload))
+
+	table.i.pages.AddPage("why", layout, true /*resize*/, true /*visible*/)
+}
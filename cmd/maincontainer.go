@@ -0,0 +1,181 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	opsmath "opsani-ignite/math"
+)
+
+// mainContainerOverrides pins the main container of workloads, by <namespace>/<workload>
+// (from the main_containers key of the config file)
+var mainContainerOverrides map[string]string
+
+// Workload annotation pinning the main container (opsani.com/main-container, as sanitized by kube-state-metrics)
+const MAIN_CONTAINER_ANNOTATION = "opsani_com_main_container"
+
+// Main container identification: points for each trait of a container, and the lead over the
+// runner-up needed to pick the top-scoring container
+const (
+	MAIN_SCORE_NAME_EXACT   = 50  // named after the workload
+	MAIN_SCORE_NAME_STEM    = 40  // named after the workload without a suffix such as -deployment
+	MAIN_SCORE_NAME_SIMILAR = 20  // name contains the workload's (stem) name, or vice versa
+	MAIN_SCORE_NAME_GENERIC = 20  // named main or app
+	MAIN_SCORE_SIDECAR      = -60 // known sidecar
+	MAIN_SCORE_HELPER       = -20 // name suggests a helper, e.g., -exporter or -proxy
+	MAIN_SCORE_COST_SHARE   = 30  // times the share of the pod cost
+	MAIN_SCORE_PORTS        = 15  // exposes ports targeted by Prometheus scrapes (typically through a Service)
+	MAIN_SCORE_TRAFFIC      = 20  // times the R² of the CPU use against the request rate
+	MAIN_SCORE_MARGIN       = 10
+)
+
+// Minimum time steps to relate a container's CPU use to the request rate, and the R² to mention it as a reason
+const (
+	MAIN_TRAFFIC_MIN_SAMPLES = 6
+	MAIN_TRAFFIC_MIN_FIT     = 0.5
+)
+
+// constant tables - workload name suffixes that container names usually omit, and helper container name suffixes
+func getWorkloadNameSuffixes() []string {
+	return []string{"-deployment", "-deploy", "-app", "-service", "-svc", "-server"}
+}
+
+func getHelperNameSuffixes() []string {
+	return []string{"-exporter", "-proxy", "-sidecar", "-agent", "-injector"}
+}
+
+// workloadStem returns the workload name without its longest known suffix
+func workloadStem(workload string) string {
+	stem := workload
+	for _, suffix := range getWorkloadNameSuffixes() {
+		if s := strings.TrimSuffix(workload, suffix); s != workload && s != "" && len(s) < len(stem) {
+			stem = s
+		}
+	}
+	return stem
+}
+
+type containerScore struct {
+	Name    string
+	Score   float64
+	Reasons []string
+}
+
+// scoreContainers scores how likely each of the app's containers is the main one, highest first
+func scoreContainers(app *appmodel.App) []containerScore {
+	workload := app.Metadata.Workload
+	stem := workloadStem(workload)
+	podCost := app.PodPseudoCost()
+
+	scores := make([]containerScore, 0, len(app.Containers))
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		s := containerScore{Name: c.Name}
+		add := func(points float64, reason string) {
+			s.Score += points
+			if reason != "" {
+				s.Reasons = append(s.Reasons, reason)
+			}
+		}
+
+		// name
+		switch {
+		case c.Name == workload:
+			add(MAIN_SCORE_NAME_EXACT, "named after the workload")
+		case c.Name == stem:
+			add(MAIN_SCORE_NAME_STEM, fmt.Sprintf("named after the workload (%v)", workload))
+		case c.Name == "main" || c.Name == "app":
+			add(MAIN_SCORE_NAME_GENERIC, fmt.Sprintf("named %v", c.Name))
+		case strings.Contains(c.Name, stem) || strings.Contains(stem, c.Name):
+			add(MAIN_SCORE_NAME_SIMILAR, "name similar to the workload's")
+		}
+		if c.Sidecar != "" {
+			add(MAIN_SCORE_SIDECAR, fmt.Sprintf("known sidecar (%v)", c.Sidecar))
+		} else {
+			for _, suffix := range getHelperNameSuffixes() {
+				if strings.HasSuffix(c.Name, suffix) {
+					add(MAIN_SCORE_HELPER, fmt.Sprintf("helper name (%v)", suffix))
+					break
+				}
+			}
+		}
+
+		// usage
+		if podCost > 0 {
+			share := c.PseudoCost / podCost
+			add(MAIN_SCORE_COST_SHARE*share, fmt.Sprintf("%.0f%% of the pod cost", share*100))
+		}
+
+		// exposed ports and traffic
+		if len(c.Ports) > 0 {
+			add(MAIN_SCORE_PORTS, fmt.Sprintf("exposes %v", strings.Join(c.Ports, ", ")))
+		}
+		if rps, cpu := alignSeries(app.Series.RequestRate, c.Series.Cpu); len(rps) >= MAIN_TRAFFIC_MIN_SAMPLES {
+			_, _, r2 := fitOrZero(opsmath.LinearRegression(rps, cpu))
+			reason := ""
+			if r2 >= MAIN_TRAFFIC_MIN_FIT {
+				reason = fmt.Sprintf("CPU use follows traffic (R² %.2f)", r2)
+			}
+			add(MAIN_SCORE_TRAFFIC*r2, reason)
+		}
+
+		scores = append(scores, s)
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores
+}
+
+// identifyMainContainer returns the name of the app's main container, or empty if it cannot be identified,
+// and why it was (or was not) chosen
+func identifyMainContainer(app *appmodel.App) (name string, reason string) {
+	// handle overrides
+	key := fmt.Sprintf("%v/%v", app.Metadata.Namespace, app.Metadata.Workload)
+	if pinned, ok := mainContainerOverrides[key]; ok {
+		if _, found := app.ContainerIndexByName(pinned); found {
+			return pinned, "pinned in the config file"
+		}
+		log.Warnf("Main container %q pinned in the config file for %v not found; ignoring", pinned, app.Metadata)
+	}
+	if pinned, ok := app.Metadata.Annotations[MAIN_CONTAINER_ANNOTATION]; ok {
+		if _, found := app.ContainerIndexByName(pinned); found {
+			return pinned, "pinned by the opsani.com/main-container annotation"
+		}
+		log.Warnf("Main container %q pinned by annotation for %v not found; ignoring", pinned, app.Metadata)
+	}
+
+	// handle trivial cases
+	if len(app.Containers) < 1 {
+		return "", ""
+	}
+	if len(app.Containers) == 1 {
+		return app.Containers[0].Name, "only container"
+	}
+
+	// pick the top-scoring container if it leads clearly
+	scores := scoreContainers(app)
+	top, next := scores[0], scores[1]
+	if top.Score-next.Score < MAIN_SCORE_MARGIN {
+		log.Warnf("Could not identify application's main container for %v: %v scores %.0f, %v scores %.0f", app.Metadata, top.Name, top.Score, next.Name, next.Score)
+		return "", fmt.Sprintf("no clear choice between %v (score %.0f) and %v (score %.0f)", top.Name, top.Score, next.Name, next.Score)
+	}
+	return top.Name, fmt.Sprintf("score %.0f vs. %.0f for %v: %v", top.Score, next.Score, next.Name, strings.Join(top.Reasons, "; "))
+}
+
+// mainContainerString describes the app's main container and why it was chosen
+func mainContainerString(app *appmodel.App) string {
+	if app.Analysis.MainContainerBy == "" {
+		return app.Analysis.MainContainer
+	}
+	if app.Analysis.MainContainer == "" {
+		return fmt.Sprintf("- (%v)", app.Analysis.MainContainerBy)
+	}
+	return fmt.Sprintf("%v (%v)", app.Analysis.MainContainer, app.Analysis.MainContainerBy)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestIdentifyMainContainer(t *testing.T) {
+	rps := func(h int) float64 { return float64(10 + h%6) }
+	container := func(name string, cost float64) appmodel.AppContainer {
+		return appmodel.AppContainer{Name: name, PseudoCost: cost}
+	}
+	sidecar := container("istio-proxy", 0.03)
+	sidecar.Sidecar = "istio-proxy"
+	exposed := container("api", 0.02)
+	exposed.Ports = []string{"http"}
+	busy := container("api", 0.02)
+	busy.Series.Cpu = hourlySeries(24, func(h int) float64 { return 0.01 * rps(h) })
+	idle := container("cron", 0.02)
+	idle.Series.Cpu = hourlySeries(24, func(h int) float64 { return 0.1 })
+
+	tests := []struct {
+		name        string
+		workload    string
+		containers  []appmodel.AppContainer
+		overrides   map[string]string
+		annotations map[string]string
+		main        string
+		reason      string
+	}{
+		{"single", "web", []appmodel.AppContainer{container("nginx", 0.01)}, nil, nil, "nginx", "only container"},
+		{"suffix stripped", "foo-deployment", []appmodel.AppContainer{container("foo-exporter", 0.02), container("foo", 0.01)}, nil, nil, "foo", "named after the workload (foo-deployment)"},
+		{"cheaper than its sidecar", "shop", []appmodel.AppContainer{sidecar, container("shop", 0.01)}, nil, nil, "shop", "named after the workload"},
+		{"generic name", "shop", []appmodel.AppContainer{container("cache", 0.02), container("main", 0.02)}, nil, nil, "main", "named main"},
+		{"exposed ports", "shop", []appmodel.AppContainer{container("worker", 0.02), exposed}, nil, nil, "api", "exposes http"},
+		{"follows traffic", "shop", []appmodel.AppContainer{idle, busy}, nil, nil, "api", "CPU use follows traffic"},
+		{"no clear choice", "shop", []appmodel.AppContainer{container("a", 0.02), container("b", 0.02)}, nil, nil, "", "no clear choice"},
+		{"pinned in config", "shop", []appmodel.AppContainer{container("a", 0.02), container("b", 0.02)}, map[string]string{"ns/shop": "b"}, nil, "b", "pinned in the config file"},
+		{"pinned by annotation", "shop", []appmodel.AppContainer{container("a", 0.02), container("b", 0.02)}, nil, map[string]string{MAIN_CONTAINER_ANNOTATION: "a"}, "a", "annotation"},
+		{"pinned container missing", "shop", []appmodel.AppContainer{container("a", 0.02), container("b", 0.02)}, map[string]string{"ns/shop": "c"}, nil, "", "no clear choice"},
+	}
+	defer func() { mainContainerOverrides = nil }()
+	for _, tt := range tests {
+		mainContainerOverrides = tt.overrides
+		app := &appmodel.App{
+			Metadata:   appmodel.AppMetadata{Namespace: "ns", Workload: tt.workload, Annotations: tt.annotations},
+			Containers: tt.containers,
+			Series:     appmodel.AppSeries{RequestRate: hourlySeries(24, rps)},
+		}
+		main, reason := identifyMainContainer(app)
+		if main != tt.main || !strings.Contains(reason, tt.reason) {
+			t.Errorf("%v: expected %q (%v), got %q (%v)", tt.name, tt.main, tt.reason, main, reason)
+		}
+	}
+}
+
+func TestWorkloadStem(t *testing.T) {
+	tests := map[string]string{
+		"foo-deployment": "foo",
+		"foo-app":        "foo",
+		"foo-svc":        "foo",
+		"foo":            "foo",
+		"-app":           "-app",
+		"foo-deploy-app": "foo-deploy",
+	}
+	for workload, stem := range tests {
+		if s := workloadStem(workload); s != stem {
+			t.Errorf("%v: expected %q, got %q", workload, stem, s)
+		}
+	}
+}
+
+func TestAnalyzeContainersOrder(t *testing.T) {
+	app := &appmodel.App{
+		Metadata: appmodel.AppMetadata{Workload: "web"},
+		Containers: []appmodel.AppContainer{
+			containerWithResources("small", 0.1, 0.1, 0, 0),
+			containerWithResources("large", 2, 2, 0, 0),
+			containerWithResources("medium", 1, 1, 0, 0),
+		},
+	}
+	analyzeContainers(app)
+	for i, name := range []string{"large", "medium", "small"} {
+		if app.Containers[i].Name != name {
+			t.Errorf("expected containers ordered by cost, got %v at %v", app.Containers[i].Name, i)
+		}
+	}
+}
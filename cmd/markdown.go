@@ -0,0 +1,91 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+// markdownEscape escapes text for use in a markdown table cell
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func markdownTableRow(wr io.Writer, values []string) {
+	for i := range values {
+		values[i] = markdownEscape(values[i])
+	}
+	fmt.Fprintf(wr, "| %v |\n", strings.Join(values, " | "))
+}
+
+func (table *AppTable) outputMarkdownHeader() {
+	table.apps = nil
+}
+
+func (table *AppTable) outputMarkdownApp(app *appmodel.App) {
+	table.apps = append(table.apps, app) // the report's summary comes first, so it is written out at the end
+}
+
+func (table *AppTable) outputMarkdownOut() {
+	wr := &errWriter{wr: table.wr}
+
+	fmt.Fprintf(wr, "# Opsani Ignite Report\n\n")
+	if table.scope != (analysisScope{}) {
+		fmt.Fprintf(wr, "Analyzed %v.\n\n", table.scope)
+	}
+
+	// summary table
+	headers := getHeadersInfo()
+	titles := make([]string, len(headers))
+	separators := make([]string, len(headers))
+	for i, h := range headers {
+		titles[i] = strings.ReplaceAll(h.Title, "\n", " ")
+		separators[i] = map[int]string{alignLeft: ":---", alignCenter: ":---:", alignRight: "---:"}[h.Alignment]
+	}
+	markdownTableRow(wr, titles)
+	fmt.Fprintf(wr, "|%v|\n", strings.Join(separators, "|"))
+	for _, app := range table.apps {
+		markdownTableRow(wr, appRowValues(app))
+	}
+
+	// details
+	for _, app := range table.apps {
+		fmt.Fprintf(wr, "\n## %v/%v\n\n", app.Metadata.Namespace, app.Metadata.Workload)
+		markdownTableRow(wr, []string{"", ""})
+		fmt.Fprintf(wr, "|---|---|\n")
+		for _, e := range buildDetailEntries(app) {
+			if e.Name == "" && e.Value == "" {
+				continue // spacing is not needed in markdown
+			}
+			markdownTableRow(wr, []string{"**" + e.Name + "**", e.Value})
+		}
+	}
+
+	if wr.err != nil {
+		log.Errorf("Failed to write markdown report: %v", wr.err)
+	}
+}
+
+// errWriter keeps the first write error, so that a sequence of writes can be checked once
+type errWriter struct {
+	wr  io.Writer
+	err error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	var n int
+	n, w.err = w.wr.Write(p)
+	return n, w.err
+}
@@ -25,6 +25,9 @@ type AppTable struct {
 	t    tablewriter.Table // table writer, if used
 	i    interactiveState  // interactive app root, if used
 	yaml *yaml.Encoder     // yaml encoder, if used
+
+	apps  []*appmodel.App // apps collected for output at the end, if used
+	scope analysisScope   // what the displayed apps were collected for
 }
 
 type DisplayMethods struct {
@@ -40,6 +43,11 @@ func getDisplayMethods() map[string]DisplayMethods {
 		OUTPUT_DETAIL:      {(*AppTable).outputDetailHeader, (*AppTable).outputDetailApp, (*AppTable).outputAnyTableOut},
 		OUTPUT_YAML:        {(*AppTable).outputYamlHeader, (*AppTable).outputYamlApp, (*AppTable).outputYamlOut},
 		OUTPUT_SERVO:       {(*AppTable).outputYamlHeader, (*AppTable).outputServoYamlApp, (*AppTable).outputYamlOut},
+		OUTPUT_DIFF:        {(*AppTable).outputDiffHeader, (*AppTable).outputDiffApp, (*AppTable).outputAnyTableOut},
+		OUTPUT_PATCH:       {(*AppTable).outputYamlHeader, (*AppTable).outputPatchApp, (*AppTable).outputYamlOut},
+		OUTPUT_HPA:         {(*AppTable).outputYamlHeader, (*AppTable).outputHpaApp, (*AppTable).outputYamlOut},
+		OUTPUT_VPA:         {(*AppTable).outputYamlHeader, (*AppTable).outputVpaApp, (*AppTable).outputYamlOut},
+		OUTPUT_MARKDOWN:    {(*AppTable).outputMarkdownHeader, (*AppTable).outputMarkdownApp, (*AppTable).outputMarkdownOut},
 	}
 }
 
@@ -191,19 +199,54 @@ func conclusionColor(c appmodel.AnalysisConclusion) int {
 type HeaderInfo struct {
 	Title     string
 	Alignment int
+	Compare   func(a, b *appmodel.App) int // column sort order: <0 if a goes before b, 0 if equal, >0 otherwise
 }
 
-func getHeadersInfo() []HeaderInfo {
-	return []HeaderInfo{
-		{"Namespace", alignLeft},
-		{"Deployment", alignLeft},
-		{"Efficiency\nRate", alignRight},
-		{"Reliability\nRisk", alignCenter},
-		{"Replicas", alignRight},
-		{"CPU", alignRight},
-		{"Mem", alignRight},
-		{"Analysis", alignLeft},
+func byString(value func(app *appmodel.App) string) func(a, b *appmodel.App) int {
+	return func(a, b *appmodel.App) int {
+		return strings.Compare(value(a), value(b))
+	}
+}
+
+func byNumber(value func(app *appmodel.App) float64) func(a, b *appmodel.App) int {
+	return func(a, b *appmodel.App) int {
+		va, vb := value(a), value(b)
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+		return 0
+	}
+}
+
+// efficiencyRateSortValue places apps with unknown efficiency rate before all others
+func efficiencyRateSortValue(app *appmodel.App) float64 {
+	if app.Analysis.EfficiencyRate == nil {
+		return -1
 	}
+	return float64(*app.Analysis.EfficiencyRate)
+}
+
+func getHeadersInfo() []HeaderInfo {
+	headers := []HeaderInfo{
+		{"Namespace", alignLeft, byString(func(app *appmodel.App) string { return app.Metadata.Namespace })},
+		{"Deployment", alignLeft, byString(func(app *appmodel.App) string { return app.Metadata.Workload })},
+		{"Efficiency\nRate", alignRight, byNumber(efficiencyRateSortValue)},
+		{"Reliability\nRisk", alignCenter, byNumber(func(app *appmodel.App) float64 { return float64(app.Analysis.ReliabilityRisk.SafeRiskLevel()) })},
+		{"Replicas", alignRight, byNumber(func(app *appmodel.App) float64 { return app.Metrics.AverageReplicas })},
+		{"CPU", alignRight, byNumber(func(app *appmodel.App) float64 { return app.Metrics.CpuUtilization })},
+		{"Mem", alignRight, byNumber(func(app *appmodel.App) float64 { return app.Metrics.MemoryUtilization })},
+		{"Pattern", alignLeft, byNumber(func(app *appmodel.App) float64 { return float64(app.Analysis.Pattern) })},
+		{"Analysis", alignLeft, byNumber(func(app *appmodel.App) float64 { return float64(app.Analysis.Conclusion) })},
+	}
+	if policiesShown() {
+		headers = append(headers, HeaderInfo{"Policy", alignLeft, byNumber(func(app *appmodel.App) float64 { return float64(len(app.Analysis.Violations)) })})
+	}
+	if baselineShown() {
+		headers = append(headers, getDeltaHeadersInfo()...)
+	}
+	return headers
 }
 
 func (table *AppTable) outputTableHeader() {
@@ -223,8 +266,8 @@ func (table *AppTable) outputTableHeader() {
 	table.t.SetBorder(false)
 }
 
-func (table *AppTable) outputTableApp(app *appmodel.App) {
-	color := appTableColor(app)
+// appRowValues returns the app's values for the columns in getHeadersInfo
+func appRowValues(app *appmodel.App) []string {
 	rowValues := []string{
 		app.Metadata.Namespace,
 		app.Metadata.Workload,
@@ -233,8 +276,24 @@ func (table *AppTable) outputTableApp(app *appmodel.App) {
 		fmt.Sprintf("%.0f", app.Metrics.AverageReplicas),
 		fmt.Sprintf("%.0f%%", app.Metrics.CpuUtilization),
 		fmt.Sprintf("%.0f%%", app.Metrics.MemoryUtilization),
+		app.Analysis.Pattern.String(),
 		app.Analysis.Conclusion.String(),
 	}
+	if policiesShown() {
+		policy, _ := policyCell(app)
+		rowValues = append(rowValues, policy)
+	}
+	if baselineShown() {
+		for _, c := range deltaCells(app) {
+			rowValues = append(rowValues, c.Value)
+		}
+	}
+	return rowValues
+}
+
+func (table *AppTable) outputTableApp(app *appmodel.App) {
+	color := appTableColor(app)
+	rowValues := appRowValues(app)
 	cellColors := []int{tablewriterColor(color)}
 	rowColors := make([]tablewriter.Colors, len(rowValues))
 	for i := range rowColors {
@@ -369,12 +428,12 @@ func (table *AppTable) outputServoYamlApp(app *appmodel.App) {
 	opsaniDev.Service = app.Metadata.Workload // TODO: get the real service, this is a stub
 
 	c := &app.Containers[cIndex]
-	cpuCores := selectResourceValue(&c.Cpu.AppContainerResourceInfo)
-	opsaniDev.Cpu.Min = fmt.Sprintf("%g", alignedResourceValue(cpuCores/4.0, false))
-	opsaniDev.Cpu.Max = fmt.Sprintf("%g", alignedResourceValue(cpuCores*2.0, true))
-	memGib := selectResourceValue(&c.Cpu.AppContainerResourceInfo)
-	opsaniDev.Memory.Min = fmt.Sprintf("%gGi", alignedResourceValue(memGib/4.0, false))
-	opsaniDev.Memory.Max = fmt.Sprintf("%gGi", alignedResourceValue(memGib*2.0, true))
+	cpuMin, cpuMax := cpuBounds(c)
+	opsaniDev.Cpu.Min = fmt.Sprintf("%g", alignedResourceValue(cpuMin, false))
+	opsaniDev.Cpu.Max = fmt.Sprintf("%g", alignedResourceValue(cpuMax, true))
+	memMin, memMax := memoryBounds(c)
+	opsaniDev.Memory.Min = fmt.Sprintf("%gGi", alignedResourceValue(memMin/(1024*1024*1024), false))
+	opsaniDev.Memory.Max = fmt.Sprintf("%gGi", alignedResourceValue(memMax/(1024*1024*1024), true))
 
 	configRoot := make(map[string]OpsaniDev, 1)
 	configRoot["opsani_dev"] = opsaniDev
@@ -387,13 +446,19 @@ func (table *AppTable) outputServoYamlApp(app *appmodel.App) {
 	servoYaml := make(map[string]string, 1)
 	servoYaml["servo.yaml"] = string(configRootBuf)
 
-	if err := table.yaml.Encode(servoYaml); err != nil {
+	var node yaml.Node
+	if err := node.Encode(servoYaml); err != nil {
+		log.Errorf("Failed to marshal app %v to yaml: %v", app.Metadata, err)
+		return
+	}
+	node.HeadComment = policyViolationsComment(app)
+	if err := table.yaml.Encode(&node); err != nil {
 		log.Errorf("Failed to write app %v to yaml: %v", app.Metadata, err)
 	}
 }
 
 func newAppTable(wr io.Writer) *AppTable {
-	return &AppTable{wr, *tablewriter.NewWriter(wr), interactiveState{}, nil}
+	return &AppTable{wr: wr, t: *tablewriter.NewWriter(wr)}
 }
 
 type detailEntry struct {
@@ -414,21 +479,33 @@ func buildDetailEntries(app *appmodel.App) []detailEntry {
 		{"Namespace", app.Metadata.Namespace, colorNone},
 		{"Deployment", app.Metadata.Workload, colorNone},
 		{"Kind", fmt.Sprintf("%v (%v)", app.Metadata.WorkloadKind, app.Metadata.WorkloadApiVersion), colorNone},
-		{"Main Container", app.Analysis.MainContainer, colorNone},
+		{"Main Container", mainContainerString(app), colorNone},
 		{"Pod QoS Class", app.Settings.QosClass, colorNone},
 		{"Average Replica Count", fmt.Sprintf("%3.1f", app.Metrics.AverageReplicas), colorNone},
 		{"Container Count", fmt.Sprintf("%3d", len(app.Containers)), colorNone},
+		{"Init Containers", initContainersString(app), colorNone},
 		{"CPU Utilization", fmt.Sprintf("%3.0f%%", app.Metrics.CpuUtilization), colorNone},
 		{"Memory Utilization", fmt.Sprintf("%3.0f%%", app.Metrics.MemoryUtilization), colorNone},
 		{"Network Traffic (approx.)", fmt.Sprintf("%3.1f req/sec", app.Metrics.RequestRate), colorNone},
+		{"Usage Pattern", app.Analysis.Pattern.String(), colorNone},
+		{"Load Imbalance", loadImbalanceString(app.Analysis.LoadImbalance), colorNone},
+		{"Capacity Model", capacityString(app.Analysis.Capacity), colorNone},
+		{"Autoscaling", autoscalingString(app), colorNone},
+		{"Sidecars", sidecarsString(app.Analysis.Sidecars), colorNone},
+		{"Ephemeral Storage", ephemeralStorageString(app), colorNone},
 		{"Opsani Flags", flagsString(app.Analysis.Flags), colorNone},
 		{"", "", colorNone},
 		{"Efficiency Rate", fmt.Sprintf("%4v%%", appmodel.Rate2String(app.Analysis.EfficiencyRate)), efficiencyColor},
 		{"Reliability Risk", fmt.Sprintf("%v", appmodel.Risk2String(app.Analysis.ReliabilityRisk)), riskColor},
 		{"Analysis", app.Analysis.Conclusion.String(), conclusionColor(app.Analysis.Conclusion)},
+		{"Analysis Profile", app.Analysis.Profile, colorNone},
 		{"", "", colorNone},
 	}
 
+	if app.Metadata.Cluster != "" {
+		entries = append([]detailEntry{{"Cluster", app.Metadata.Cluster, colorNone}}, entries...)
+	}
+
 	if len(app.Analysis.Opportunities) > 0 {
 		entries = append(entries, detailEntry{"Opportunities", strings.Join(app.Analysis.Opportunities, "\n"), opportunityColor})
 	}
@@ -441,6 +518,95 @@ func buildDetailEntries(app *appmodel.App) []detailEntry {
 	if len(app.Analysis.Recommendations) > 0 {
 		entries = append(entries, detailEntry{"Recommendations", strings.Join(app.Analysis.Recommendations, "\n"), recommendationColor})
 	}
+	if whatIf := whatIfString(app.Analysis.Capacity); whatIf != "" {
+		entries = append(entries, detailEntry{"What If", whatIf, recommendationColor})
+	}
+
+	if len(app.Analysis.Contributions) > 0 {
+		entries = append(entries, detailEntry{"", "", colorNone})
+		entries = append(entries, detailEntry{"Rating", fmt.Sprintf("%v (confidence %v%%)", app.Analysis.Rating, app.Analysis.Confidence), colorNone})
+		entries = append(entries, detailEntry{"Scoring", contributionsString(app.Analysis.Contributions), colorNone})
+	}
+
+	if d := appDelta(app); d != nil {
+		changes := make([]string, 0, len(d.Fields))
+		for _, f := range d.Fields {
+			changes = append(changes, fmt.Sprintf("%v: %v -> %v", f.Field, f.Before, f.After))
+		}
+		entries = append(entries, detailEntry{"", "", colorNone})
+		entries = append(entries, detailEntry{"Baseline Comparison", d.Change.String(), colorNone})
+		if len(changes) > 0 {
+			entries = append(entries, detailEntry{"Changes", strings.Join(changes, "\n"), colorNone})
+		}
+	}
 
 	return entries
 }
+
+// loadImbalanceString describes how the load is spread across the pods
+func loadImbalanceString(l *appmodel.LoadImbalance) string {
+	if l == nil {
+		return "-"
+	}
+	parts := []string{fmt.Sprintf("%v pods", l.Pods)}
+	if l.CpuMaxMeanRatio > 0 {
+		parts = append(parts, fmt.Sprintf("CPU max/mean %.1f, Gini %.2f", l.CpuMaxMeanRatio, l.CpuGini))
+	}
+	if l.RequestRateMaxMeanRatio > 0 {
+		parts = append(parts, fmt.Sprintf("requests max/mean %.1f, Gini %.2f", l.RequestRateMaxMeanRatio, l.RequestRateGini))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// contributionsString describes the scoring contributions, one per line
+func contributionsString(contributions []appmodel.ScoreContribution) string {
+	lines := make([]string, 0, len(contributions))
+	for _, c := range contributions {
+		names := make([]string, 0, len(c.Inputs))
+		for name := range c.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		inputs := make([]string, 0, len(names))
+		for _, name := range names {
+			inputs = append(inputs, fmt.Sprintf("%v=%.4g", name, c.Inputs[name]))
+		}
+		line := fmt.Sprintf("%v: rating %+d, confidence %+d (%v", c.Rule, c.Rating, c.Confidence, c.Description)
+		if len(inputs) > 0 {
+			line += "; " + strings.Join(inputs, ", ")
+		}
+		lines = append(lines, line+")")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cpuString formats a CPU resource value in cores (e.g., "250m" or "1.5"); zero values are shown as "-"
+func cpuString(cores float64) string {
+	if cores == 0 {
+		return "-"
+	}
+	if cores < 1 {
+		return fmt.Sprintf("%.0fm", cores*1000)
+	}
+	return fmt.Sprintf("%.2f", cores)
+}
+
+// memoryString formats a memory resource value in bytes (e.g., "128Mi" or "1.5Gi"); zero values are shown as "-"
+func memoryString(bytes float64) string {
+	const mib = 1024 * 1024
+	if bytes == 0 {
+		return "-"
+	}
+	if bytes < 1024*mib {
+		return fmt.Sprintf("%.0fMi", bytes/mib)
+	}
+	return fmt.Sprintf("%.2fGi", bytes/(1024*mib))
+}
+
+// saturationString formats a saturation ratio as percent
+func saturationString(saturation float64) string {
+	if saturation == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", saturation*100)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+// Target saturation for the resource requests in generated patches, i.e., how much of the request
+// the observed average usage should take, leaving the rest as headroom for peaks
+const (
+	PATCH_CPU_TARGET_SATURATION    = 0.7
+	PATCH_MEMORY_TARGET_SATURATION = 0.8
+)
+
+type patchResources struct {
+	Cpu    string `yaml:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+}
+
+type patchContainer struct {
+	Name      string `yaml:"name"`
+	Resources struct {
+		Requests patchResources `yaml:"requests,omitempty"`
+		Limits   patchResources `yaml:"limits,omitempty"`
+	} `yaml:"resources"`
+}
+
+// workloadPatch is a partial workload manifest, suitable for server-side apply
+type workloadPatch struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Template struct {
+			Spec struct {
+				Containers []patchContainer `yaml:"containers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// patchRequest computes the request that puts usage at the target saturation, rounded up to the granularity
+func patchRequest(usage, targetSaturation, granularity float64) float64 {
+	return math.Ceil(usage/targetSaturation/granularity) * granularity
+}
+
+// patchLimit keeps the current limit, unless it is below the new request (which Kubernetes rejects)
+func patchLimit(limit, request float64) float64 {
+	if limit > 0 && limit < request {
+		return request
+	}
+	return 0 // no change
+}
+
+func patchCpuString(cores float64) string {
+	if cores == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0fm", cores*1000)
+}
+
+func patchMemoryString(bytes float64) string {
+	if bytes == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0fMi", bytes/(1024*1024))
+}
+
+// buildPatch builds a patch that right-sizes the requests of the app's containers based on their observed usage;
+// containers without usage data are left unchanged. Known sidecars are skipped: they are usually injected
+// by a webhook and not part of the workload's pod template. Returns nil if there is nothing to patch.
+func buildPatch(app *appmodel.App) *workloadPatch {
+	p := &workloadPatch{ApiVersion: app.Metadata.WorkloadApiVersion, Kind: app.Metadata.WorkloadKind}
+	p.Metadata.Name = app.Metadata.Workload
+	p.Metadata.Namespace = app.Metadata.Namespace
+	for _, c := range app.Containers {
+		if c.Sidecar != "" {
+			continue
+		}
+		pc := patchContainer{Name: c.Name}
+		if c.Cpu.Usage > 0 {
+			request := patchRequest(c.Cpu.Usage, PATCH_CPU_TARGET_SATURATION, 0.005)
+			pc.Resources.Requests.Cpu = patchCpuString(request)
+			pc.Resources.Limits.Cpu = patchCpuString(patchLimit(c.Cpu.Limit, request))
+		}
+		if c.Memory.Usage > 0 {
+			request := patchRequest(c.Memory.Usage, PATCH_MEMORY_TARGET_SATURATION, 1024*1024)
+			pc.Resources.Requests.Memory = patchMemoryString(request)
+			pc.Resources.Limits.Memory = patchMemoryString(patchLimit(c.Memory.Limit, request))
+		}
+		if pc.Resources.Requests != (patchResources{}) {
+			p.Spec.Template.Spec.Containers = append(p.Spec.Template.Spec.Containers, pc)
+		}
+	}
+	if len(p.Spec.Template.Spec.Containers) == 0 {
+		return nil
+	}
+	return p
+}
+
+func (table *AppTable) outputPatchApp(app *appmodel.App) {
+	p := buildPatch(app)
+	if p == nil {
+		log.Warnf("No patch produced for application %v: no container usage data", app.Metadata)
+		return
+	}
+
+	var node yaml.Node
+	if err := node.Encode(p); err != nil {
+		log.Errorf("Failed to marshal patch for app %v to yaml: %v", app.Metadata, err)
+		return
+	}
+	node.HeadComment = fmt.Sprintf("Right-sized resource requests for %v/%v (%.0f%% target CPU, %.0f%% target memory saturation)\n"+
+		"Review, then apply with: kubectl apply --server-side --field-manager=opsani-ignite -f <file>",
+		app.Metadata.Namespace, app.Metadata.Workload, PATCH_CPU_TARGET_SATURATION*100, PATCH_MEMORY_TARGET_SATURATION*100)
+	if violations := policyViolationsComment(app); violations != "" {
+		node.HeadComment += "\n" + violations
+	}
+	if err := table.yaml.Encode(&node); err != nil {
+		log.Errorf("Failed to write patch for app %v to yaml: %v", app.Metadata, err)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestBuildPatch(t *testing.T) {
+	app := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "web", WorkloadKind: "Deployment", WorkloadApiVersion: "apps/v1"}}
+	web := appmodel.AppContainer{Name: "web"}
+	web.Cpu.Usage = 0.35                  // 350m / 0.7 -> 500m
+	web.Cpu.Limit = 0.25                  // below the new request -> raised
+	web.Memory.Usage = 100 * 1024 * 1024  // 100Mi / 0.8 -> 125Mi
+	web.Memory.Limit = 1024 * 1024 * 1024 // above the new request -> unchanged
+	idle := appmodel.AppContainer{Name: "idle"}
+	proxy := appmodel.AppContainer{Name: "istio-proxy", Sidecar: "istio-proxy"}
+	proxy.Cpu.Usage, proxy.Memory.Usage = 0.05, 50*1024*1024
+	app.Containers = []appmodel.AppContainer{web, idle, proxy}
+
+	p := buildPatch(app)
+	if p == nil {
+		t.Fatal("expected a patch")
+	}
+	if p.Kind != "Deployment" || p.Metadata.Name != "web" || p.Metadata.Namespace != "shop" {
+		t.Errorf("unexpected patch identity: %+v", p)
+	}
+	containers := p.Spec.Template.Spec.Containers
+	if len(containers) != 1 || containers[0].Name != "web" {
+		t.Fatalf("expected only the non-sidecar container with usage data to be patched, got %+v", containers)
+	}
+	r := containers[0].Resources
+	expected := []struct{ name, got, want string }{
+		{"cpu request", r.Requests.Cpu, "500m"},
+		{"cpu limit", r.Limits.Cpu, "500m"},
+		{"memory request", r.Requests.Memory, "125Mi"},
+		{"memory limit", r.Limits.Memory, ""},
+	}
+	for _, e := range expected {
+		if e.got != e.want {
+			t.Errorf("%v: expected %q, got %q", e.name, e.want, e.got)
+		}
+	}
+
+	if buildPatch(&appmodel.App{Containers: []appmodel.AppContainer{idle}}) != nil {
+		t.Error("expected no patch without usage data")
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/spf13/viper"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+// PolicyConfig is a policy rule, as defined in the config file under the "policies" key
+type PolicyConfig struct {
+	Id         string `mapstructure:"id"`
+	Expression string `mapstructure:"expression"` // CEL expression over the app, true if the app violates the policy
+	Message    string `mapstructure:"message"`
+	Severity   string `mapstructure:"severity"` // info/warning/critical; default warning
+	Block      bool   `mapstructure:"block"`    // whether a violation blocks optimization
+}
+
+// policyRule is an analysis rule that checks an app against a policy written as a CEL expression
+type policyRule struct {
+	config     PolicyConfig
+	severity   RuleSeverity
+	program    cel.Program
+	warnErrors sync.Once // evaluation errors are logged as warnings once per policy, then at trace level
+}
+
+func newPolicyEnv() (*cel.Env, error) {
+	return cel.NewEnv(cel.Declarations(decls.NewVar("app", decls.NewMapType(decls.String, decls.Dyn))))
+}
+
+// newPolicyRule compiles the policy's expression
+func newPolicyRule(env *cel.Env, config PolicyConfig) (*policyRule, error) {
+	if config.Id == "" {
+		return nil, fmt.Errorf("policy rule id is required (expression %q)", config.Expression)
+	}
+	if config.Expression == "" {
+		return nil, fmt.Errorf("policy rule %q: expression is required", config.Id)
+	}
+	if config.Message == "" {
+		config.Message = fmt.Sprintf("Violates policy %v", config.Id)
+	}
+	severity := SEVERITY_WARNING
+	if config.Severity != "" {
+		var err error
+		if severity, err = parseRuleSeverity(config.Severity); err != nil {
+			return nil, fmt.Errorf("policy rule %q: %v", config.Id, err)
+		}
+	}
+
+	ast, issues := env.Compile(config.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("policy rule %q: %v", config.Id, issues.Err())
+	}
+	if t := cel.FormatType(ast.ResultType()); t != "bool" && t != "dyn" {
+		return nil, fmt.Errorf("policy rule %q: expression must be a bool, found %v", config.Id, t)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("policy rule %q: %v", config.Id, err)
+	}
+	return &policyRule{config: config, severity: severity, program: program}, nil
+}
+
+// loadPolicyRules compiles the policy rules defined in the config file
+func loadPolicyRules() ([]*policyRule, error) {
+	var configs []PolicyConfig
+	if err := viper.UnmarshalKey("policies", &configs); err != nil {
+		return nil, fmt.Errorf("invalid policies in config: %v", err)
+	}
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	env, err := newPolicyEnv()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*policyRule, 0, len(configs))
+	for _, config := range configs {
+		rule, err := newPolicyRule(env, config)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r *policyRule) Id() string             { return r.config.Id }
+func (r *policyRule) Category() string       { return RULE_CATEGORY_POLICY }
+func (r *policyRule) Severity() RuleSeverity { return r.severity }
+func (r *policyRule) Description() string {
+	return fmt.Sprintf("%v (%v)", r.config.Message, r.config.Expression)
+}
+
+// genericValue converts the app into the generic form seen by policy expressions: the same schema as
+// the JSON/YAML output, with numbers as doubles
+func genericValue(app *appmodel.App) (map[string]interface{}, error) {
+	buf, err := json.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	err = json.Unmarshal(buf, &value)
+	return value, err
+}
+
+// policyAppInput converts the app, without its analysis, metric series and sources, for policy expressions;
+// it is prepared once per app and shared by the policies, see policyInput
+func policyAppInput(app *appmodel.App) (map[string]interface{}, error) {
+	a := *app
+	a.Series = appmodel.AppSeries{}
+	a.Sources = nil
+	a.Containers = make([]appmodel.AppContainer, len(app.Containers))
+	for i, c := range app.Containers {
+		c.Series = appmodel.AppContainerSeries{}
+		a.Containers[i] = c
+	}
+	a.InitContainers = make([]appmodel.AppContainer, len(app.InitContainers))
+	for i, c := range app.InitContainers {
+		c.Series = appmodel.AppContainerSeries{}
+		a.InitContainers[i] = c
+	}
+	input, err := genericValue(&a)
+	delete(input, "analysis") // set by policyInput
+	return input, err
+}
+
+// policyInput combines the app input with the analysis so far (which earlier policies may have added to)
+func policyInput(appInput map[string]interface{}, o *appmodel.AppAnalysis) (map[string]interface{}, error) {
+	analysis, err := genericValue(&appmodel.App{Analysis: *o})
+	if err != nil {
+		return nil, err
+	}
+	input := make(map[string]interface{}, len(appInput))
+	for k, v := range appInput {
+		input[k] = v
+	}
+	input["analysis"] = analysis["analysis"]
+	return input, nil
+}
+
+func (r *policyRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	appInput, err := policyAppInput(app)
+	if err != nil {
+		log.Errorf("Failed to prepare app %v for policy %q: %v", app.Metadata, r.config.Id, err)
+		return
+	}
+	r.evaluateInput(app, appInput, o)
+}
+
+// evaluateInput evaluates the policy against the app, prepared by policyAppInput
+func (r *policyRule) evaluateInput(app *appmodel.App, appInput map[string]interface{}, o *appmodel.AppAnalysis) {
+	input, err := policyInput(appInput, o)
+	if err != nil {
+		log.Errorf("Failed to prepare app %v for policy %q: %v", app.Metadata, r.config.Id, err)
+		return
+	}
+	out, _, err := r.program.Eval(map[string]interface{}{"app": input})
+	if err != nil {
+		// a field not present for this app (e.g., no namespace labels), or mistyped in the expression
+		warned := false
+		r.warnErrors.Do(func() {
+			log.Warnf("Policy %q could not be evaluated for app %v, treating it as not violated: %v (test optional fields with has(), e.g., has(app.metadata.namespace_labels); further errors are logged at trace level)",
+				r.config.Id, app.Metadata, err)
+			warned = true
+		})
+		if !warned {
+			log.Tracef("Policy %q not evaluated for app %v: %v", r.config.Id, app.Metadata, err)
+		}
+		return
+	}
+	violated, ok := out.Value().(bool)
+	if !ok {
+		log.Warnf("Policy %q returned %v instead of a bool for app %v; ignoring", r.config.Id, out.Value(), app.Metadata)
+		return
+	}
+	if !violated {
+		return
+	}
+
+	o.Violations = append(o.Violations, appmodel.PolicyViolation{
+		Policy:   r.config.Id,
+		Message:  r.config.Message,
+		Severity: r.severity.String(),
+		Blocking: r.config.Block,
+	})
+	msg := fmt.Sprintf("Policy %v: %v", r.config.Id, r.config.Message)
+	if r.config.Block {
+		o.Blockers = append(o.Blockers, msg)
+	} else {
+		o.Cautions = append(o.Cautions, msg)
+	}
+}
+
+// policyViolationsComment lists the app's policy violations, for use as a yaml comment; empty if none
+func policyViolationsComment(app *appmodel.App) string {
+	lines := []string{}
+	for _, v := range app.Analysis.Violations {
+		blocking := ""
+		if v.Blocking {
+			blocking = ", blocking"
+		}
+		lines = append(lines, fmt.Sprintf("Policy violation %v (%v%v): %v", v.Policy, v.Severity, blocking, v.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// policiesShown returns true if policy rules are configured, so that policy violations are shown in the table
+func policiesShown() bool {
+	for _, profile := range analysisProfiles.All() {
+		for _, rule := range profile.rules.Enabled() {
+			if rule.Category() == RULE_CATEGORY_POLICY {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policyCell returns the app's policy violations summary for the table and its color
+func policyCell(app *appmodel.App) (string, int) {
+	if len(app.Analysis.Violations) == 0 {
+		return "-", colorNone
+	}
+	color := colorYellow
+	ids := make([]string, 0, len(app.Analysis.Violations))
+	for _, v := range app.Analysis.Violations {
+		ids = append(ids, v.Policy)
+		if v.Blocking {
+			color = colorRed
+		}
+	}
+	return strings.Join(ids, ","), color
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestPolicyRule(t *testing.T) {
+	env, err := newPolicyEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prodGuaranteed := PolicyConfig{
+		Id:         "prod-guaranteed",
+		Expression: `has(app.metadata.namespace_labels) && app.metadata.namespace_labels["tier"] == "prod" && app.settings.qos_class != "guaranteed"`,
+		Message:    "Production workloads must be Guaranteed QoS",
+		Severity:   "critical",
+		Block:      true,
+	}
+	maxCores := PolicyConfig{
+		Id:         "max-cores",
+		Expression: `app.containers.exists(c, c.cpu.resource.request > 4.0)`,
+		Message:    "No container may request more than 4 cores",
+	}
+	mistyped := PolicyConfig{
+		Id:         "mistyped",
+		Expression: `app.setings.qos_class != "guaranteed"`,
+	}
+
+	app := func(tier string, qos string, cores float64) *appmodel.App {
+		a := &appmodel.App{Settings: appmodel.AppSettings{QosClass: qos}}
+		if tier != "" {
+			a.Metadata.NamespaceLabels = map[string]string{"tier": tier}
+		}
+		a.Containers = []appmodel.AppContainer{containerWithResources("web", cores, cores, 1e9, 1e9)}
+		return a
+	}
+	tests := []struct {
+		name     string
+		policy   PolicyConfig
+		app      *appmodel.App
+		violated bool
+	}{
+		{"prod burstable", prodGuaranteed, app("prod", appmodel.QOS_BURSTABLE, 1), true},
+		{"prod guaranteed", prodGuaranteed, app("prod", appmodel.QOS_GUARANTEED, 1), false},
+		{"dev burstable", prodGuaranteed, app("dev", appmodel.QOS_BURSTABLE, 1), false},
+		{"no namespace labels", prodGuaranteed, app("", appmodel.QOS_BURSTABLE, 1), false},
+		{"small container", maxCores, app("", appmodel.QOS_GUARANTEED, 2), false},
+		{"large container", maxCores, app("", appmodel.QOS_GUARANTEED, 8), true},
+		{"mistyped field", mistyped, app("", appmodel.QOS_BURSTABLE, 1), false},
+	}
+	for _, tt := range tests {
+		rule, err := newPolicyRule(env, tt.policy)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		o := &appmodel.AppAnalysis{Flags: make(map[appmodel.AppFlag]bool)}
+		rule.Evaluate(tt.app, o)
+		if (len(o.Violations) == 1) != tt.violated {
+			t.Errorf("%v: expected violated %v, got violations %+v", tt.name, tt.violated, o.Violations)
+			continue
+		}
+		if !tt.violated {
+			continue
+		}
+		if v := o.Violations[0]; v.Policy != tt.policy.Id || v.Blocking != tt.policy.Block {
+			t.Errorf("%v: unexpected violation %+v", tt.name, v)
+		}
+		if tt.policy.Block && len(o.Blockers) != 1 || !tt.policy.Block && len(o.Cautions) != 1 {
+			t.Errorf("%v: expected a blocker or caution, got blockers %v, cautions %v", tt.name, o.Blockers, o.Cautions)
+		}
+	}
+
+	invalid := []PolicyConfig{
+		{Expression: `true`},
+		{Id: "no-expression"},
+		{Id: "syntax", Expression: `app.settings.qos_class ==`},
+		{Id: "not-bool", Expression: `1 + 2`},
+		{Id: "severity", Expression: `true`, Severity: "dire"},
+	}
+	for _, config := range invalid {
+		if _, err := newPolicyRule(env, config); err == nil {
+			t.Errorf("expected an error for policy %+v", config)
+		}
+	}
+}
+
+func TestPolicyRulesSharedInput(t *testing.T) {
+	env, err := newPolicyEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := newPolicyRule(env, PolicyConfig{Id: "large", Expression: `app.containers.exists(c, c.cpu.resource.request > 4.0)`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newPolicyRule(env, PolicyConfig{Id: "large-web", Expression: `app.analysis.policy_violations.exists(v, v.policy == "large")`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &appmodel.App{Containers: []appmodel.AppContainer{containerWithResources("web", 8, 8, 1e9, 1e9)}}
+	appInput, err := policyAppInput(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &appmodel.AppAnalysis{Flags: make(map[appmodel.AppFlag]bool)}
+	first.evaluateInput(app, appInput, o)
+	second.evaluateInput(app, appInput, o)
+	if len(o.Violations) != 2 {
+		t.Errorf("expected the second policy to see the first one's violation, got %+v", o.Violations)
+	}
+	if analysis, ok := appInput["analysis"]; ok {
+		t.Errorf("expected the shared app input to be left without analysis, got %v", analysis)
+	}
+}
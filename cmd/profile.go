@@ -0,0 +1,261 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/spf13/viper"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+const (
+	PROFILE_CONSERVATIVE = "conservative"
+	PROFILE_BALANCED     = "balanced"
+	PROFILE_AGGRESSIVE   = "aggressive"
+)
+
+var profileName string
+
+// AnalysisProfile is a named set of analysis weights, thresholds and rule settings
+type AnalysisProfile struct {
+	Name              string  `yaml:"-"`
+	CpuWeight         float64 `yaml:"cpu_weight"`          // weight of CPU utilization in the efficiency rate
+	MemoryWeight      float64 `yaml:"memory_weight"`       // weight of memory utilization in the efficiency rate
+	ExcessiveCostRate int     `yaml:"excessive_cost_rate"` // efficiency rate below which cost is excessive
+	OptimizeRate      int     `yaml:"optimize_rate"`       // efficiency rate below which optimization is recommended
+	rules             *ruleRegistry
+}
+
+// ProfileSelector selects a profile for the apps in matching namespaces
+type ProfileSelector struct {
+	Profile   string            `mapstructure:"profile"`
+	Namespace string            `mapstructure:"namespace"` // namespace name or glob pattern (e.g., "batch-*"); any if empty
+	Labels    map[string]string `mapstructure:"labels"`    // namespace labels, all of which must match
+}
+
+func (s *ProfileSelector) matches(app *appmodel.App) bool {
+	if s.Namespace != "" {
+		if ok, _ := path.Match(s.Namespace, app.Metadata.Namespace); !ok {
+			return false
+		}
+	}
+	for name, value := range s.Labels {
+		if app.Metadata.NamespaceLabels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// const table - built-in profiles, as settings in the same form as profiles in the config file
+func getBuiltinProfiles() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		// risk-averse: flags saturation early, slower to call cost excessive
+		PROFILE_CONSERVATIVE: {
+			"excessive_cost_rate": 50,
+			"optimize_rate":       70,
+			"rules": map[string]interface{}{
+				"saturation-risk": map[string]interface{}{
+					"severe_utilization": 150, "severe_throttling": 0.5,
+					"high_utilization": 100, "high_throttling": 0.15,
+					"close_utilization": 75, "close_throttling": 0.05,
+				},
+			},
+		},
+		// the defaults
+		PROFILE_BALANCED: {},
+		// tolerates high utilization (e.g., batch processing), expects high efficiency
+		PROFILE_AGGRESSIVE: {
+			"excessive_cost_rate": 70,
+			"optimize_rate":       90,
+			"rules": map[string]interface{}{
+				"saturation-risk": map[string]interface{}{
+					"severe_utilization": 200, "severe_throttling": 0.7,
+					"high_utilization": 130, "high_throttling": 0.35,
+					"close_utilization": 95, "close_throttling": 0.2,
+				},
+			},
+		},
+	}
+}
+
+// profileSet holds the analysis profiles and selects the profile for each app
+type profileSet struct {
+	profiles       map[string]*AnalysisProfile
+	selectors      []ProfileSelector
+	defaultProfile string
+}
+
+// analysisProfiles are the profiles used by the analysis (see configureAnalysisProfiles)
+var analysisProfiles = mustBuildProfileSet()
+
+func mustBuildProfileSet() *profileSet {
+	set, err := buildProfileSet(nil, nil, nil, nil, "")
+	if err != nil {
+		panic(err) // built-in profiles must be valid
+	}
+	return set
+}
+
+// applyProfileSettings applies settings (in the config file form) to the profile and its rules
+func applyProfileSettings(p *AnalysisProfile, settings map[string]interface{}) error {
+	settings = copySettings(settings)
+	delete(settings, "base")
+	if rules, ok := settings["rules"]; ok {
+		rulesConfig, ok := rules.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("rules must be a map, found %v", rules)
+		}
+		if err := p.rules.Configure(rulesConfig); err != nil {
+			return err
+		}
+		delete(settings, "rules")
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+	return decodeSettings(p, settings)
+}
+
+// buildProfile builds a profile, starting from the balanced defaults: applies its base profile (for profiles
+// not built in), the built-in profile's settings, the rules config shared by all profiles and the profile's
+// own settings from the config file
+func buildProfile(name string, policies []*policyRule, rulesConfig map[string]interface{}, config map[string]interface{}) (*AnalysisProfile, error) {
+	p := &AnalysisProfile{
+		Name:              name,
+		CpuWeight:         0.6,
+		MemoryWeight:      0.4,
+		ExcessiveCostRate: 60,
+		OptimizeRate:      80,
+		rules:             newRuleRegistry(getBuiltinRules()...),
+	}
+	for _, policy := range policies {
+		if err := p.rules.Register(policy); err != nil {
+			return nil, err
+		}
+	}
+
+	builtin, ok := getBuiltinProfiles()[name]
+	if !ok {
+		base := PROFILE_BALANCED
+		if b, ok := config["base"]; ok {
+			base = fmt.Sprintf("%v", b)
+		}
+		if builtin, ok = getBuiltinProfiles()[base]; !ok {
+			return nil, fmt.Errorf("unknown base profile %q, must be one of the built-in profiles", base)
+		}
+	}
+	if err := applyProfileSettings(p, builtin); err != nil {
+		return nil, err
+	}
+	if err := p.rules.Configure(rulesConfig); err != nil {
+		return nil, err
+	}
+	if err := applyProfileSettings(p, config); err != nil {
+		return nil, err
+	}
+
+	if p.CpuWeight < 0 || p.MemoryWeight < 0 || p.CpuWeight+p.MemoryWeight <= 0 {
+		return nil, fmt.Errorf("cpu_weight and memory_weight must not be negative and must not both be 0")
+	}
+	return p, nil
+}
+
+func buildProfileSet(policies []*policyRule, rulesConfig map[string]interface{}, profilesConfig map[string]interface{}, selectors []ProfileSelector, defaultProfile string) (*profileSet, error) {
+	names := []string{}
+	for name := range getBuiltinProfiles() {
+		names = append(names, name)
+	}
+	for name := range profilesConfig {
+		if _, ok := getBuiltinProfiles()[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	set := &profileSet{profiles: make(map[string]*AnalysisProfile), selectors: selectors, defaultProfile: defaultProfile}
+	for _, name := range names {
+		config := map[string]interface{}{}
+		if c, ok := profilesConfig[name]; ok && c != nil {
+			if config, ok = c.(map[string]interface{}); !ok {
+				return nil, fmt.Errorf("settings for profile %q must be a map, found %v", name, c)
+			}
+		}
+		p, err := buildProfile(name, policies, rulesConfig, config)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %v", name, err)
+		}
+		set.profiles[name] = p
+	}
+
+	if set.defaultProfile == "" {
+		set.defaultProfile = PROFILE_BALANCED
+	}
+	if _, ok := set.profiles[set.defaultProfile]; !ok {
+		return nil, fmt.Errorf("unknown analysis profile %q, must be one of %v", set.defaultProfile, names)
+	}
+	for _, s := range selectors {
+		if _, ok := set.profiles[s.Profile]; !ok {
+			return nil, fmt.Errorf("unknown analysis profile %q in profile selectors, must be one of %v", s.Profile, names)
+		}
+		if _, err := path.Match(s.Namespace, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q in profile selectors: %v", s.Namespace, err)
+		}
+	}
+	return set, nil
+}
+
+// Default returns the profile selected for the run
+func (set *profileSet) Default() *AnalysisProfile {
+	return set.profiles[set.defaultProfile]
+}
+
+// ForApp returns the profile of the first selector matching the app, or the profile selected for the run
+func (set *profileSet) ForApp(app *appmodel.App) *AnalysisProfile {
+	for i := range set.selectors {
+		if set.selectors[i].matches(app) {
+			return set.profiles[set.selectors[i].Profile]
+		}
+	}
+	return set.Default()
+}
+
+// All returns the profiles, ordered by name
+func (set *profileSet) All() []*AnalysisProfile {
+	profiles := make([]*AnalysisProfile, 0, len(set.profiles))
+	for _, p := range set.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// configureAnalysisProfiles sets up the analysis profiles and their rules from the config file
+func configureAnalysisProfiles() error {
+	policies, err := loadPolicyRules()
+	if err != nil {
+		return err
+	}
+	var selectors []ProfileSelector
+	if err := viper.UnmarshalKey("profile_selectors", &selectors); err != nil {
+		return fmt.Errorf("invalid profile_selectors in config: %v", err)
+	}
+	set, err := buildProfileSet(policies, viper.GetStringMap("rules"), viper.GetStringMap("profiles"), selectors, viper.GetString("profile"))
+	if err != nil {
+		return err
+	}
+	analysisProfiles = set
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Analysis profile: conservative, balanced (default), aggressive or a profile defined in the config file")
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestProfileSet(t *testing.T) {
+	profilesConfig := map[string]interface{}{
+		"batch": map[string]interface{}{
+			"base":       PROFILE_AGGRESSIVE,
+			"cpu_weight": 0.8,
+			"rules": map[string]interface{}{
+				"replicas": map[string]interface{}{"enabled": false},
+			},
+		},
+		PROFILE_CONSERVATIVE: map[string]interface{}{"optimize_rate": 75},
+	}
+	rulesConfig := map[string]interface{}{
+		"request-rate": map[string]interface{}{"low_rate": 5},
+	}
+	selectors := []ProfileSelector{
+		{Profile: "batch", Namespace: "batch-*"},
+		{Profile: PROFILE_CONSERVATIVE, Labels: map[string]string{"tier": "prod"}},
+	}
+	set, err := buildProfileSet(nil, rulesConfig, profilesConfig, selectors, PROFILE_BALANCED)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// profile settings: built-in, base profile and config overrides
+	batch := set.profiles["batch"]
+	if batch.CpuWeight != 0.8 || batch.MemoryWeight != 0.4 || batch.ExcessiveCostRate != 70 || batch.rules.IsEnabled("replicas") {
+		t.Errorf("unexpected batch profile %+v (replicas enabled %v)", batch, batch.rules.IsEnabled("replicas"))
+	}
+	if risk := batch.rules.Lookup("saturation-risk").(*saturationRiskRule); risk.CloseUtilization != 95 {
+		t.Errorf("expected batch profile to inherit the aggressive saturation thresholds, got %+v", risk)
+	}
+	conservative := set.profiles[PROFILE_CONSERVATIVE]
+	if conservative.OptimizeRate != 75 || conservative.ExcessiveCostRate != 50 {
+		t.Errorf("expected the config to override the built-in conservative profile, got %+v", conservative)
+	}
+	for _, p := range set.All() {
+		if rule := p.rules.Lookup("request-rate").(*requestRateRule); rule.LowRate != 5 {
+			t.Errorf("profile %v: expected the shared rules config to apply, got %+v", p.Name, rule)
+		}
+	}
+
+	// profile selection
+	tests := []struct {
+		namespace string
+		labels    map[string]string
+		profile   string
+	}{
+		{"batch-jobs", nil, "batch"},
+		{"shop", map[string]string{"tier": "prod"}, PROFILE_CONSERVATIVE},
+		{"shop", map[string]string{"tier": "dev"}, PROFILE_BALANCED},
+		{"shop", nil, PROFILE_BALANCED},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: tt.namespace, NamespaceLabels: tt.labels}}
+		if p := set.ForApp(app); p.Name != tt.profile {
+			t.Errorf("%v %v: expected profile %v, got %v", tt.namespace, tt.labels, tt.profile, p.Name)
+		}
+	}
+
+	// invalid configurations
+	invalid := []struct {
+		name           string
+		profilesConfig map[string]interface{}
+		selectors      []ProfileSelector
+		defaultProfile string
+	}{
+		{"unknown default", nil, nil, "reckless"},
+		{"unknown selector profile", nil, []ProfileSelector{{Profile: "reckless"}}, ""},
+		{"unknown base", map[string]interface{}{"batch": map[string]interface{}{"base": "batch"}}, nil, ""},
+		{"unknown setting", map[string]interface{}{"batch": map[string]interface{}{"cpu": 1}}, nil, ""},
+		{"zero weights", map[string]interface{}{"batch": map[string]interface{}{"cpu_weight": 0, "memory_weight": 0}}, nil, ""},
+	}
+	for _, tt := range invalid {
+		if _, err := buildProfileSet(nil, nil, tt.profilesConfig, tt.selectors, tt.defaultProfile); err == nil {
+			t.Errorf("%v: expected an error", tt.name)
+		}
+	}
+}
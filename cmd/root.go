@@ -20,6 +20,7 @@ import (
 var cfgFile string
 var promUriString string
 var promUri *url.URL
+var clusterName string
 var timeStartString string
 var timeEndString string
 var timeStepString string
@@ -37,11 +38,16 @@ const (
 	OUTPUT_DETAIL      = "detail"
 	OUTPUT_YAML        = "yaml"
 	OUTPUT_SERVO       = "servo.yaml"
+	OUTPUT_DIFF        = "diff"
+	OUTPUT_PATCH       = "patch"
+	OUTPUT_HPA         = "hpa"
+	OUTPUT_VPA         = "vpa"
+	OUTPUT_MARKDOWN    = "markdown"
 )
 
 // constant table - format types, keep in sync with OUTPUT_xxx constants above
 func getOutputFormats() []string {
-	return []string{OUTPUT_INTERACTIVE, OUTPUT_TABLE, OUTPUT_DETAIL, OUTPUT_YAML, OUTPUT_SERVO}
+	return []string{OUTPUT_INTERACTIVE, OUTPUT_TABLE, OUTPUT_DETAIL, OUTPUT_YAML, OUTPUT_SERVO, OUTPUT_DIFF, OUTPUT_PATCH, OUTPUT_HPA, OUTPUT_VPA, OUTPUT_MARKDOWN}
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -74,14 +80,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.opsani-ignite.yaml)")
 
 	rootCmd.PersistentFlags().StringVarP(&promUriString, "prometheus-url", "p", "", "URI to Prometheus API (typically port-forwarded to localhost using kubectl)")
-	rootCmd.MarkPersistentFlagRequired("prometheus-url") // TODO: this doesn't seem to do anything, enforcing explicitly in parser function
+	// note: not marked as a required flag, since not all subcommands need it; enforcing explicitly in parser function
 	viper.BindPFlag("prometheus-url", rootCmd.PersistentFlags().Lookup("prometheus-url"))
+	rootCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "Name of the cluster, used to label the results")
 
 	rootCmd.PersistentFlags().StringVar(&timeStartString, "start", "-7d", "Analysis start time, in RFC3339 or relative form")
 	rootCmd.PersistentFlags().StringVar(&timeEndString, "end", "-0d", "Analysis end time, in RFC3339 or relative form")
-	rootCmd.PersistentFlags().StringVar(&timeStepString, "step", "1d", "Time resolution, in relative form")
+	rootCmd.PersistentFlags().StringVar(&timeStepString, "step", AUTO_STEP, "Time resolution, in relative form, or auto (1h, finer for time ranges shorter than 2 days)")
 
 	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", fmt.Sprintf("Output format (%v)", strings.Join(getOutputFormats(), "|")))
+	rootCmd.PersistentFlags().StringVar(&baselineFile, "baseline", "", "Previous results file (from -o yaml) to compare the current run against")
 	rootCmd.PersistentFlags().BoolVarP(&hideBlocked, "hide-blocked", "b", false, "Hide applications that don't meet optimization prerequisites")
 	rootCmd.PersistentFlags().BoolVar(&showDebug, "debug", false, "Display tracing/debug information to stderr")
 	rootCmd.PersistentFlags().BoolVarP(&suppressWarnings, "quiet", "q", false, "Suppress warning and info level messages")
@@ -139,6 +147,49 @@ func parseInstant(s string, option string) (instant time.Time, err error) {
 	return
 }
 
+// AUTO_STEP is the --step value that selects the time resolution from the time range
+const AUTO_STEP = "auto"
+
+// autoStep returns the time resolution for a time range: 1 hour, fine enough to see daily cycles, or for
+// time ranges shorter than 2 days, a resolution giving 48 samples (but at least 1 minute)
+func autoStep(start time.Time, end time.Time) time.Duration {
+	step := end.Sub(start) / 48
+	if step > time.Hour {
+		return time.Hour
+	}
+	if step < time.Minute {
+		return time.Minute
+	}
+	return step.Truncate(time.Minute)
+}
+
+func parseTimeRange(startString, endString, stepString string) (start time.Time, end time.Time, step time.Duration, err error) {
+	start, err = parseInstant(startString, "--start")
+	if err != nil {
+		return
+	}
+	end, err = parseInstant(endString, "--end")
+	if err != nil {
+		return
+	}
+	if stepString == AUTO_STEP {
+		step = autoStep(start, end)
+	} else if step, err = tparse.AbsoluteDuration(start, stepString); err != nil {
+		err = fmt.Errorf("Could not parse time resolution: %v", err)
+		return
+	}
+	if !start.Before(end) {
+		err = fmt.Errorf("Analysis start time must be earlier than end time")
+	} else if step < time.Minute {
+		err = fmt.Errorf("Analysis time resolution must be at least 1 minute (found %v)", step)
+	} else if step > 24*time.Hour {
+		err = fmt.Errorf("Analysis time resolution must be at shorter than a day (found %v)", step)
+	} else if end.Sub(start)/step < 2 {
+		err = fmt.Errorf("Analysis time & resolution should allow for at least 2 samples")
+	}
+	return
+}
+
 func validateFlags(cmd *cobra.Command, args []string) error {
 	var err error
 
@@ -167,31 +218,26 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("--output format must be one of %v", getOutputFormats())
 		}
 	}
+	if outputFormat == OUTPUT_DIFF && baselineFile == "" {
+		return fmt.Errorf("--output %v requires a --baseline file", OUTPUT_DIFF)
+	}
+	if saveHistory {
+		if _, err := historyRetentionPolicy(); err != nil {
+			return err
+		}
+	}
 
-	// -- Time intervals parse and check
-	timeStart, err = parseInstant(timeStartString, "--start")
-	if err != nil {
+	// set up analysis profiles and rules
+	if err := configureAnalysisProfiles(); err != nil {
 		return err
 	}
-	timeEnd, err = parseInstant(timeEndString, "--end")
+	mainContainerOverrides = viper.GetStringMapString("main_containers")
+
+	// -- Time intervals parse and check
+	timeStart, timeEnd, timeStep, err = parseTimeRange(timeStartString, timeEndString, timeStepString)
 	if err != nil {
 		return err
 	}
-	timeStep, err = tparse.AbsoluteDuration(timeStart, timeStepString)
-	if err != nil {
-		return fmt.Errorf("Could not parse time resolution: %v", err)
-	}
-	if !timeStart.Before(timeEnd) {
-		return fmt.Errorf("Analysis start time must be earlier than end time")
-	}
-	if timeStep < time.Minute {
-		return fmt.Errorf("Analysis time resolution must be at least 1 minute (found %v)", timeStep)
-	} else if timeStep > 24*time.Hour {
-		return fmt.Errorf("Analysis time resolution must be at shorter than a day (found %v)", timeStep)
-	}
-	if timeEnd.Sub(timeStart)/timeStep < 2 {
-		return fmt.Errorf("Analysis time & resolution should allow for at least 2 samples")
-	}
 
 	// check prometheus URI
 	return parseRequiredUriFlag(&promUri, promUriString, "-p/--prometheus-url")
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeRangeStep(t *testing.T) {
+	tests := []struct {
+		start, end, step string
+		expected         time.Duration
+	}{
+		{"-7d", "-0d", AUTO_STEP, time.Hour},
+		{"-2d", "-0d", AUTO_STEP, time.Hour},
+		{"-1d", "-0d", AUTO_STEP, 30 * time.Minute},
+		{"-30m", "-0d", AUTO_STEP, time.Minute},
+		{"-7d", "-0d", "1d", 24 * time.Hour},
+		{"-7d", "-0d", "15m", 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		_, _, step, err := parseTimeRange(tt.start, tt.end, tt.step)
+		if err != nil || step != tt.expected {
+			t.Errorf("%v to %v, step %v: expected %v, got %v (%v)", tt.start, tt.end, tt.step, tt.expected, step, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+// AnalysisRule is a single analysis heuristic: it evaluates an application and updates its analysis
+// (flags, cautions, blockers, recommendations, reliability risk and score contributions)
+type AnalysisRule interface {
+	Id() string
+	Category() string
+	Severity() RuleSeverity
+	Description() string
+	Evaluate(app *appmodel.App, o *appmodel.AppAnalysis)
+}
+
+const (
+	RULE_CATEGORY_WORKLOAD    = "workload"
+	RULE_CATEGORY_RESOURCES   = "resources"
+	RULE_CATEGORY_UTILIZATION = "utilization"
+	RULE_CATEGORY_TRAFFIC     = "traffic"
+	RULE_CATEGORY_SCALING     = "scaling"
+	RULE_CATEGORY_RELIABILITY = "reliability"
+	RULE_CATEGORY_POLICY      = "policy" // rules defined in the config file
+)
+
+type RuleSeverity int
+
+const (
+	SEVERITY_INFO RuleSeverity = iota
+	SEVERITY_WARNING
+	SEVERITY_CRITICAL
+)
+
+// const table - severity names, keep in sync with SEVERITY_xxx constants above
+func getRuleSeverityNames() []string {
+	return []string{"info", "warning", "critical"}
+}
+
+func (s RuleSeverity) String() string {
+	return getRuleSeverityNames()[s]
+}
+
+func parseRuleSeverity(name string) (RuleSeverity, error) {
+	for index, n := range getRuleSeverityNames() {
+		if strings.EqualFold(name, n) {
+			return RuleSeverity(index), nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognized severity %q, must be one of %v", name, getRuleSeverityNames())
+}
+
+// ruleRegistry holds the analysis rules, in evaluation order, and which of them are enabled
+type ruleRegistry struct {
+	rules    []AnalysisRule
+	disabled map[string]bool
+}
+
+func newRuleRegistry(rules ...AnalysisRule) *ruleRegistry {
+	r := &ruleRegistry{disabled: make(map[string]bool)}
+	for _, rule := range rules {
+		if err := r.Register(rule); err != nil {
+			panic(err) // built-in rules must have unique ids
+		}
+	}
+	return r
+}
+
+// Register adds a rule, to be evaluated after the rules already registered
+func (r *ruleRegistry) Register(rule AnalysisRule) error {
+	if r.Lookup(rule.Id()) != nil {
+		return fmt.Errorf("duplicate analysis rule %q", rule.Id())
+	}
+	r.rules = append(r.rules, rule)
+	return nil
+}
+
+// Lookup returns the rule with the given id, or nil if there is no such rule
+func (r *ruleRegistry) Lookup(id string) AnalysisRule {
+	for _, rule := range r.rules {
+		if rule.Id() == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *ruleRegistry) SetEnabled(id string, enabled bool) error {
+	if r.Lookup(id) == nil {
+		return fmt.Errorf("unknown analysis rule %q", id)
+	}
+	r.disabled[id] = !enabled
+	return nil
+}
+
+func (r *ruleRegistry) IsEnabled(id string) bool {
+	return !r.disabled[id]
+}
+
+// All returns all registered rules, enabled or not
+func (r *ruleRegistry) All() []AnalysisRule {
+	return r.rules
+}
+
+// Enabled returns the enabled rules, in evaluation order
+func (r *ruleRegistry) Enabled() []AnalysisRule {
+	rules := make([]AnalysisRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		if r.IsEnabled(rule.Id()) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Configure applies the rules section of the config file: a map of rule ids to their settings, where
+// "enabled" enables or disables the rule and the other settings replace the rule's defaults, e.g.:
+//
+//	rules:
+//	  replicas:
+//	    many_min: 10
+//	  qos-risk:
+//	    enabled: false
+func (r *ruleRegistry) Configure(config map[string]interface{}) error {
+	ids := make([]string, 0, len(config))
+	for id := range config {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		rule := r.Lookup(id)
+		if rule == nil {
+			return fmt.Errorf("unknown analysis rule %q in config", id)
+		}
+		if config[id] == nil {
+			continue
+		}
+		settings, ok := config[id].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("settings for analysis rule %q must be a map, found %v", id, config[id])
+		}
+		settings = copySettings(settings)
+		if enabled, ok := settings["enabled"]; ok {
+			b, ok := enabled.(bool)
+			if !ok {
+				return fmt.Errorf("analysis rule %q: enabled must be true or false, found %v", id, enabled)
+			}
+			r.SetEnabled(id, b)
+			delete(settings, "enabled")
+		}
+		if len(settings) == 0 {
+			continue
+		}
+		if err := decodeSettings(rule, settings); err != nil {
+			return fmt.Errorf("analysis rule %q: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func copySettings(settings map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		c[k] = v
+	}
+	return c
+}
+
+// decodeSettings sets the target's fields from the settings, using the fields' yaml tags; unknown settings are rejected
+func decodeSettings(target interface{}, settings map[string]interface{}) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(target)
+}
+
+// rulesCmd represents the rules command
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "List the analysis rules",
+	Long: `Lists the rules used to analyze applications, with their settings in the
+selected analysis profile (--profile).
+
+Rules can be disabled or configured in the config file, under the "rules" key,
+by rule id; for example:
+
+  rules:
+    qos-risk:
+      enabled: false
+    replicas:
+      many_min: 10
+
+Profiles can override rule settings the same way, under "profiles.<name>.rules".`,
+	Args: cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return configureAnalysisProfiles()
+	},
+	RunE: runRules,
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+}
+
+func runRules(cmd *cobra.Command, args []string) error {
+	profile := analysisProfiles.Default()
+	fmt.Printf("Profile %v: CPU weight %g, memory weight %g, excessive cost below %v%% efficiency, optimize below %v%% efficiency\n\n",
+		profile.Name, profile.CpuWeight, profile.MemoryWeight, profile.ExcessiveCostRate, profile.OptimizeRate)
+
+	t := tablewriter.NewWriter(os.Stdout)
+	t.SetHeader([]string{"Rule", "Category", "Severity", "Enabled", "Description", "Settings"})
+	t.SetAutoWrapText(false)
+	t.SetBorder(false)
+	for _, rule := range profile.rules.All() {
+		settings, err := yaml.Marshal(rule)
+		if err != nil {
+			return err
+		}
+		s := string(bytes.TrimSpace(settings))
+		if s == "{}" {
+			s = ""
+		}
+		t.Append([]string{rule.Id(), rule.Category(), rule.Severity().String(), fmt.Sprintf("%v", profile.rules.IsEnabled(rule.Id())), rule.Description(), s})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,713 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	opsmath "opsani-ignite/math"
+)
+
+// const table - built-in analysis rules with their default settings, in evaluation order
+func getBuiltinRules() []AnalysisRule {
+	return []AnalysisRule{
+		&mainContainerRule{},
+		&multiContainerRule{},
+		&writeableVolumeRule{},
+		&resourceSpecRule{},
+		&utilizationRule{
+			Ratings:    getResourceUtilizationRatingsTable(),
+			Confidence: 30,
+		},
+		&workloadPatternRule{
+			IdleCpu:             0.01,
+			IdleRate:            0.1,
+			SteadyCv:            0.15,
+			SeasonalCorrelation: 0.5,
+			BurstyCv:            0.5,
+			BurstyPeakRatio:     3,
+			BatchPeakRatio:      10,
+			LowRate:             10,
+		},
+		&requestRateRule{
+			LowRate:    2,
+			LowRating:  -10,
+			HighRate:   100,
+			HighRating: 10,
+		},
+		&capacityModelRule{
+			MinSamples:        6,
+			MinTrafficCv:      0.1,
+			MinFit:            0.5,
+			TargetUtilization: 70,
+			TrafficMultiplier: 2,
+		},
+		&replicasRule{
+			SingleMax:         1,
+			SingleRating:      -20,
+			SingleConfidence:  10,
+			SeveralMin:        3,
+			SeveralRating:     10,
+			SeveralConfidence: 10,
+			ManyMin:           7,
+			ManyRating:        20,
+			ManyConfidence:    30,
+		},
+		&autoscalingRule{
+			MinSamples:      6,
+			CpuTarget:       70,
+			BurstyCpuTarget: 50,
+			MinReplicas:     2,
+			Headroom:        1.5,
+			BoundFraction:   0.1,
+			TargetTolerance: 15,
+		},
+		&loadImbalanceRule{
+			MaxMeanRatio: 2,
+			Gini:         0.3,
+			MinCpu:       0.05,
+			MinRate:      1,
+		},
+		&sidecarOverheadRule{
+			CostShare: 0.2,
+		},
+		&initContainersRule{
+			Excess: 0.1,
+		},
+		&qosRiskRule{},
+		&saturationRiskRule{
+			SevereUtilization: 200,
+			SevereThrottling:  0.7,
+			HighUtilization:   120,
+			HighThrottling:    0.25,
+			CloseUtilization:  90,
+			CloseThrottling:   0.1,
+		},
+		&memoryGrowthRule{
+			Days:         7,
+			MinFit:       0.7,
+			DropFraction: 0.3,
+			MinDrops:     2,
+		},
+		&ephemeralStorageRule{
+			HighUsage:      0.9,
+			CloseUsage:     0.7,
+			MinUnrequested: 100 * 1024 * 1024,
+		},
+	}
+}
+
+// --- Workload ---------------------------------------------------------------
+
+type mainContainerRule struct{}
+
+func (r *mainContainerRule) Id() string             { return "main-container" }
+func (r *mainContainerRule) Category() string       { return RULE_CATEGORY_WORKLOAD }
+func (r *mainContainerRule) Severity() RuleSeverity { return SEVERITY_CRITICAL }
+func (r *mainContainerRule) Description() string {
+	return "The container to optimize must be identified"
+}
+
+func (r *mainContainerRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	if app.Analysis.MainContainer != "" {
+		o.Flags[appmodel.F_MAIN_CONTAINER] = true
+	} else {
+		o.Blockers = append(o.Blockers, "Could not identify main container")
+		o.Flags[appmodel.F_MAIN_CONTAINER] = false
+	}
+}
+
+type multiContainerRule struct{}
+
+func (r *multiContainerRule) Id() string             { return "multi-container" }
+func (r *multiContainerRule) Category() string       { return RULE_CATEGORY_WORKLOAD }
+func (r *multiContainerRule) Severity() RuleSeverity { return SEVERITY_INFO }
+func (r *multiContainerRule) Description() string    { return "Marks pods with more than one container" }
+
+func (r *multiContainerRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	if count := len(app.Containers); count > 0 {
+		o.Flags[appmodel.F_MULTI_CONTAINER] = count > 1 // flag not set if no container info
+	}
+}
+
+type writeableVolumeRule struct{}
+
+func (r *writeableVolumeRule) Id() string             { return "writeable-volume" }
+func (r *writeableVolumeRule) Category() string       { return RULE_CATEGORY_WORKLOAD }
+func (r *writeableVolumeRule) Severity() RuleSeverity { return SEVERITY_CRITICAL }
+func (r *writeableVolumeRule) Description() string {
+	return "Stateful applications (with writeable volumes) cannot be optimized"
+}
+
+func (r *writeableVolumeRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	// having a writeable PVC disqualifies the app immediately (stateful)
+	if app.Settings.WriteableVolume {
+		o.Blockers = append(o.Blockers, "Stateful: pods have writeable volumes")
+		o.Flags[appmodel.F_WRITEABLE_VOLUME] = true
+	} else {
+		o.Flags[appmodel.F_WRITEABLE_VOLUME] = false
+	}
+}
+
+// --- Resources --------------------------------------------------------------
+
+type resourceSpecRule struct{}
+
+func (r *resourceSpecRule) Id() string             { return "resource-spec" }
+func (r *resourceSpecRule) Category() string       { return RULE_CATEGORY_RESOURCES }
+func (r *resourceSpecRule) Severity() RuleSeverity { return SEVERITY_CRITICAL }
+func (r *resourceSpecRule) Description() string {
+	return "The main container must have CPU and memory resources specified"
+}
+
+func (r *resourceSpecRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	o.Flags[appmodel.F_RESOURCE_GUARANTEED] = app.Settings.QosClass == appmodel.QOS_GUARANTEED
+	o.Flags[appmodel.F_RESOURCE_LIMITS] = resourcesLimited(app)
+	if resGood, msg := resourcesExplicitlyDefined(app); resGood {
+		o.Flags[appmodel.F_RESOURCE_SPEC] = true
+	} else {
+		o.Flags[appmodel.F_RESOURCE_SPEC] = false
+		o.Blockers = append(o.Blockers, msg)
+		o.Recommendations = append(o.Recommendations, "Define resource levels to improve reliability")
+	}
+}
+
+// --- Utilization ------------------------------------------------------------
+
+type ResourceUtilizationRating struct {
+	UtilizationFloor float64 `yaml:"floor"`
+	RatingBump       int     `yaml:"rating"`
+}
+
+// const table
+func getResourceUtilizationRatingsTable() []ResourceUtilizationRating {
+	return []ResourceUtilizationRating{
+		{100, 30}, // >=100 provides opportunity to improve performance/rightsize
+		{80, 10},  // 80..100 likely not much room to optimize
+		{40, 40},  // 40..80 some optimization room
+		{1, 60},   // 1..40 likely lots to optimize
+		{0, 0},    // no utilization - likely can't optimize
+	}
+}
+
+func utilizationRating(ratings []ResourceUtilizationRating, v float64) int {
+	for _, r := range ratings {
+		if v >= r.UtilizationFloor {
+			return r.RatingBump
+		}
+	}
+	return 0
+}
+
+func utilizationCombinedRating(ratings []ResourceUtilizationRating, cpuUtil, memUtil float64) int {
+	// convert resource utilization % to rating bump, for each resource separately
+	cpuBump, memBump := utilizationRating(ratings, cpuUtil), utilizationRating(ratings, memUtil)
+
+	// if rating is 0 for any resource, use 0
+	if cpuBump == 0 || memBump == 0 {
+		return 0
+	}
+
+	// average bump
+	return (cpuBump + memBump) / 2
+}
+
+type utilizationRule struct {
+	Ratings    []ResourceUtilizationRating `yaml:"ratings"`    // rating bump by utilization floor, highest floor first
+	Confidence int                         `yaml:"confidence"` // confidence added when utilization is known
+}
+
+func (r *utilizationRule) Id() string             { return "utilization" }
+func (r *utilizationRule) Category() string       { return RULE_CATEGORY_UTILIZATION }
+func (r *utilizationRule) Severity() RuleSeverity { return SEVERITY_INFO }
+func (r *utilizationRule) Description() string {
+	return "Rates the optimization opportunity by CPU and memory utilization"
+}
+
+func (r *utilizationRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	o.Flags[appmodel.F_UTILIZATION] = app.Metrics.CpuUtilization > 0 && app.Metrics.MemoryUtilization > 0
+	utilBump := utilizationCombinedRating(r.Ratings, app.Metrics.CpuUtilization, app.Metrics.MemoryUtilization)
+	if utilBump == 0 {
+		return
+	}
+	contribute(o, r.Id(), "Resource utilization", utilBump, r.Confidence, map[string]float64{
+		"cpu_utilization":    app.Metrics.CpuUtilization,
+		"memory_utilization": app.Metrics.MemoryUtilization,
+	})
+	if app.Metrics.CpuUtilization >= 100 || app.Metrics.MemoryUtilization >= 100 {
+		o.Opportunities = append(o.Opportunities, "Improve performance/reliability")
+		o.Flags[appmodel.F_BURST] = true
+	} else if utilBump >= 30 {
+		effImpr := efficiencyImprovementEstimate(app)
+		if effImpr != "" {
+			effImpr = " by " + effImpr
+		}
+		o.Opportunities = append(o.Opportunities, fmt.Sprintf("Improve efficiency%v", effImpr))
+		o.Flags[appmodel.F_BURST] = false
+	}
+}
+
+// patternStats are the statistics a usage series is classified by
+type patternStats struct {
+	Mean      float64
+	Cv        float64 // coefficient of variation
+	PeakRatio float64 // peak to median ratio
+	Daily     float64 // autocorrelation at a 24h lag, NaN if the series is too coarse or short
+	Weekly    float64 // autocorrelation at a 7d lag, NaN if the series is too coarse or short
+}
+
+// minimum number of samples per day and per week to detect daily and weekly cycles
+const (
+	PATTERN_DAILY_MIN_SAMPLES  = 4
+	PATTERN_WEEKLY_MIN_SAMPLES = 7
+)
+
+// warnPatternStep warns if the step is too coarse to detect daily cycles, so no app can be
+// classified as diurnal (e.g., with --step 1d)
+func warnPatternStep(step time.Duration) {
+	if maxStep := 24 * time.Hour / PATTERN_DAILY_MIN_SAMPLES; step > maxStep {
+		log.Warnf("Step %v is too coarse to detect daily usage cycles, leaving the pattern of apps with little variation undetermined; use a step of %v or less (or --step %v) to have diurnal apps and their autoscaling recommendations reported", step, maxStep, AUTO_STEP)
+	}
+}
+
+// seasonalLag returns the number of samples in the period, if the series' step allows detecting
+// a pattern repeating with the period (at least minSamples samples per period); 0 otherwise
+func seasonalLag(series appmodel.TimeSeries, period time.Duration, minSamples int) int {
+	if len(series) < 2 {
+		return 0
+	}
+	steps := make([]float64, 0, len(series)-1)
+	for i := 1; i < len(series); i++ {
+		steps = append(steps, float64(series[i].Time.Sub(series[i-1].Time)))
+	}
+	step := opsmath.Median(steps...)
+	if step <= 0 {
+		return 0
+	}
+	lag := int(math.Round(float64(period) / step))
+	if lag < minSamples {
+		return 0
+	}
+	return lag
+}
+
+func calcPatternStats(series appmodel.TimeSeries) patternStats {
+	values := series.Values()
+	stats := patternStats{
+		Mean:      opsmath.Avg(values...),
+		Cv:        opsmath.CoefficientOfVariation(values...),
+		PeakRatio: opsmath.Max(values...) / opsmath.Median(values...),
+		Daily:     math.NaN(),
+		Weekly:    math.NaN(),
+	}
+	if lag := seasonalLag(series, 24*time.Hour, PATTERN_DAILY_MIN_SAMPLES); lag > 0 {
+		stats.Daily = opsmath.Autocorrelation(values, lag)
+	}
+	if lag := seasonalLag(series, 7*24*time.Hour, PATTERN_WEEKLY_MIN_SAMPLES); lag > 0 {
+		stats.Weekly = opsmath.Autocorrelation(values, lag)
+	}
+	return stats
+}
+
+type workloadPatternRule struct {
+	IdleCpu             float64 `yaml:"idle_cpu"`             // average CPU use (cores) below which the app is idle
+	IdleRate            float64 `yaml:"idle_rate"`            // average request rate (per second) below which the app is idle
+	SteadyCv            float64 `yaml:"steady_cv"`            // coefficient of variation below which use is steady
+	SeasonalCorrelation float64 `yaml:"seasonal_correlation"` // autocorrelation at a 24h/7d lag at or above which use is diurnal/weekly
+	BurstyCv            float64 `yaml:"bursty_cv"`            // coefficient of variation at or above which use is bursty
+	BurstyPeakRatio     float64 `yaml:"bursty_peak_ratio"`    // peak-to-median ratio at or above which use is bursty
+	BatchPeakRatio      float64 `yaml:"batch_peak_ratio"`     // peak-to-median ratio at or above which use is batch/periodic
+	LowRate             float64 `yaml:"low_rate"`             // request rate (per second) below which a bursty app has low traffic
+}
+
+func (r *workloadPatternRule) Id() string             { return "workload-pattern" }
+func (r *workloadPatternRule) Category() string       { return RULE_CATEGORY_UTILIZATION }
+func (r *workloadPatternRule) Severity() RuleSeverity { return SEVERITY_INFO }
+func (r *workloadPatternRule) Description() string {
+	return "Classifies CPU use and traffic over time as steady, diurnal, weekly, bursty, batch or idle"
+}
+
+// classify returns the usage pattern of a series; idleMean is the average below which the app is idle
+func (r *workloadPatternRule) classify(series appmodel.TimeSeries, idleMean float64) (appmodel.WorkloadPattern, patternStats) {
+	if len(series) < 3 {
+		return appmodel.PATTERN_UNKNOWN, patternStats{}
+	}
+	s := calcPatternStats(series)
+	var steady appmodel.WorkloadPattern = appmodel.PATTERN_STEADY
+	if seasonalLag(series, 24*time.Hour, PATTERN_DAILY_MIN_SAMPLES) == 0 {
+		// the step is too coarse to see a daily cycle (averaging over each step flattens it), so use that
+		// looks steady may well be diurnal
+		steady = appmodel.PATTERN_UNKNOWN
+	}
+	switch {
+	case s.Mean < idleMean:
+		return appmodel.PATTERN_IDLE, s
+	case math.IsNaN(s.Cv) || s.Cv < r.SteadyCv:
+		return steady, s
+	case s.PeakRatio >= r.BatchPeakRatio: // mostly quiet, with runs of heavy use
+		return appmodel.PATTERN_BATCH, s
+	case s.Daily >= r.SeasonalCorrelation:
+		return appmodel.PATTERN_DIURNAL, s
+	case s.Weekly >= r.SeasonalCorrelation:
+		return appmodel.PATTERN_WEEKLY, s
+	case s.Cv >= r.BurstyCv || s.PeakRatio >= r.BurstyPeakRatio:
+		return appmodel.PATTERN_BURSTY, s
+	}
+	return steady, s
+}
+
+func seasonal(p appmodel.WorkloadPattern) bool {
+	return p == appmodel.PATTERN_DIURNAL || p == appmodel.PATTERN_WEEKLY
+}
+
+func (r *workloadPatternRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	// CPU use of the main container (or the only container)
+	var cpuSeries appmodel.TimeSeries
+	if index, ok := app.ContainerIndexByName(app.Analysis.MainContainer); ok {
+		cpuSeries = app.Containers[index].Series.Cpu
+	} else if len(app.Containers) == 1 {
+		cpuSeries = app.Containers[0].Series.Cpu
+	}
+	cpuPattern, cpuStats := r.classify(cpuSeries, r.IdleCpu)
+	ratePattern, rateStats := r.classify(app.Series.RequestRate, r.IdleRate)
+	log.Tracef("Workload pattern for app %v: CPU %v %+v, request rate %v %+v", app.Metadata, cpuPattern, cpuStats, ratePattern, rateStats)
+
+	// classify by CPU use; traffic shows seasonality more clearly and says whether an app is really idle
+	o.Pattern = cpuPattern
+	if cpuPattern == appmodel.PATTERN_UNKNOWN ||
+		cpuPattern == appmodel.PATTERN_IDLE && ratePattern != appmodel.PATTERN_IDLE && ratePattern != appmodel.PATTERN_UNKNOWN ||
+		!seasonal(cpuPattern) && seasonal(ratePattern) {
+		o.Pattern = ratePattern
+	}
+
+	switch o.Pattern {
+	case appmodel.PATTERN_DIURNAL:
+		if !app.Settings.HpaEnabled {
+			o.Recommendations = append(o.Recommendations, "Use a horizontal pod autoscaler (HPA) to follow the daily load cycle")
+		}
+	case appmodel.PATTERN_WEEKLY:
+		if !app.Settings.HpaEnabled {
+			o.Recommendations = append(o.Recommendations, "Use a horizontal pod autoscaler (HPA) to follow the weekly load cycle")
+		}
+	case appmodel.PATTERN_BURSTY:
+		o.Flags[appmodel.F_BURST] = true
+		if app.Metrics.RequestRate < r.LowRate && app.Settings.QosClass == appmodel.QOS_GUARANTEED {
+			o.Recommendations = append(o.Recommendations, "Use Burstable QoS (limits above requests) to absorb usage spikes at low traffic")
+		}
+	case appmodel.PATTERN_BATCH:
+		o.Recommendations = append(o.Recommendations, "Consider running the periodic work as a Job or CronJob instead of a Deployment")
+	case appmodel.PATTERN_IDLE:
+		o.Opportunities = append(o.Opportunities, "Scale down or remove idle application")
+	}
+}
+
+// --- Traffic ----------------------------------------------------------------
+
+type requestRateRule struct {
+	LowRate    float64 `yaml:"low_rate"`    // request rate (per second) below which traffic is considered low
+	LowRating  int     `yaml:"low_rating"`  // rating delta for low traffic
+	HighRate   float64 `yaml:"high_rate"`   // request rate (per second) above which traffic is considered high
+	HighRating int     `yaml:"high_rating"` // rating delta for high traffic
+}
+
+func (r *requestRateRule) Id() string             { return "request-rate" }
+func (r *requestRateRule) Category() string       { return RULE_CATEGORY_TRAFFIC }
+func (r *requestRateRule) Severity() RuleSeverity { return SEVERITY_WARNING }
+func (r *requestRateRule) Description() string {
+	return "Applications must process requests; more traffic makes a better candidate"
+}
+
+func (r *requestRateRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	inputs := map[string]float64{"request_rate": app.Metrics.RequestRate}
+	if app.Metrics.RequestRate == 0 {
+		o.Blockers = append(o.Blockers, "No requests are being processed")
+		o.Flags[appmodel.F_TRAFFIC] = false
+	} else if app.Metrics.RequestRate < r.LowRate {
+		o.Cautions = append(o.Cautions, "Low request rate")
+		contribute(o, r.Id(), fmt.Sprintf("Less than %g requests/sec", r.LowRate), r.LowRating, 0, inputs)
+		// note: don't set traffic flag
+	} else {
+		o.Flags[appmodel.F_TRAFFIC] = true
+		if app.Metrics.RequestRate > r.HighRate {
+			// low confidence as we don't know if traffic is served or originated
+			contribute(o, r.Id(), fmt.Sprintf("More than %g requests/sec", r.HighRate), r.HighRating, 0, inputs)
+		}
+	}
+}
+
+// --- Scaling ----------------------------------------------------------------
+
+type replicasRule struct {
+	SingleMax         float64 `yaml:"single_max"`         // average replica count at or below which the app is considered single-replica
+	SingleRating      int     `yaml:"single_rating"`      // rating delta for a single replica
+	SingleConfidence  int     `yaml:"single_confidence"`  // confidence delta for a single replica
+	SeveralMin        float64 `yaml:"several_min"`        // average replica count above which the app has several replicas
+	SeveralRating     int     `yaml:"several_rating"`     // rating delta for several replicas
+	SeveralConfidence int     `yaml:"several_confidence"` // confidence delta for several replicas
+	ManyMin           float64 `yaml:"many_min"`           // average replica count at or above which the app has many replicas
+	ManyRating        int     `yaml:"many_rating"`        // rating delta for many replicas
+	ManyConfidence    int     `yaml:"many_confidence"`    // confidence delta for many replicas
+}
+
+func (r *replicasRule) Id() string             { return "replicas" }
+func (r *replicasRule) Category() string       { return RULE_CATEGORY_SCALING }
+func (r *replicasRule) Severity() RuleSeverity { return SEVERITY_WARNING }
+func (r *replicasRule) Description() string {
+	return "Rates the optimization opportunity by the average replica count"
+}
+
+func (r *replicasRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	replicas := app.Metrics.AverageReplicas
+	inputs := map[string]float64{"average_replicas": replicas}
+	if replicas <= r.SingleMax {
+		contribute(o, r.Id(), "Less than 2 replicas", r.SingleRating, r.SingleConfidence, inputs)
+		o.Cautions = append(o.Cautions, "Less than 2 replicas")
+		o.Flags[appmodel.F_SINGLE_REPLICA] = true
+		o.Flags[appmodel.F_MANY_REPLICAS] = false
+	} else if replicas >= r.ManyMin {
+		contribute(o, r.Id(), fmt.Sprintf("%g or more replicas", r.ManyMin), r.ManyRating, r.ManyConfidence, inputs)
+		o.Flags[appmodel.F_SINGLE_REPLICA] = false
+		o.Flags[appmodel.F_MANY_REPLICAS] = true
+	} else {
+		if replicas > r.SeveralMin {
+			contribute(o, r.Id(), fmt.Sprintf("More than %g replicas", r.SeveralMin), r.SeveralRating, r.SeveralConfidence, inputs)
+		}
+		o.Flags[appmodel.F_SINGLE_REPLICA] = false
+		o.Flags[appmodel.F_MANY_REPLICAS] = false
+	}
+}
+
+type loadImbalanceRule struct {
+	MaxMeanRatio float64 `yaml:"max_mean_ratio"` // busiest pod's load relative to the average at or above which load is skewed
+	Gini         float64 `yaml:"gini"`           // Gini coefficient of the pods' load at or above which load is skewed
+	MinCpu       float64 `yaml:"min_cpu"`        // average CPU use (cores per pod) below which CPU imbalance is ignored
+	MinRate      float64 `yaml:"min_rate"`       // average request rate (per pod) below which traffic imbalance is ignored
+}
+
+func (r *loadImbalanceRule) Id() string             { return "load-imbalance" }
+func (r *loadImbalanceRule) Category() string       { return RULE_CATEGORY_SCALING }
+func (r *loadImbalanceRule) Severity() RuleSeverity { return SEVERITY_WARNING }
+func (r *loadImbalanceRule) Description() string {
+	return "Load should be spread evenly across the replicas"
+}
+
+// podAverages returns each pod's average over its series, ordered by pod name
+func podAverages(seriesByPod map[string]appmodel.TimeSeries) []float64 {
+	pods := make([]string, 0, len(seriesByPod))
+	for pod := range seriesByPod {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+	averages := make([]float64, 0, len(pods))
+	for _, pod := range pods {
+		if len(seriesByPod[pod]) > 0 {
+			averages = append(averages, opsmath.Avg(seriesByPod[pod].Values()...))
+		}
+	}
+	return averages
+}
+
+// imbalance returns the max/mean ratio and Gini coefficient of the pods' averages, or 0s if there are less
+// than 2 pods or their average is below minMean
+func imbalance(averages []float64, minMean float64) (ratio float64, gini float64) {
+	if len(averages) < 2 {
+		return 0, 0
+	}
+	mean := opsmath.Sum(averages...) / float64(len(averages))
+	if mean <= 0 || mean < minMean {
+		return 0, 0
+	}
+	return opsmath.Max(averages...) / mean, opsmath.Gini(averages...)
+}
+
+func (r *loadImbalanceRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	var cpuByPod map[string]appmodel.TimeSeries
+	if index, ok := app.ContainerIndexByName(app.Analysis.MainContainer); ok {
+		cpuByPod = app.Containers[index].Series.CpuByPod
+	} else if len(app.Containers) == 1 {
+		cpuByPod = app.Containers[0].Series.CpuByPod
+	}
+	cpuAverages, rateAverages := podAverages(cpuByPod), podAverages(app.Series.RequestRateByPod)
+	if len(cpuAverages) < 2 && len(rateAverages) < 2 {
+		return // single pod or no per-pod data
+	}
+
+	l := &appmodel.LoadImbalance{Pods: len(cpuAverages)}
+	if len(rateAverages) > l.Pods {
+		l.Pods = len(rateAverages)
+	}
+	l.CpuMaxMeanRatio, l.CpuGini = imbalance(cpuAverages, r.MinCpu)
+	l.RequestRateMaxMeanRatio, l.RequestRateGini = imbalance(rateAverages, r.MinRate)
+	o.LoadImbalance = l
+
+	skewed := []string{}
+	if l.CpuMaxMeanRatio >= r.MaxMeanRatio || l.CpuGini >= r.Gini {
+		skewed = append(skewed, fmt.Sprintf("CPU max/mean %.1f, Gini %.2f", l.CpuMaxMeanRatio, l.CpuGini))
+	}
+	if l.RequestRateMaxMeanRatio >= r.MaxMeanRatio || l.RequestRateGini >= r.Gini {
+		skewed = append(skewed, fmt.Sprintf("requests max/mean %.1f, Gini %.2f", l.RequestRateMaxMeanRatio, l.RequestRateGini))
+	}
+	if len(skewed) > 0 {
+		o.Cautions = append(o.Cautions, fmt.Sprintf("Load is unevenly spread across %v pods (%v); check for sticky sessions, load balancing or hot partitions", l.Pods, strings.Join(skewed, "; ")))
+	}
+}
+
+// --- Reliability ------------------------------------------------------------
+
+type qosRiskRule struct{}
+
+func (r *qosRiskRule) Id() string             { return "qos-risk" }
+func (r *qosRiskRule) Category() string       { return RULE_CATEGORY_RELIABILITY }
+func (r *qosRiskRule) Severity() RuleSeverity { return SEVERITY_WARNING }
+func (r *qosRiskRule) Description() string {
+	return "Pods that are not in the Guaranteed QoS class are at risk of eviction"
+}
+
+func (r *qosRiskRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	if app.Settings.QosClass == appmodel.QOS_BESTEFFORT {
+		o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_HIGH)
+		o.Cautions = append(o.Cautions, "Pod QoS class is Best Effort")
+	} else if app.Settings.QosClass != appmodel.QOS_GUARANTEED {
+		o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_MEDIUM)
+		o.Cautions = append(o.Cautions, fmt.Sprintf("Pod QOS class is %v", strings.Title(app.Settings.QosClass)))
+	}
+}
+
+type saturationRiskRule struct {
+	SevereUtilization float64 `yaml:"severe_utilization"` // utilization (%) at which use significantly exceeds allocation
+	SevereThrottling  float64 `yaml:"severe_throttling"`  // CPU throttling at which use significantly exceeds allocation
+	HighUtilization   float64 `yaml:"high_utilization"`   // utilization (%) above which use exceeds allocation
+	HighThrottling    float64 `yaml:"high_throttling"`    // CPU throttling above which use exceeds allocation
+	CloseUtilization  float64 `yaml:"close_utilization"`  // utilization (%) above which use is close to allocation
+	CloseThrottling   float64 `yaml:"close_throttling"`   // CPU throttling above which use is close to allocation
+}
+
+func (r *saturationRiskRule) Id() string             { return "saturation-risk" }
+func (r *saturationRiskRule) Category() string       { return RULE_CATEGORY_RELIABILITY }
+func (r *saturationRiskRule) Severity() RuleSeverity { return SEVERITY_CRITICAL }
+func (r *saturationRiskRule) Description() string {
+	return "Resource use close to or exceeding the allocation risks throttling and OOM kills"
+}
+
+func (r *saturationRiskRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	m := &app.Metrics
+	if m.CpuUtilization >= r.SevereUtilization ||
+		m.MemoryUtilization >= r.SevereUtilization ||
+		m.CpuSecondsThrottled >= r.SevereThrottling {
+		o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_HIGH)
+		o.Cautions = append(o.Cautions, "Resource utilization significantly exceeds allocation")
+	} else if m.CpuUtilization > r.HighUtilization ||
+		m.MemoryUtilization > r.HighUtilization ||
+		m.CpuSecondsThrottled > r.HighThrottling {
+		o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_HIGH)
+		o.Cautions = append(o.Cautions, "Resource utilization exceeds allocation")
+	} else if m.CpuUtilization > r.CloseUtilization ||
+		m.MemoryUtilization > r.CloseUtilization ||
+		m.CpuSecondsThrottled > r.CloseThrottling {
+		o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_MEDIUM)
+		o.Cautions = append(o.Cautions, "Resource utilization close to allocation")
+	}
+}
+
+type memoryGrowthRule struct {
+	Days         float64 `yaml:"days"`          // horizon (in days) for memory projected to reach the limit
+	MinFit       float64 `yaml:"min_fit"`       // minimum goodness of fit (R²) for a growth trend to be trusted
+	DropFraction float64 `yaml:"drop_fraction"` // fraction by which memory use must fall to count as a restart
+	MinDrops     int     `yaml:"min_drops"`     // minimum restarts after growth in a pod to call it a sawtooth pattern
+}
+
+// minimum number of samples to fit a memory growth trend
+const MEMORY_TREND_MIN_SAMPLES = 5
+
+func (r *memoryGrowthRule) Id() string             { return "memory-growth" }
+func (r *memoryGrowthRule) Category() string       { return RULE_CATEGORY_RELIABILITY }
+func (r *memoryGrowthRule) Severity() RuleSeverity { return SEVERITY_WARNING }
+func (r *memoryGrowthRule) Description() string {
+	return "Memory use steadily growing towards the limit (e.g., a leak) leads to OOM kills"
+}
+
+// fitMemoryTrend fits a trend (x in seconds) to a pod's memory use, ignoring segments too short to fit
+func fitMemoryTrend(series appmodel.TimeSeries) opsmath.LinearFit {
+	if len(series) < MEMORY_TREND_MIN_SAMPLES {
+		return opsmath.LinearFit{Slope: math.NaN(), Intercept: math.NaN(), R2: math.NaN()}
+	}
+	x := make([]float64, len(series))
+	for i := range series {
+		x[i] = float64(series[i].Time.Unix())
+	}
+	return opsmath.LinearRegression(x, series.Values())
+}
+
+// memoryTrend analyzes a pod's memory use series, returning the number of restarts following steady growth
+// and the days until memory use is projected to reach the limit (NaN if not growing)
+func (r *memoryGrowthRule) memoryTrend(series appmodel.TimeSeries, limit float64) (sawteeth int, days float64) {
+	// split the series at the sharp drops (restarts) and fit each segment
+	drops := append(opsmath.Drops(series.Values(), r.DropFraction), len(series))
+	start := 0
+	var fit opsmath.LinearFit
+	for _, end := range drops {
+		fit = fitMemoryTrend(series[start:end])
+		if end < len(series) && fit.Slope > 0 && fit.R2 >= r.MinFit {
+			sawteeth += 1
+		}
+		start = end
+	}
+
+	// project the growth since the last restart
+	days = math.NaN()
+	if fit.Slope > 0 && fit.R2 >= r.MinFit {
+		now := float64(series[len(series)-1].Time.Unix())
+		days = math.Max(fit.Reaches(limit)-now, 0) / (24 * 60 * 60)
+	}
+	return sawteeth, days
+}
+
+func (r *memoryGrowthRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	for _, c := range app.Containers {
+		if c.Memory.Limit <= 0 {
+			continue // no limit to run into
+		}
+		pods := make([]string, 0, len(c.Series.MemoryByPod))
+		for pod := range c.Series.MemoryByPod {
+			pods = append(pods, pod)
+		}
+		sort.Strings(pods)
+
+		sawtoothPods := 0
+		soonest := math.NaN()
+		for _, pod := range pods {
+			sawteeth, days := r.memoryTrend(c.Series.MemoryByPod[pod], c.Memory.Limit)
+			if sawteeth >= r.MinDrops {
+				sawtoothPods += 1
+			}
+			if days <= r.Days && (math.IsNaN(soonest) || days < soonest) {
+				soonest = days
+			}
+		}
+
+		if sawtoothPods > 0 {
+			var risk appmodel.RiskLevel = appmodel.RISK_MEDIUM
+			if c.RestartCount > 0 {
+				risk = appmodel.RISK_HIGH // the restarts are likely OOM kills
+			}
+			o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, risk)
+			o.Cautions = append(o.Cautions, fmt.Sprintf("Memory use of container %q repeatedly grows and drops, suggesting a leak and restarts (%v pod(s))", c.Name, sawtoothPods))
+		}
+		if !math.IsNaN(soonest) {
+			o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_MEDIUM)
+			o.Cautions = append(o.Cautions, fmt.Sprintf("Memory use of container %q is projected to reach its limit in %.1f days", c.Name, soonest))
+		}
+		if sawtoothPods > 0 || !math.IsNaN(soonest) {
+			o.Recommendations = append(o.Recommendations, fmt.Sprintf("Investigate memory growth in container %q before reducing its memory", c.Name))
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+// evaluateRule evaluates a built-in rule, with its default settings, on the app
+func evaluateRule(t *testing.T, id string, app *appmodel.App) *appmodel.AppAnalysis {
+	t.Helper()
+	rule := newRuleRegistry(getBuiltinRules()...).Lookup(id)
+	if rule == nil {
+		t.Fatalf("rule %q not found", id)
+	}
+	o := &appmodel.AppAnalysis{Flags: make(map[appmodel.AppFlag]bool)}
+	rule.Evaluate(app, o)
+	return o
+}
+
+// testSeriesStart is the time of the first sample of the series built by the test helpers
+var testSeriesStart = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// testSeries returns count samples, step apart, valued by value(i) for the i-th sample
+func testSeries(step time.Duration, count int, value func(i int) float64) appmodel.TimeSeries {
+	s := make(appmodel.TimeSeries, count)
+	for i := range s {
+		s[i] = appmodel.Sample{Time: testSeriesStart.Add(time.Duration(i) * step), Value: value(i)}
+	}
+	return s
+}
+
+// hourlySeries returns count hourly samples valued by value(h) for hour h
+func hourlySeries(count int, value func(h int) float64) appmodel.TimeSeries {
+	return testSeries(time.Hour, count, value)
+}
+
+// hourlyValues returns hourly samples with the given values
+func hourlyValues(values ...float64) appmodel.TimeSeries {
+	return hourlySeries(len(values), func(h int) float64 { return values[h] })
+}
+
+func containerWithResources(name string, cpuRequest, cpuLimit, memRequest, memLimit float64) appmodel.AppContainer {
+	c := appmodel.AppContainer{Name: name}
+	c.Cpu.Request, c.Cpu.Limit = cpuRequest, cpuLimit
+	c.Memory.Request, c.Memory.Limit = memRequest, memLimit
+	return c
+}
+
+func TestMainContainerRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		main     string
+		flag     bool
+		blockers int
+	}{
+		{"identified", "web", true, 0},
+		{"not identified", "", false, 1},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Analysis: appmodel.AppAnalysis{MainContainer: tt.main}}
+		o := evaluateRule(t, "main-container", app)
+		if o.Flags[appmodel.F_MAIN_CONTAINER] != tt.flag || len(o.Blockers) != tt.blockers {
+			t.Errorf("%v: expected flag %v and %v blocker(s), got %v and %v", tt.name, tt.flag, tt.blockers, o.Flags[appmodel.F_MAIN_CONTAINER], o.Blockers)
+		}
+	}
+}
+
+func TestMultiContainerRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers int
+		flag, set  bool
+	}{
+		{"no container info", 0, false, false},
+		{"single container", 1, false, true},
+		{"sidecar", 2, true, true},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Containers: make([]appmodel.AppContainer, tt.containers)}
+		o := evaluateRule(t, "multi-container", app)
+		flag, set := o.Flags[appmodel.F_MULTI_CONTAINER]
+		if flag != tt.flag || set != tt.set {
+			t.Errorf("%v: expected flag %v (set %v), got %v (set %v)", tt.name, tt.flag, tt.set, flag, set)
+		}
+	}
+}
+
+func TestWriteableVolumeRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		volume   bool
+		blockers int
+	}{
+		{"stateless", false, 0},
+		{"stateful", true, 1},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Settings: appmodel.AppSettings{WriteableVolume: tt.volume}}
+		o := evaluateRule(t, "writeable-volume", app)
+		if o.Flags[appmodel.F_WRITEABLE_VOLUME] != tt.volume || len(o.Blockers) != tt.blockers {
+			t.Errorf("%v: expected flag %v and %v blocker(s), got %v and %v", tt.name, tt.volume, tt.blockers, o.Flags[appmodel.F_WRITEABLE_VOLUME], o.Blockers)
+		}
+	}
+}
+
+func TestResourceSpecRule(t *testing.T) {
+	tests := []struct {
+		name                  string
+		qos                   string
+		main                  appmodel.AppContainer
+		guaranteed, limits    bool
+		spec                  bool
+		blockers, recommended int
+	}{
+		{"guaranteed", appmodel.QOS_GUARANTEED, containerWithResources("web", 1, 1, 1e9, 1e9), true, true, true, 0, 0},
+		{"requests only", appmodel.QOS_BURSTABLE, containerWithResources("web", 1, 0, 1e9, 0), false, false, true, 0, 0},
+		{"no memory", appmodel.QOS_BURSTABLE, containerWithResources("web", 1, 1, 0, 0), false, false, false, 1, 1},
+		{"best effort", appmodel.QOS_BESTEFFORT, containerWithResources("web", 0, 0, 0, 0), false, false, false, 1, 1},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{
+			Settings:   appmodel.AppSettings{QosClass: tt.qos},
+			Containers: []appmodel.AppContainer{tt.main},
+			Analysis:   appmodel.AppAnalysis{MainContainer: tt.main.Name},
+		}
+		o := evaluateRule(t, "resource-spec", app)
+		got := []bool{o.Flags[appmodel.F_RESOURCE_GUARANTEED], o.Flags[appmodel.F_RESOURCE_LIMITS], o.Flags[appmodel.F_RESOURCE_SPEC]}
+		want := []bool{tt.guaranteed, tt.limits, tt.spec}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: expected guaranteed/limits/spec flags %v, got %v", tt.name, want, got)
+		}
+		if len(o.Blockers) != tt.blockers || len(o.Recommendations) != tt.recommended {
+			t.Errorf("%v: expected %v blocker(s) and %v recommendation(s), got %v and %v", tt.name, tt.blockers, tt.recommended, o.Blockers, o.Recommendations)
+		}
+	}
+}
+
+func TestUtilizationRule(t *testing.T) {
+	tests := []struct {
+		name          string
+		cpu, mem      float64
+		rating        int
+		confidence    int
+		burst         bool
+		opportunities int
+	}{
+		{"no metrics", 0, 0, 0, 0, false, 0},
+		{"idle cpu", 0, 50, 0, 0, false, 0},
+		{"low utilization", 10, 20, 60, 30, false, 1},
+		{"moderate utilization", 50, 30, 50, 30, false, 1},
+		{"high utilization", 85, 90, 10, 30, false, 0},
+		{"bursting", 150, 50, 35, 30, true, 1},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Metrics: appmodel.AppMetrics{CpuUtilization: tt.cpu, MemoryUtilization: tt.mem}}
+		o := evaluateRule(t, "utilization", app)
+		if o.Rating != tt.rating || o.Confidence != tt.confidence {
+			t.Errorf("%v: expected rating %v, confidence %v; got %v, %v", tt.name, tt.rating, tt.confidence, o.Rating, o.Confidence)
+		}
+		if o.Flags[appmodel.F_BURST] != tt.burst || len(o.Opportunities) != tt.opportunities {
+			t.Errorf("%v: expected burst %v and %v opportunities, got %v and %v", tt.name, tt.burst, tt.opportunities, o.Flags[appmodel.F_BURST], o.Opportunities)
+		}
+		if o.Flags[appmodel.F_UTILIZATION] != (tt.cpu > 0 && tt.mem > 0) {
+			t.Errorf("%v: unexpected utilization flag %v", tt.name, o.Flags[appmodel.F_UTILIZATION])
+		}
+	}
+}
+
+func TestWorkloadPatternRule(t *testing.T) {
+	hourly := func(value func(h int) float64) appmodel.TimeSeries { return hourlySeries(7*24, value) }
+	daily := func(value func(d int) float64) appmodel.TimeSeries { return testSeries(24*time.Hour, 21, value) }
+
+	tests := []struct {
+		name            string
+		cpu, rate       appmodel.TimeSeries
+		pattern         appmodel.WorkloadPattern
+		recommendations int
+	}{
+		{"no data", nil, nil, appmodel.PATTERN_UNKNOWN, 0},
+		{"idle", hourly(func(h int) float64 { return 0.001 }), nil, appmodel.PATTERN_IDLE, 0},
+		{"steady", hourly(func(h int) float64 { return 0.5 + 0.01*float64(h%3) }), nil, appmodel.PATTERN_STEADY, 0},
+		{"diurnal", hourly(func(h int) float64 { return 0.5 + 0.3*math.Sin(2*math.Pi*float64(h)/24) }), nil, appmodel.PATTERN_DIURNAL, 1},
+		{"weekly", daily(func(d int) float64 {
+			if d%7 >= 5 {
+				return 0.3 // weekend
+			}
+			return 1
+		}), nil, appmodel.PATTERN_WEEKLY, 1},
+		{"bursty", hourly(func(h int) float64 {
+			if h*7919%13 == 0 {
+				return 1
+			}
+			return 0.2
+		}), nil, appmodel.PATTERN_BURSTY, 1},
+		{"batch", hourly(func(h int) float64 {
+			if h%6 == 0 {
+				return 2
+			}
+			return 0.001
+		}), nil, appmodel.PATTERN_BATCH, 1},
+		{"diurnal traffic", nil, hourly(func(h int) float64 { return 50 + 40*math.Sin(2*math.Pi*float64(h)/24) }), appmodel.PATTERN_DIURNAL, 1},
+		{"idle CPU, steady traffic", hourly(func(h int) float64 { return 0.001 }), hourly(func(h int) float64 { return 5 }), appmodel.PATTERN_STEADY, 0},
+		{"too coarse for a daily cycle", daily(func(d int) float64 { return 0.5 + 0.01*float64(d%3) }), nil, appmodel.PATTERN_UNKNOWN, 0},
+	}
+	for _, tt := range tests {
+		c := appmodel.AppContainer{Name: "web"}
+		c.Series.Cpu = tt.cpu
+		app := &appmodel.App{
+			Containers: []appmodel.AppContainer{c},
+			Settings:   appmodel.AppSettings{QosClass: appmodel.QOS_GUARANTEED},
+			Metrics:    appmodel.AppMetrics{RequestRate: 1},
+			Series:     appmodel.AppSeries{RequestRate: tt.rate},
+		}
+		o := evaluateRule(t, "workload-pattern", app)
+		if o.Pattern != tt.pattern || len(o.Recommendations) != tt.recommendations {
+			t.Errorf("%v: expected pattern %v and %v recommendation(s), got %v and %v", tt.name, tt.pattern, tt.recommendations, o.Pattern, o.Recommendations)
+		}
+		if o.Flags[appmodel.F_BURST] != (tt.pattern == appmodel.PATTERN_BURSTY) {
+			t.Errorf("%v: unexpected burst flag %v", tt.name, o.Flags[appmodel.F_BURST])
+		}
+	}
+}
+
+func TestRequestRateRule(t *testing.T) {
+	tests := []struct {
+		name               string
+		rps                float64
+		rating             int
+		traffic            bool
+		blockers, cautions int
+	}{
+		{"no traffic", 0, 0, false, 1, 0},
+		{"low traffic", 1, -10, false, 0, 1},
+		{"moderate traffic", 50, 0, true, 0, 0},
+		{"high traffic", 500, 10, true, 0, 0},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Metrics: appmodel.AppMetrics{RequestRate: tt.rps}}
+		o := evaluateRule(t, "request-rate", app)
+		if o.Rating != tt.rating || o.Flags[appmodel.F_TRAFFIC] != tt.traffic {
+			t.Errorf("%v: expected rating %v and traffic %v, got %v and %v", tt.name, tt.rating, tt.traffic, o.Rating, o.Flags[appmodel.F_TRAFFIC])
+		}
+		if len(o.Blockers) != tt.blockers || len(o.Cautions) != tt.cautions {
+			t.Errorf("%v: expected %v blocker(s) and %v caution(s), got %v and %v", tt.name, tt.blockers, tt.cautions, o.Blockers, o.Cautions)
+		}
+	}
+}
+
+func TestReplicasRule(t *testing.T) {
+	tests := []struct {
+		name               string
+		replicas           float64
+		rating, confidence int
+		single, many       bool
+	}{
+		{"single replica", 1, -20, 10, true, false},
+		{"few replicas", 2.5, 0, 0, false, false},
+		{"several replicas", 5, 10, 10, false, false},
+		{"many replicas", 12, 20, 30, false, true},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Metrics: appmodel.AppMetrics{AverageReplicas: tt.replicas}}
+		o := evaluateRule(t, "replicas", app)
+		if o.Rating != tt.rating || o.Confidence != tt.confidence {
+			t.Errorf("%v: expected rating %v, confidence %v; got %v, %v", tt.name, tt.rating, tt.confidence, o.Rating, o.Confidence)
+		}
+		if o.Flags[appmodel.F_SINGLE_REPLICA] != tt.single || o.Flags[appmodel.F_MANY_REPLICAS] != tt.many {
+			t.Errorf("%v: expected single/many flags %v/%v, got %v/%v", tt.name, tt.single, tt.many, o.Flags[appmodel.F_SINGLE_REPLICA], o.Flags[appmodel.F_MANY_REPLICAS])
+		}
+		if tt.rating != 0 && (len(o.Contributions) != 1 || o.Contributions[0].Rule != "replicas") {
+			t.Errorf("%v: expected a single replicas contribution, got %+v", tt.name, o.Contributions)
+		}
+	}
+}
+
+func TestLoadImbalanceRule(t *testing.T) {
+	byPod := func(averages ...float64) map[string]appmodel.TimeSeries {
+		pods := make(map[string]appmodel.TimeSeries, len(averages))
+		for i, v := range averages {
+			pods[fmt.Sprintf("web-5d8f9-%v", i)] = hourlyValues(v, v)
+		}
+		return pods
+	}
+	tests := []struct {
+		name     string
+		cpu      []float64
+		rate     []float64
+		analyzed bool
+		cautions int
+	}{
+		{"no per-pod data", nil, nil, false, 0},
+		{"single pod", []float64{1}, []float64{100}, false, 0},
+		{"balanced", []float64{0.5, 0.6, 0.5, 0.55}, []float64{100, 110, 95, 100}, true, 0},
+		{"hot replica", []float64{1, 0.1, 0.1, 0.1, 0.1}, []float64{100, 110, 95, 100, 100}, true, 1},
+		{"skewed traffic", []float64{0.5, 0.6}, []float64{300, 10}, true, 1},
+		{"idle pods", []float64{0.01, 0.001, 0.001}, nil, true, 0},
+	}
+	for _, tt := range tests {
+		c := appmodel.AppContainer{Name: "web"}
+		c.Series.CpuByPod = byPod(tt.cpu...)
+		app := &appmodel.App{
+			Containers: []appmodel.AppContainer{c},
+			Series:     appmodel.AppSeries{RequestRateByPod: byPod(tt.rate...)},
+		}
+		o := evaluateRule(t, "load-imbalance", app)
+		if (o.LoadImbalance != nil) != tt.analyzed || len(o.Cautions) != tt.cautions {
+			t.Errorf("%v: expected analyzed %v and %v caution(s), got %+v and %v", tt.name, tt.analyzed, tt.cautions, o.LoadImbalance, o.Cautions)
+		}
+	}
+}
+
+func TestQosRiskRule(t *testing.T) {
+	tests := []struct {
+		qos  string
+		risk appmodel.RiskLevel
+	}{
+		{appmodel.QOS_GUARANTEED, appmodel.RISK_UNKNOWN},
+		{appmodel.QOS_BURSTABLE, appmodel.RISK_MEDIUM},
+		{appmodel.QOS_BESTEFFORT, appmodel.RISK_HIGH},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Settings: appmodel.AppSettings{QosClass: tt.qos}}
+		o := evaluateRule(t, "qos-risk", app)
+		if risk := o.ReliabilityRisk.SafeRiskLevel(); risk != tt.risk {
+			t.Errorf("%v: expected risk %v, got %v", tt.qos, appmodel.Risk2String(&tt.risk), appmodel.Risk2String(&risk))
+		}
+	}
+}
+
+func TestSaturationRiskRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		cpu, mem   float64
+		throttling float64
+		risk       appmodel.RiskLevel
+	}{
+		{"comfortable", 50, 60, 0, appmodel.RISK_UNKNOWN},
+		{"close to allocation", 95, 60, 0, appmodel.RISK_MEDIUM},
+		{"some throttling", 50, 60, 0.15, appmodel.RISK_MEDIUM},
+		{"exceeds allocation", 50, 130, 0, appmodel.RISK_HIGH},
+		{"heavy throttling", 50, 60, 0.8, appmodel.RISK_HIGH},
+		{"significantly exceeds allocation", 250, 60, 0, appmodel.RISK_HIGH},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{Metrics: appmodel.AppMetrics{CpuUtilization: tt.cpu, MemoryUtilization: tt.mem, CpuSecondsThrottled: tt.throttling}}
+		o := evaluateRule(t, "saturation-risk", app)
+		if risk := o.ReliabilityRisk.SafeRiskLevel(); risk != tt.risk {
+			t.Errorf("%v: expected risk %v, got %v", tt.name, appmodel.Risk2String(&tt.risk), appmodel.Risk2String(&risk))
+		}
+		if (tt.risk != appmodel.RISK_UNKNOWN) != (len(o.Cautions) == 1) {
+			t.Errorf("%v: unexpected cautions %v", tt.name, o.Cautions)
+		}
+	}
+}
+
+func TestMemoryGrowthRule(t *testing.T) {
+	const MB = 1024 * 1024
+	tests := []struct {
+		name     string
+		limit    float64
+		restarts float64
+		memory   func(hour int) float64
+		cautions int
+		risk     appmodel.RiskLevel
+	}{
+		{"flat", 1024 * MB, 0, func(h int) float64 { return 500 * MB }, 0, appmodel.RISK_UNKNOWN},
+		{"slow growth", 1024 * MB, 0, func(h int) float64 { return 300*MB + float64(h)*MB }, 0, appmodel.RISK_UNKNOWN},
+		{"growth to limit", 1024 * MB, 0, func(h int) float64 { return 300*MB + float64(h)*3*MB }, 1, appmodel.RISK_MEDIUM},
+		{"no limit", 0, 0, func(h int) float64 { return 300*MB + float64(h)*3*MB }, 0, appmodel.RISK_UNKNOWN},
+		{"noisy", 1024 * MB, 0, func(h int) float64 { return 500*MB + float64(h%2)*300*MB + float64(h)*MB }, 0, appmodel.RISK_UNKNOWN},
+		{"sawtooth", 1024 * MB, 0, func(h int) float64 { return 300*MB + float64(h%48)*10*MB }, 2, appmodel.RISK_MEDIUM},
+		{"sawtooth with restarts", 1024 * MB, 3, func(h int) float64 { return 300*MB + float64(h%48)*10*MB }, 2, appmodel.RISK_HIGH},
+	}
+	for _, tt := range tests {
+		c := containerWithResources("web", 1, 1, tt.limit, tt.limit)
+		c.RestartCount = tt.restarts
+		c.Series.MemoryByPod = map[string]appmodel.TimeSeries{"web-5d8f9-abcde": hourlySeries(7*24, tt.memory)}
+		app := &appmodel.App{Containers: []appmodel.AppContainer{c}}
+		o := evaluateRule(t, "memory-growth", app)
+		if risk := o.ReliabilityRisk.SafeRiskLevel(); risk != tt.risk || len(o.Cautions) != tt.cautions {
+			t.Errorf("%v: expected risk %v and %v caution(s), got %v and %v", tt.name, appmodel.Risk2String(&tt.risk), tt.cautions, appmodel.Risk2String(&risk), o.Cautions)
+		}
+	}
+}
+
+func TestRuleRegistryConfigure(t *testing.T) {
+	r := newRuleRegistry(getBuiltinRules()...)
+	err := r.Configure(map[string]interface{}{
+		"qos-risk": map[string]interface{}{"enabled": false},
+		"replicas": map[string]interface{}{"many_min": 10, "many_rating": 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.IsEnabled("qos-risk") || !r.IsEnabled("replicas") {
+		t.Error("expected qos-risk to be disabled and replicas enabled")
+	}
+	if got := r.Lookup("replicas").(*replicasRule); got.ManyMin != 10 || got.ManyRating != 5 || got.SingleMax != 1 || got.ManyConfidence != 30 {
+		t.Errorf("expected many_min and many_rating to be replaced and the other settings kept, got %+v", got)
+	}
+	o := &appmodel.AppAnalysis{Flags: make(map[appmodel.AppFlag]bool)}
+	r.Lookup("replicas").Evaluate(&appmodel.App{Metrics: appmodel.AppMetrics{AverageReplicas: 12}}, o)
+	if o.Rating != 5 || o.Confidence != 30 {
+		t.Errorf("expected the configured rating delta for many replicas, got rating %v, confidence %v", o.Rating, o.Confidence)
+	}
+
+	bad := []map[string]interface{}{
+		{"no-such-rule": map[string]interface{}{"enabled": false}},
+		{"replicas": map[string]interface{}{"no_such_setting": 1}},
+		{"replicas": map[string]interface{}{"enabled": "maybe"}},
+	}
+	for _, config := range bad {
+		if err := newRuleRegistry(getBuiltinRules()...).Configure(config); err == nil {
+			t.Errorf("expected an error for config %v", config)
+		}
+	}
+}
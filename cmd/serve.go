@@ -0,0 +1,133 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	"opsani-ignite/server"
+	prom "opsani-ignite/sources/prometheus"
+)
+
+var serveAddress string
+var serveCacheSize int
+var serveCacheTTL time.Duration
+
+const serveShutdownTimeout = 30 * time.Second
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the analysis as a REST API",
+	Long: `Runs Opsani Ignite as an HTTP server, exposing the analysis as a REST API:
+
+  POST /api/v1/runs                                   start an analysis run
+  GET  /api/v1/runs                                   list recent runs
+  GET  /api/v1/runs/{id}                              get run status and progress
+  GET  /api/v1/runs/{id}/apps                         get the analyzed applications
+  GET  /api/v1/runs/{id}/apps/{namespace}/{workload}  get a single analyzed application
+
+The run request body is a JSON object with optional cluster, namespace, workload,
+start, end and step values; the command line options provide the defaults.
+Results are returned as JSON, or as YAML with ?format=yaml.
+
+Additional clusters can be defined in the config file as a map of cluster names
+to Prometheus URLs under the "clusters" key.`,
+	Args: cobra.NoArgs,
+	Run:  runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddress, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().IntVar(&serveCacheSize, "cache-size", 20, "Maximum number of completed runs to keep")
+	serveCmd.Flags().DurationVar(&serveCacheTTL, "cache-ttl", time.Hour, "How long to keep completed runs; identical requests within this time reuse the run")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// igniteAnalyzer performs analysis runs requested via the REST API
+type igniteAnalyzer struct {
+	defaultPromUri *url.URL
+}
+
+func (a *igniteAnalyzer) resolve(req server.Request) (uri *url.URL, start time.Time, end time.Time, step time.Duration, err error) {
+	uri = a.defaultPromUri
+	if req.Cluster != "" {
+		clusterUri := viper.GetStringMapString("clusters")[req.Cluster]
+		if clusterUri == "" {
+			err = fmt.Errorf("unknown cluster %q", req.Cluster)
+			return
+		}
+		if err = parseRequiredUriFlag(&uri, clusterUri, "clusters."+req.Cluster); err != nil {
+			return
+		}
+	}
+	if req.Workload != "" && req.Namespace == "" {
+		err = fmt.Errorf("workload requires a namespace")
+		return
+	}
+
+	startString, endString, stepString := timeStartString, timeEndString, timeStepString
+	if req.Start != "" {
+		startString = req.Start
+	}
+	if req.End != "" {
+		endString = req.End
+	}
+	if req.Step != "" {
+		stepString = req.Step
+	}
+	start, end, step, err = parseTimeRange(startString, endString, stepString)
+	return
+}
+
+func (a *igniteAnalyzer) Validate(req server.Request) error {
+	_, _, _, _, err := a.resolve(req)
+	return err
+}
+
+func (a *igniteAnalyzer) Analyze(ctx context.Context, req server.Request, progressCallback log.ProgressUpdateFunc) ([]*appmodel.App, error) {
+	uri, start, end, step, err := a.resolve(req) // relative times are evaluated when the run starts
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Starting analysis run for %#v", req)
+	cluster := req.Cluster
+	if cluster == "" {
+		cluster = clusterName
+	}
+	return collectAndAnalyze(ctx, uri, cluster, req.Namespace, req.Workload, start, end, step, progressCallback)
+}
+
+func runServe(cmd *cobra.Command, args []string) {
+	logFile := setupLogFile()
+	defer logFile.Close()
+
+	prom.Init()
+	srv := server.New(&igniteAnalyzer{promUri}, server.Options{CacheSize: serveCacheSize, CacheTTL: serveCacheTTL})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	msg := fmt.Sprintf("Serving REST API on %v, using Prometheus API at %q", serveAddress, promUri)
+	log.Print(msg)
+	fmt.Fprintln(os.Stderr, msg)
+	if err := srv.ListenAndServe(ctx, serveAddress, serveShutdownTimeout); err != nil {
+		log.Errorf("Server failed: %v", err)
+		fmt.Fprintf(os.Stderr, "Server failed: %v\n", err)
+		os.Exit(1)
+	}
+}
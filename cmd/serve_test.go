@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	"opsani-ignite/server"
+	prom "opsani-ignite/sources/prometheus"
+)
+
+// newFakePrometheus serves a single namespace "shop" with a single deployment "web" running one container
+func newFakePrometheus() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		var data string
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/label/namespace/values"):
+			data = `["shop","kube-system"]`
+		case strings.HasSuffix(r.URL.Path, "/query_range"):
+			series := "[]"
+			if strings.Contains(query, "kube_deployment_status_replicas") {
+				series = `[{"metric":{},"values":[[1633046400,"3"],[1633132800,"3"]]}]`
+			} else if strings.Contains(query, "container_memory_working_set_bytes") && strings.HasPrefix(query, "avg by (container)") {
+				series = `[{"metric":{"container":"web"},"values":[[1633046400,"134217728"],[1633132800,"134217728"]]}]`
+			} else if strings.Contains(query, "container_cpu_usage_seconds_total") && strings.HasPrefix(query, "avg by (container)") {
+				series = `[{"metric":{"container":"web"},"values":[[1633046400,"0.1"],[1633132800,"0.1"]]}]`
+			}
+			data = fmt.Sprintf(`{"resultType":"matrix","result":%v}`, series)
+		case strings.HasSuffix(r.URL.Path, "/query"):
+			vector := "[]"
+			if strings.Contains(query, "kube_deployment_labels") {
+				vector = `[{"metric":{"deployment":"web"},"value":[1633132800,"1"]}]`
+			} else if strings.Contains(query, "kube_pod_container_info") {
+				vector = `[{"metric":{"container":"web"},"value":[1633132800,"3"]}]`
+			} else if strings.Contains(query, "kube_pod_container_resource_requests") {
+				vector = `[{"metric":{"container":"web","resource":"cpu"},"value":[1633132800,"0.5"]},
+					{"metric":{"container":"web","resource":"memory"},"value":[1633132800,"268435456"]}]`
+			}
+			data = fmt.Sprintf(`{"resultType":"vector","result":%v}`, vector)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":%v}`, data)
+	}))
+}
+
+func TestServeAgainstFakePrometheus(t *testing.T) {
+	fakeProm := newFakePrometheus()
+	defer fakeProm.Close()
+	uri, _ := url.Parse(fakeProm.URL)
+	timeStartString, timeEndString, timeStepString = "-2d", "-0d", "1d"
+
+	prom.Init()
+	srv := server.New(&igniteAnalyzer{uri}, server.Options{CacheSize: 5, CacheTTL: time.Hour})
+	api := httptest.NewServer(srv.Handler())
+	defer api.Close()
+
+	post := func(body string) (int, server.RunStatus) {
+		resp, err := http.Post(api.URL+"/api/v1/runs", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var status server.RunStatus
+		json.NewDecoder(resp.Body).Decode(&status)
+		return resp.StatusCode, status
+	}
+	get := func(path string, v interface{}) int {
+		resp, err := http.Get(api.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(v)
+		return resp.StatusCode
+	}
+
+	// invalid requests are rejected
+	if code, _ := post(`{"step":"1s"}`); code != http.StatusBadRequest {
+		t.Errorf("expected bad request for invalid step, got %v", code)
+	}
+
+	// start and poll until done
+	code, status := post(`{}`)
+	if code != http.StatusAccepted || status.Id == "" {
+		t.Fatalf("expected run to start, got %v %+v", code, status)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for status.State == server.RUN_RUNNING && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		get("/api/v1/runs/"+status.Id, &status)
+	}
+	if status.State != server.RUN_DONE || status.AppCount != 1 || status.Progress.WorkloadsDone != 1 {
+		t.Fatalf("expected run to complete with 1 app, got %+v", status)
+	}
+
+	// fetch results
+	var apps []map[string]interface{}
+	if code := get("/api/v1/runs/"+status.Id+"/apps", &apps); code != http.StatusOK || len(apps) != 1 {
+		t.Fatalf("expected 1 app, got %v %v", code, apps)
+	}
+	var app map[string]interface{}
+	if code := get("/api/v1/runs/"+status.Id+"/apps/shop/web", &app); code != http.StatusOK {
+		t.Fatalf("expected app shop/web, got %v", code)
+	}
+	analysis, _ := app["analysis"].(map[string]interface{})
+	if analysis["main_container"] != "web" {
+		t.Errorf("expected main container %q, got %v", "web", analysis["main_container"])
+	}
+	if code := get("/api/v1/runs/"+status.Id+"/apps/shop/db", &app); code != http.StatusNotFound {
+		t.Errorf("expected not found for unknown app, got %v", code)
+	}
+
+	// identical request reuses the cached run
+	if code, cached := post(`{}`); code != http.StatusOK || cached.Id != status.Id {
+		t.Errorf("expected cached run %v, got %v %+v", status.Id, code, cached)
+	}
+}
+
+// staticAnalyzer returns the same apps for every run
+type staticAnalyzer struct {
+	apps []*appmodel.App
+}
+
+func (a *staticAnalyzer) Validate(req server.Request) error { return nil }
+func (a *staticAnalyzer) Analyze(ctx context.Context, req server.Request, progressCallback log.ProgressUpdateFunc) ([]*appmodel.App, error) {
+	return a.apps, nil
+}
+
+func TestServeNonFiniteValues(t *testing.T) {
+	good := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "web"}}
+	bad := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "db"}}
+	bad.Metrics.CpuUtilization, bad.Metrics.RequestRate = math.NaN(), math.Inf(1)
+	srv := server.New(&staticAnalyzer{[]*appmodel.App{good, bad}}, server.Options{CacheSize: 5, CacheTTL: time.Hour})
+	api := httptest.NewServer(srv.Handler())
+	defer api.Close()
+
+	resp, err := http.Post(api.URL+"/api/v1/runs", "application/json", bytes.NewBufferString(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status server.RunStatus
+	json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for status.State != server.RUN_DONE && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		resp, err := http.Get(api.URL + "/api/v1/runs/" + status.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+	}
+
+	resp, err = http.Get(api.URL + "/api/v1/runs/" + status.Id + "/apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var apps []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&apps); resp.StatusCode != http.StatusOK || err != nil || len(apps) != 2 {
+		t.Fatalf("expected 2 apps despite non-finite values, got %v %v (%v)", resp.StatusCode, apps, err)
+	}
+	for _, app := range apps {
+		metadata, _ := app["metadata"].(map[string]interface{})
+		metrics, _ := app["metrics"].(map[string]interface{})
+		if v, ok := metrics["cpu_saturation"]; metadata["workload"] == "db" && (!ok || v != nil) {
+			t.Errorf("expected non-finite values encoded as null, got %v", metrics)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	opsmath "opsani-ignite/math"
+	prom "opsani-ignite/sources/prometheus"
+)
+
+// Percentile of the sidecars' use per pod, across apps, to size suggested injector default requests for
+const SIDECAR_SUGGESTION_PERCENTILE = 90
+
+// knownSidecar is a commonly injected sidecar, recognized by its container name or image
+type knownSidecar struct {
+	Name   string   // sidecar name, as reported
+	Names  []string // container names it runs as
+	Images []string // image repositories it runs from, matched as the trailing path of the repository
+}
+
+// constant table - known sidecars
+func getKnownSidecars() []knownSidecar {
+	return []knownSidecar{
+		{"istio-proxy", []string{"istio-proxy"}, []string{"istio/proxyv2", "istio/proxy"}},
+		{"linkerd-proxy", []string{"linkerd-proxy"}, []string{"linkerd/proxy", "linkerd-io/proxy"}},
+		{"fluent-bit", []string{"fluent-bit", "fluentbit"}, []string{"fluent/fluent-bit"}},
+		{"vault-agent", []string{"vault-agent"}, nil}, // runs the vault image, like vault itself
+	}
+}
+
+// imageRepository returns the image reference without its tag or digest
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// identifySidecar returns the name of the known sidecar the container runs, or empty if none.
+// A single-container app has no sidecars.
+func identifySidecar(app *appmodel.App, c *appmodel.AppContainer) string {
+	if len(app.Containers) < 2 {
+		return ""
+	}
+	repository := imageRepository(c.Image)
+	for _, s := range getKnownSidecars() {
+		for _, name := range s.Names {
+			if c.Name == name {
+				return s.Name
+			}
+		}
+		for _, image := range s.Images {
+			if repository == image || strings.HasSuffix(repository, "/"+image) {
+				return s.Name
+			}
+		}
+	}
+	return ""
+}
+
+type sidecarOverheadRule struct {
+	CostShare float64 `yaml:"cost_share"` // fraction of the pod cost taken by sidecars above which to recommend right-sizing them
+}
+
+func (r *sidecarOverheadRule) Id() string             { return "sidecar-overhead" }
+func (r *sidecarOverheadRule) Category() string       { return RULE_CATEGORY_RESOURCES }
+func (r *sidecarOverheadRule) Severity() RuleSeverity { return SEVERITY_INFO }
+func (r *sidecarOverheadRule) Description() string {
+	return "Reports the share of the app's cost taken by known sidecars (service mesh proxies, log shippers, etc.)"
+}
+
+func (r *sidecarOverheadRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	var names []string
+	cost := 0.0
+	for i := range app.Containers {
+		if c := &app.Containers[i]; c.Sidecar != "" {
+			names = append(names, c.Name)
+			cost += c.PseudoCost
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	o.Sidecars = &appmodel.SidecarOverhead{
+		Containers:  names,
+		MonthlyCost: cost * app.Metrics.AverageReplicas * appmodel.HOURS_PER_MONTH,
+	}
+	if total := app.PodPseudoCost(); total > 0 {
+		o.Sidecars.CostShare = cost / total
+	}
+	if o.Sidecars.CostShare >= r.CostShare {
+		o.Recommendations = append(o.Recommendations, fmt.Sprintf("Sidecars (%v) take %.0f%% of the pod cost; right-size their resources",
+			strings.Join(names, ", "), o.Sidecars.CostShare*100))
+	}
+}
+
+// sidecarsString describes the cost of the app's sidecars
+func sidecarsString(s *appmodel.SidecarOverhead) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%v: %.0f%% of the pod cost, $%.2f/month", strings.Join(s.Containers, ", "), s.CostShare*100, s.MonthlyCost)
+}
+
+// --- Cluster-wide Sidecar Overhead -----------------------------------------
+
+// sidecarSummary aggregates a known sidecar's resources and cost across apps
+type sidecarSummary struct {
+	Sidecar       string
+	Apps          int
+	Pods          float64 // average replicas, summed across apps
+	CpuRequest    float64 // total across pods, in cores
+	CpuUsage      float64 // total across pods, in cores
+	MemoryRequest float64 // total across pods, in bytes
+	MemoryUsage   float64 // total across pods, in bytes
+	MonthlyCost   float64
+
+	// per pod, one value per app
+	cpuRequests, cpuUses, memoryRequests, memoryUses []float64
+}
+
+// suggestedRequests returns the requests to set as the sidecar injector's defaults: the requests that put
+// the use per pod of most apps (the configured percentile) at the patch target saturation
+func (s *sidecarSummary) suggestedRequests() (cpu, memory float64) {
+	if len(s.cpuUses) > 0 {
+		cpu = patchRequest(opsmath.Percentile(SIDECAR_SUGGESTION_PERCENTILE, s.cpuUses...), PATCH_CPU_TARGET_SATURATION, 0.005)
+	}
+	if len(s.memoryUses) > 0 {
+		memory = patchRequest(opsmath.Percentile(SIDECAR_SUGGESTION_PERCENTILE, s.memoryUses...), PATCH_MEMORY_TARGET_SATURATION, 1024*1024)
+	}
+	return cpu, memory
+}
+
+// typicalRequests returns the median requests per pod across apps, i.e., likely the injector's defaults
+func (s *sidecarSummary) typicalRequests() (cpu, memory float64) {
+	if len(s.cpuRequests) > 0 {
+		cpu = opsmath.Median(s.cpuRequests...)
+	}
+	if len(s.memoryRequests) > 0 {
+		memory = opsmath.Median(s.memoryRequests...)
+	}
+	return cpu, memory
+}
+
+// summarizeSidecars aggregates the known sidecars across the apps, ordered by cost (highest first);
+// also returns the total monthly cost of the apps
+func summarizeSidecars(apps []*appmodel.App) (summaries []*sidecarSummary, totalCost float64) {
+	bySidecar := make(map[string]*sidecarSummary)
+	for _, app := range apps {
+		totalCost += app.MonthlyCost()
+		counted := make(map[string]bool)
+		for i := range app.Containers {
+			c := &app.Containers[i]
+			if c.Sidecar == "" {
+				continue
+			}
+			s, ok := bySidecar[c.Sidecar]
+			if !ok {
+				s = &sidecarSummary{Sidecar: c.Sidecar}
+				bySidecar[c.Sidecar] = s
+				summaries = append(summaries, s)
+			}
+			if !counted[c.Sidecar] {
+				s.Apps++
+				s.Pods += app.Metrics.AverageReplicas
+				counted[c.Sidecar] = true
+			}
+			pods := app.Metrics.AverageReplicas
+			s.CpuRequest += c.Cpu.Request * pods
+			s.CpuUsage += c.Cpu.Usage * pods
+			s.MemoryRequest += c.Memory.Request * pods
+			s.MemoryUsage += c.Memory.Usage * pods
+			s.MonthlyCost += c.PseudoCost * pods * appmodel.HOURS_PER_MONTH
+			if c.Cpu.Request > 0 {
+				s.cpuRequests = append(s.cpuRequests, c.Cpu.Request)
+			}
+			if c.Cpu.Usage > 0 {
+				s.cpuUses = append(s.cpuUses, c.Cpu.Usage)
+			}
+			if c.Memory.Request > 0 {
+				s.memoryRequests = append(s.memoryRequests, c.Memory.Request)
+			}
+			if c.Memory.Usage > 0 {
+				s.memoryUses = append(s.memoryUses, c.Memory.Usage)
+			}
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].MonthlyCost > summaries[j].MonthlyCost })
+	return summaries, totalCost
+}
+
+func outputSidecars(summaries []*sidecarSummary, totalCost float64, appCount int) {
+	sidecarCost := 0.0
+	for _, s := range summaries {
+		sidecarCost += s.MonthlyCost
+	}
+	share := func(cost float64) string {
+		if totalCost == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", cost/totalCost*100)
+	}
+	fmt.Printf("Sidecar overhead: $%.2f/month of $%.2f/month (%v) across %v applications\n\n", sidecarCost, totalCost, share(sidecarCost), appCount)
+	if len(summaries) == 0 {
+		return
+	}
+
+	t := tablewriter.NewWriter(os.Stdout)
+	t.SetHeader([]string{"Sidecar", "Apps", "Pods", "CPU Request", "CPU Use", "Mem Request", "Mem Use", "Monthly Cost", "Share"})
+	t.SetAutoFormatHeaders(false)
+	t.SetBorder(false)
+	for _, s := range summaries {
+		t.Append([]string{
+			s.Sidecar, fmt.Sprintf("%v", s.Apps), fmt.Sprintf("%.1f", s.Pods),
+			cpuString(s.CpuRequest), cpuString(s.CpuUsage), memoryString(s.MemoryRequest), memoryString(s.MemoryUsage),
+			fmt.Sprintf("$%.2f", s.MonthlyCost), share(s.MonthlyCost),
+		})
+	}
+	t.Render()
+
+	fmt.Printf("\nSuggested sidecar injector default requests (sized for the %vth percentile of use per pod):\n", SIDECAR_SUGGESTION_PERCENTILE)
+	for _, s := range summaries {
+		typicalCpu, typicalMemory := s.typicalRequests()
+		cpu, memory := s.suggestedRequests()
+		if cpu == 0 && memory == 0 {
+			fmt.Printf("  %v: no usage data\n", s.Sidecar)
+			continue
+		}
+		fmt.Printf("  %v: CPU %v -> %v, memory %v -> %v\n", s.Sidecar, cpuString(typicalCpu), cpuString(cpu), memoryString(typicalMemory), memoryString(memory))
+	}
+}
+
+// sidecarsCmd represents the sidecars command
+var sidecarsCmd = &cobra.Command{
+	Use:   "sidecars [<namespace>]",
+	Short: "Show the overhead of known sidecars across applications",
+	Long: `Shows the resources and cost of known sidecars (istio-proxy, linkerd-proxy,
+fluent-bit, vault-agent), recognized by container name or image, across the
+applications in the cluster or namespace, and suggests default requests for
+the sidecar injectors, sized for the use per pod of most applications.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runSidecars,
+}
+
+func init() {
+	rootCmd.AddCommand(sidecarsCmd)
+}
+
+func runSidecars(cmd *cobra.Command, args []string) {
+	logFile := setupLogFile()
+	defer logFile.Close()
+
+	namespace := ""
+	if len(args) >= 1 {
+		namespace = args[0]
+	}
+	prom.Init()
+	apps, err := collectAndAnalyze(context.Background(), promUri, clusterName, namespace, "", timeStart, timeEnd, timeStep, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to obtain data from Prometheus at %q: %v\n", promUri, err)
+		os.Exit(1)
+	}
+	summaries, totalCost := summarizeSidecars(apps)
+	log.Infof("Summarized %v sidecars across %v apps", len(summaries), len(apps))
+	outputSidecars(summaries, totalCost, len(apps))
+}
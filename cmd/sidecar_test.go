@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestIdentifySidecar(t *testing.T) {
+	tests := []struct {
+		name, image, sidecar string
+	}{
+		{"istio-proxy", "docker.io/istio/proxyv2:1.10.0", "istio-proxy"},
+		{"proxy", "docker.io/istio/proxyv2:1.10.0", "istio-proxy"},
+		{"linkerd-proxy", "cr.l5d.io/linkerd/proxy:stable-2.10.2", "linkerd-proxy"},
+		{"logs", "fluent/fluent-bit@sha256:0123abcd", "fluent-bit"},
+		{"logs", "registry:5000/fluent/fluent-bit:1.8", "fluent-bit"},
+		{"vault-agent", "hashicorp/vault:1.7.0", "vault-agent"},
+		{"vault", "hashicorp/vault:1.7.0", ""},
+		{"web", "example/web:1.0", ""},
+		{"web", "example/notistio/proxyv2x:1.0", ""},
+	}
+	for _, tt := range tests {
+		c := appmodel.AppContainer{Name: tt.name, Image: tt.image}
+		app := &appmodel.App{Containers: []appmodel.AppContainer{{Name: "main"}, c}}
+		if sidecar := identifySidecar(app, &c); sidecar != tt.sidecar {
+			t.Errorf("%v (%v): expected %q, got %q", tt.name, tt.image, tt.sidecar, sidecar)
+		}
+	}
+	single := &appmodel.App{Containers: []appmodel.AppContainer{{Name: "istio-proxy"}}}
+	if sidecar := identifySidecar(single, &single.Containers[0]); sidecar != "" {
+		t.Errorf("expected no sidecar in a single-container app, got %q", sidecar)
+	}
+}
+
+func TestSidecarOverheadRule(t *testing.T) {
+	web := containerWithResources("web", 1, 1, 0, 0)
+	web.PseudoCost = 0.03
+	proxy := containerWithResources("istio-proxy", 0.1, 2, 0, 0)
+	proxy.PseudoCost, proxy.Sidecar = 0.01, "istio-proxy"
+	app := &appmodel.App{
+		Containers: []appmodel.AppContainer{web, proxy},
+		Metrics:    appmodel.AppMetrics{AverageReplicas: 4},
+	}
+	o := evaluateRule(t, "sidecar-overhead", app)
+	if o.Sidecars == nil || len(o.Sidecars.Containers) != 1 || o.Sidecars.Containers[0] != "istio-proxy" {
+		t.Fatalf("expected the istio-proxy overhead, got %+v", o.Sidecars)
+	}
+	if math.Abs(o.Sidecars.CostShare-0.25) > 1e-9 || math.Abs(o.Sidecars.MonthlyCost-0.01*4*appmodel.HOURS_PER_MONTH) > 1e-9 {
+		t.Errorf("unexpected overhead %+v", o.Sidecars)
+	}
+	if len(o.Recommendations) != 1 {
+		t.Errorf("expected a recommendation to right-size the sidecars, got %v", o.Recommendations)
+	}
+
+	app.Containers[1].Sidecar = ""
+	if o := evaluateRule(t, "sidecar-overhead", app); o.Sidecars != nil || len(o.Recommendations) != 0 {
+		t.Errorf("expected no overhead without sidecars, got %+v, %v", o.Sidecars, o.Recommendations)
+	}
+}
+
+func TestSummarizeSidecars(t *testing.T) {
+	const Mi = 1024 * 1024
+	newApp := func(replicas float64, proxyCpuUse float64) *appmodel.App {
+		web := containerWithResources("web", 1, 1, 512*Mi, 512*Mi)
+		web.PseudoCost = 0.03
+		proxy := containerWithResources("istio-proxy", 0.1, 2, 128*Mi, 1024*Mi)
+		proxy.Cpu.Usage, proxy.Memory.Usage = proxyCpuUse, 40*Mi
+		proxy.PseudoCost, proxy.Sidecar = 0.01, "istio-proxy"
+		return &appmodel.App{Containers: []appmodel.AppContainer{web, proxy}, Metrics: appmodel.AppMetrics{AverageReplicas: replicas}}
+	}
+	apps := []*appmodel.App{newApp(2, 0.01), newApp(4, 0.02), newApp(1, 0.014)}
+	apps = append(apps, &appmodel.App{Containers: []appmodel.AppContainer{{Name: "solo", PseudoCost: 0.05}}, Metrics: appmodel.AppMetrics{AverageReplicas: 2}})
+
+	summaries, totalCost := summarizeSidecars(apps)
+	if len(summaries) != 1 {
+		t.Fatalf("expected a single sidecar, got %+v", summaries)
+	}
+	s := summaries[0]
+	if s.Sidecar != "istio-proxy" || s.Apps != 3 || s.Pods != 7 || math.Abs(s.CpuRequest-0.7) > 1e-9 || s.MemoryRequest != 7*128*Mi {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if cost := 0.01 * 7 * appmodel.HOURS_PER_MONTH; math.Abs(s.MonthlyCost-cost) > 1e-9 {
+		t.Errorf("expected sidecar cost %v, got %v", cost, s.MonthlyCost)
+	}
+	if cost := (0.04*7 + 0.05*2) * appmodel.HOURS_PER_MONTH; math.Abs(totalCost-cost) > 1e-9 {
+		t.Errorf("expected total cost %v, got %v", cost, totalCost)
+	}
+
+	// 90th percentile of 10m, 14m, 20m is 20m; at 70% saturation -> 30m; 40Mi at 80% -> 50Mi
+	cpu, memory := s.suggestedRequests()
+	if math.Abs(cpu-0.03) > 1e-9 || memory != 50*Mi {
+		t.Errorf("expected suggested requests 30m/50Mi, got %v/%v", cpuString(cpu), memoryString(memory))
+	}
+	if cpu, memory := s.typicalRequests(); cpu != 0.1 || memory != 128*Mi {
+		t.Errorf("expected typical requests 100m/128Mi, got %v/%v", cpuString(cpu), memoryString(memory))
+	}
+}
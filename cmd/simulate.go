@@ -0,0 +1,317 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	prom "opsani-ignite/sources/prometheus"
+)
+
+var simulateSettings []string
+var simulateReplicas float64
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate <namespace> <deployment>",
+	Short: "Replay the application's usage against proposed resource settings",
+	Long: `Replays the usage collected over the analysis time range against proposed
+resource requests/limits and replica count, and reports how often usage would
+have exceeded the requests and limits, the expected CPU throttling and OOM
+exposure, and the resulting QoS class, efficiency rate and cost.
+
+Settings are given as <container>.<cpu|memory>.<request|limit>=<quantity>, using
+Kubernetes quantities; settings not given keep their current values. Example:
+
+  opsani-ignite simulate shop web --set web.cpu.request=500m --set web.memory.limit=1Gi --replicas 3
+
+CPU use is assumed to spread evenly across the replicas; memory use per pod is
+assumed not to change with the replica count.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runSimulate,
+}
+
+func init() {
+	simulateCmd.Flags().StringArrayVar(&simulateSettings, "set", nil, "Proposed setting, as <container>.<cpu|memory>.<request|limit>=<quantity> (repeatable)")
+	simulateCmd.Flags().Float64Var(&simulateReplicas, "replicas", 0, "Proposed replica count (default is the current average)")
+	rootCmd.AddCommand(simulateCmd)
+}
+
+// parseQuantity parses a Kubernetes resource quantity (e.g., 500m, 2, 512Mi, 1G) into a number (cores or bytes)
+func parseQuantity(s string) (float64, error) {
+	suffixes := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+		{"m", 1e-3}, {"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15}, {"E", 1e18},
+	}
+	number, multiplier := s, 1.0
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix.suffix) {
+			number, multiplier = strings.TrimSuffix(s, suffix.suffix), suffix.multiplier
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return v * multiplier, nil
+}
+
+// applySetting applies a proposed setting (<container>.<cpu|memory>.<request|limit>=<quantity>) to the app
+func applySetting(app *appmodel.App, setting string) error {
+	parts := strings.SplitN(setting, "=", 2)
+	path := strings.Split(parts[0], ".")
+	if len(parts) != 2 || len(path) != 3 {
+		return fmt.Errorf("invalid setting %q, expected <container>.<cpu|memory>.<request|limit>=<quantity>", setting)
+	}
+	index, ok := app.ContainerIndexByName(path[0])
+	if !ok {
+		return fmt.Errorf("invalid setting %q: container %q not found", setting, path[0])
+	}
+	value, err := parseQuantity(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid setting %q: %v", setting, err)
+	}
+
+	var r *appmodel.AppContainerResourceInfo
+	switch path[1] {
+	case "cpu":
+		r = &app.Containers[index].Cpu.AppContainerResourceInfo
+	case "memory":
+		r = &app.Containers[index].Memory.AppContainerResourceInfo
+	default:
+		return fmt.Errorf("invalid setting %q: unknown resource %q, expected cpu or memory", setting, path[1])
+	}
+	switch path[2] {
+	case "request":
+		r.Request = value
+	case "limit":
+		r.Limit = value
+	default:
+		return fmt.Errorf("invalid setting %q: unknown field %q, expected request or limit", setting, path[2])
+	}
+	return nil
+}
+
+// containerSimulation is the outcome of replaying a container's usage against its proposed settings
+type containerSimulation struct {
+	Name              string
+	CpuSamples        int
+	CpuOverRequest    float64 // fraction of samples with usage over the request
+	CpuOverLimit      float64 // fraction of samples with usage over the limit
+	CpuThrottling     float64 // fraction of the CPU demand above the limit (expected throttling)
+	MemorySamples     int
+	MemoryOverRequest float64 // fraction of samples with usage over the request
+	MemoryOverLimit   float64 // fraction of samples with usage at or over the limit (OOM exposure)
+	MemoryPeak        float64 // peak usage relative to the limit (0 if no limit)
+}
+
+// simulation is the outcome of replaying an app's usage against proposed settings
+type simulation struct {
+	Before, After     *appmodel.App
+	Containers        []containerSimulation
+	MonthlyCostBefore float64
+	MonthlyCostAfter  float64
+}
+
+// usageSamples returns the samples of a container's usage, per pod if available
+func usageSamples(byPod map[string]appmodel.TimeSeries, average appmodel.TimeSeries, scale float64) []float64 {
+	samples := []float64{}
+	if len(byPod) == 0 {
+		byPod = map[string]appmodel.TimeSeries{"": average}
+	}
+	for _, series := range byPod {
+		for _, s := range series {
+			if !math.IsNaN(s.Value) {
+				samples = append(samples, s.Value*scale)
+			}
+		}
+	}
+	return samples
+}
+
+// replay returns the fraction of samples over the request and over the limit (at or over, if atLimit),
+// and the fraction of the total usage above the limit
+func replay(samples []float64, request float64, limit float64, atLimit bool) (overRequest, overLimit, aboveLimit float64) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	var nOverRequest, nOverLimit int
+	var total, above float64
+	for _, v := range samples {
+		total += v
+		if request > 0 && v > request {
+			nOverRequest++
+		}
+		if limit > 0 && (v > limit || atLimit && v >= limit) {
+			nOverLimit++
+			above += v - limit
+		}
+	}
+	overRequest = float64(nOverRequest) / float64(len(samples))
+	overLimit = float64(nOverLimit) / float64(len(samples))
+	if total > 0 {
+		aboveLimit = above / total
+	}
+	return overRequest, overLimit, aboveLimit
+}
+
+// allocationMonthlyCost estimates the monthly cost of the app's allocated resources (requests, or limits
+// if not set, or usage if neither is set), so that current and proposed settings are costed the same way
+func allocationMonthlyCost(app *appmodel.App) float64 {
+	allocation := func(r *appmodel.AppContainerResourceInfo) float64 {
+		if r.Request > 0 {
+			return r.Request
+		}
+		if r.Limit > 0 {
+			return r.Limit
+		}
+		return r.Usage
+	}
+	cost := 0.0
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		cost += resourcesPseudoCost(allocation(&c.Cpu.AppContainerResourceInfo), allocation(&c.Memory.AppContainerResourceInfo))
+	}
+	return cost * app.Metrics.AverageReplicas * appmodel.HOURS_PER_MONTH
+}
+
+// simulateApp replays the analyzed app's usage against the proposed settings and replicas (0 to keep the
+// current replicas), and analyzes the app as if it ran with them
+func simulateApp(app *appmodel.App, settings []string, replicas float64) (*simulation, error) {
+	sim := *app
+	sim.Containers = append([]appmodel.AppContainer{}, app.Containers...)
+	for _, setting := range settings {
+		if err := applySetting(&sim, setting); err != nil {
+			return nil, err
+		}
+	}
+
+	// CPU use spreads across the replicas
+	cpuScale := 1.0
+	if replicas > 0 {
+		if app.Metrics.AverageReplicas > 0 {
+			cpuScale = app.Metrics.AverageReplicas / replicas
+		}
+		sim.Metrics.AverageReplicas = replicas
+	}
+
+	s := &simulation{Before: app, After: &sim}
+	for i := range sim.Containers {
+		c := &sim.Containers[i]
+		cpu := usageSamples(c.Series.CpuByPod, c.Series.Cpu, cpuScale)
+		mem := usageSamples(c.Series.MemoryByPod, c.Series.Memory, 1)
+		cs := containerSimulation{Name: c.Name, CpuSamples: len(cpu), MemorySamples: len(mem)}
+		cs.CpuOverRequest, cs.CpuOverLimit, cs.CpuThrottling = replay(cpu, c.Cpu.Request, c.Cpu.Limit, false)
+		cs.MemoryOverRequest, cs.MemoryOverLimit, _ = replay(mem, c.Memory.Request, c.Memory.Limit, true)
+		if len(mem) > 0 && c.Memory.Limit > 0 {
+			peak := 0.0
+			for _, v := range mem {
+				peak = math.Max(peak, v)
+			}
+			cs.MemoryPeak = peak / c.Memory.Limit
+		}
+		s.Containers = append(s.Containers, cs)
+
+		// re-derive utilization from the proposed settings
+		c.Cpu.Usage *= cpuScale
+		c.Cpu.Saturation = 0
+		c.Memory.Saturation = 0
+	}
+
+	// analyze the app with the proposed settings
+	sim.Settings.QosClass = computePodQoS(&sim)
+	sim.Analysis = appmodel.AppAnalysis{MainContainer: app.Analysis.MainContainer}
+	analyzeApp(&sim)
+
+	s.MonthlyCostBefore = allocationMonthlyCost(app)
+	s.MonthlyCostAfter = allocationMonthlyCost(&sim)
+	return s, nil
+}
+
+func outputSimulation(s *simulation) {
+	fmt.Printf("Simulation of %v/%v over %v - %v\n\n", s.Before.Metadata.Namespace, s.Before.Metadata.Workload, timeStart.Format("2006-01-02 15:04"), timeEnd.Format("2006-01-02 15:04"))
+
+	t := tablewriter.NewWriter(os.Stdout)
+	t.SetHeader([]string{"Container", "CPU Request", "CPU Limit", "Over Request", "Over Limit", "Throttling", "Mem Request", "Mem Limit", "Over Request", "OOM Exposure", "Peak/Limit"})
+	t.SetAutoFormatHeaders(false)
+	t.SetBorder(false)
+	percent := func(fraction float64) string { return fmt.Sprintf("%.1f%%", fraction*100) }
+	for _, cs := range s.Containers {
+		index, _ := s.After.ContainerIndexByName(cs.Name) // the analysis may reorder the containers
+		c := &s.After.Containers[index]
+		t.Append([]string{
+			cs.Name,
+			cpuString(c.Cpu.Request), cpuString(c.Cpu.Limit),
+			percent(cs.CpuOverRequest), percent(cs.CpuOverLimit), percent(cs.CpuThrottling),
+			memoryString(c.Memory.Request), memoryString(c.Memory.Limit),
+			percent(cs.MemoryOverRequest), percent(cs.MemoryOverLimit), percent(cs.MemoryPeak),
+		})
+	}
+	t.Render()
+
+	fmt.Println()
+	d := tablewriter.NewWriter(os.Stdout)
+	d.SetHeader([]string{"", "Current", "Proposed"})
+	d.SetBorder(false)
+	d.AppendBulk([][]string{
+		{"Replicas", fmt.Sprintf("%.1f", s.Before.Metrics.AverageReplicas), fmt.Sprintf("%.1f", s.After.Metrics.AverageReplicas)},
+		{"QoS Class", s.Before.Settings.QosClass, s.After.Settings.QosClass},
+		{"Efficiency Rate", appmodel.Rate2String(s.Before.Analysis.EfficiencyRate), appmodel.Rate2String(s.After.Analysis.EfficiencyRate)},
+		{"Reliability Risk", appmodel.Risk2String(s.Before.Analysis.ReliabilityRisk), appmodel.Risk2String(s.After.Analysis.ReliabilityRisk)},
+		{"Analysis", s.Before.Analysis.Conclusion.String(), s.After.Analysis.Conclusion.String()},
+		{"Monthly Cost", fmt.Sprintf("$%.2f", s.MonthlyCostBefore), fmt.Sprintf("$%.2f (%+.2f)", s.MonthlyCostAfter, s.MonthlyCostAfter-s.MonthlyCostBefore)},
+	})
+	d.Render()
+	if len(s.After.Analysis.Cautions) > 0 {
+		fmt.Printf("\nCautions with the proposed settings:\n  %v\n", strings.Join(s.After.Analysis.Cautions, "\n  "))
+	}
+}
+
+func runSimulate(cmd *cobra.Command, args []string) {
+	logFile := setupLogFile()
+	defer logFile.Close()
+
+	namespace, deployment := args[0], args[1]
+	prom.Init()
+	apps, err := collectAndAnalyze(context.Background(), promUri, clusterName, namespace, deployment, timeStart, timeEnd, timeStep, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to obtain data from Prometheus at %q: %v\n", promUri, err)
+		os.Exit(1)
+	}
+	var app *appmodel.App
+	for _, a := range apps {
+		if a.Metadata.Workload == deployment {
+			app = a
+			break
+		}
+	}
+	if app == nil {
+		fmt.Fprintf(os.Stderr, "Application %q not found in namespace %q\n", deployment, namespace)
+		os.Exit(1)
+	}
+
+	s, err := simulateApp(app, simulateSettings, simulateReplicas)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	log.Infof("Simulated app %v with settings %v, replicas %v", app.Metadata, simulateSettings, simulateReplicas)
+	outputSimulation(s)
+}
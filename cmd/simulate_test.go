@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		s     string
+		value float64
+	}{
+		{"2", 2},
+		{"500m", 0.5},
+		{"1.5", 1.5},
+		{"512Mi", 512 * 1024 * 1024},
+		{"1Gi", 1024 * 1024 * 1024},
+		{"1G", 1e9},
+		{"100k", 1e5},
+	}
+	for _, tt := range tests {
+		if v, err := parseQuantity(tt.s); err != nil || v != tt.value {
+			t.Errorf("%q: expected %v, got %v (error %v)", tt.s, tt.value, v, err)
+		}
+	}
+	for _, s := range []string{"", "abc", "1Xi", "-1"} {
+		if _, err := parseQuantity(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestSimulateApp(t *testing.T) {
+	const GiB = 1024 * 1024 * 1024
+	app := &appmodel.App{
+		Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "web"},
+		Metrics:  appmodel.AppMetrics{AverageReplicas: 4, RequestRate: 10},
+	}
+	c := containerWithResources("web", 1, 1, 2*GiB, 2*GiB)
+	c.Cpu.Usage, c.Memory.Usage = 0.5, 0.9*GiB
+	c.Series.CpuByPod = map[string]appmodel.TimeSeries{
+		"web-1": hourlyValues(0.2, 0.4, 0.6, 0.8),
+		"web-2": hourlyValues(0.3, 0.5, 0.5, 0.7),
+	}
+	c.Series.Memory = hourlyValues(0.5*GiB, 0.8*GiB, 1.0*GiB, 1.3*GiB)
+	app.Containers = []appmodel.AppContainer{c}
+	analyzeApp(app)
+
+	s, err := simulateApp(app, []string{"web.cpu.request=500m", "web.memory.limit=1Gi"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Containers[0].Cpu.Request != 1 || app.Metrics.AverageReplicas != 4 {
+		t.Errorf("expected the analyzed app to be unchanged, got %+v", app.Containers[0])
+	}
+
+	// CPU use doubles with half the replicas: 0.4, 0.8, 1.2, 1.6, 0.6, 1.0, 1.0, 1.4
+	cs := s.Containers[0]
+	if cs.CpuSamples != 8 || cs.CpuOverRequest != 7.0/8 || cs.CpuOverLimit != 3.0/8 {
+		t.Errorf("unexpected CPU replay %+v", cs)
+	}
+	// 1.2 cores above the limit, of a total demand of 8 cores
+	if throttling := (0.2 + 0.6 + 0.4) / 8.0; math.Abs(cs.CpuThrottling-throttling) > 1e-9 {
+		t.Errorf("expected throttling %v, got %v", throttling, cs.CpuThrottling)
+	}
+	// memory request stays at 2GiB, limit is now 1GiB
+	if cs.MemorySamples != 4 || cs.MemoryOverRequest != 0 || cs.MemoryOverLimit != 0.5 || math.Abs(cs.MemoryPeak-1.3) > 1e-9 {
+		t.Errorf("unexpected memory replay %+v", cs)
+	}
+
+	if s.After.Settings.QosClass != appmodel.QOS_BURSTABLE {
+		t.Errorf("expected the proposed settings to be Burstable, got %v", s.After.Settings.QosClass)
+	}
+	if s.After.Analysis.EfficiencyRate == nil || s.Before.Analysis.EfficiencyRate == nil || *s.After.Analysis.EfficiencyRate <= *s.Before.Analysis.EfficiencyRate {
+		t.Errorf("expected the efficiency rate to improve, got %v -> %v", appmodel.Rate2String(s.Before.Analysis.EfficiencyRate), appmodel.Rate2String(s.After.Analysis.EfficiencyRate))
+	}
+	if s.MonthlyCostAfter >= s.MonthlyCostBefore {
+		t.Errorf("expected the cost to go down, got %v -> %v", s.MonthlyCostBefore, s.MonthlyCostAfter)
+	}
+
+	for _, setting := range []string{"db.cpu.request=1", "web.disk.request=1", "web.cpu.max=1", "web.cpu.request", "web.cpu.request=lots"} {
+		if _, err := simulateApp(app, []string{setting}, 0); err == nil {
+			t.Errorf("%q: expected an error", setting)
+		}
+	}
+}
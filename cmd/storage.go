@@ -0,0 +1,133 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	appmodel "opsani-ignite/app/model"
+	opsmath "opsani-ignite/math"
+)
+
+// --- Init Containers --------------------------------------------------------
+
+type initContainersRule struct {
+	Excess float64 `yaml:"excess"` // fraction by which an init container's request must exceed the containers' total to report it
+}
+
+func (r *initContainersRule) Id() string             { return "init-containers" }
+func (r *initContainersRule) Category() string       { return RULE_CATEGORY_RESOURCES }
+func (r *initContainersRule) Severity() RuleSeverity { return SEVERITY_INFO }
+func (r *initContainersRule) Description() string {
+	return "Init container requests above the containers' total raise the resources reserved for every pod"
+}
+
+func (r *initContainersRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	cpu, memory, _ := app.ContainersRequests()
+	for i := range app.InitContainers {
+		c := &app.InitContainers[i]
+		if c.Cpu.Request > cpu*(1+r.Excess) {
+			o.Recommendations = append(o.Recommendations, fmt.Sprintf("Init container %q requests %v CPU, more than the containers' %v, raising the pod's effective request; reduce it",
+				c.Name, cpuString(c.Cpu.Request), cpuString(cpu)))
+		}
+		if c.Memory.Request > memory*(1+r.Excess) {
+			o.Recommendations = append(o.Recommendations, fmt.Sprintf("Init container %q requests %v memory, more than the containers' %v, raising the pod's effective request; reduce it",
+				c.Name, memoryString(c.Memory.Request), memoryString(memory)))
+		}
+	}
+}
+
+// initContainersString lists the app's init containers and the pod's effective requests, if the init containers raise them
+func initContainersString(app *appmodel.App) string {
+	if len(app.InitContainers) == 0 {
+		return "-"
+	}
+	names := make([]string, len(app.InitContainers))
+	for i := range app.InitContainers {
+		names[i] = app.InitContainers[i].Name
+	}
+	s := strings.Join(names, ", ")
+
+	cpu, memory, _ := app.ContainersRequests()
+	effectiveCpu, effectiveMemory, _ := app.PodEffectiveRequests()
+	if effectiveCpu > cpu || effectiveMemory > memory {
+		s += fmt.Sprintf(" (pod requests %v CPU, %v memory)", cpuString(effectiveCpu), memoryString(effectiveMemory))
+	}
+	return s
+}
+
+// --- Ephemeral Storage ------------------------------------------------------
+
+type ephemeralStorageRule struct {
+	HighUsage      float64 `yaml:"high_usage"`      // fraction of the limit used at peak that makes eviction likely
+	CloseUsage     float64 `yaml:"close_usage"`     // fraction of the limit used at peak that comes close to eviction
+	MinUnrequested float64 `yaml:"min_unrequested"` // use (in bytes) above the request from which to recommend a request
+}
+
+func (r *ephemeralStorageRule) Id() string             { return "ephemeral-storage" }
+func (r *ephemeralStorageRule) Category() string       { return RULE_CATEGORY_RELIABILITY }
+func (r *ephemeralStorageRule) Severity() RuleSeverity { return SEVERITY_WARNING }
+func (r *ephemeralStorageRule) Description() string {
+	return "Ephemeral storage use above the limit gets pods evicted, and above the request makes them first to go under node disk pressure"
+}
+
+// peakEphemeralStorage returns the container's peak ephemeral storage use, falling back to its average use
+func peakEphemeralStorage(c *appmodel.AppContainer) float64 {
+	if peak := opsmath.Max(c.Series.EphemeralStorage.Values()...); !math.IsNaN(peak) {
+		return peak
+	}
+	return c.EphemeralStorage.Usage
+}
+
+func (r *ephemeralStorageRule) Evaluate(app *appmodel.App, o *appmodel.AppAnalysis) {
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		peak := peakEphemeralStorage(c)
+		if peak <= 0 {
+			continue
+		}
+
+		if limit := c.EphemeralStorage.Limit; limit > 0 && peak >= limit*r.CloseUsage {
+			var risk appmodel.RiskLevel = appmodel.RISK_MEDIUM
+			if peak >= limit*r.HighUsage {
+				risk = appmodel.RISK_HIGH
+			}
+			o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, risk)
+			o.Cautions = append(o.Cautions, fmt.Sprintf("Ephemeral storage use of container %q peaked at %.0f%% of its %v limit; the pod is evicted when it exceeds the limit",
+				c.Name, peak/limit*100, memoryString(limit)))
+			o.Recommendations = append(o.Recommendations, fmt.Sprintf("Raise the ephemeral storage limit of container %q or clean up its local files (logs, caches)", c.Name))
+		}
+
+		if peak > c.EphemeralStorage.Request && peak-c.EphemeralStorage.Request >= r.MinUnrequested {
+			o.ReliabilityRisk = bumpRisk(o.ReliabilityRisk, appmodel.RISK_LOW)
+			o.Recommendations = append(o.Recommendations, fmt.Sprintf("Request at least %v ephemeral storage for container %q; pods using more than they request are evicted first under node disk pressure",
+				memoryString(peak), c.Name))
+		}
+	}
+}
+
+// ephemeralStorageString describes the pod's ephemeral storage requests, limits and peak use
+func ephemeralStorageString(app *appmodel.App) string {
+	_, _, request := app.ContainersRequests()
+	limit, peak := 0.0, 0.0
+	limited := true
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		limit += c.EphemeralStorage.Limit
+		limited = limited && c.EphemeralStorage.Limit > 0
+		peak += peakEphemeralStorage(c)
+	}
+	if request == 0 && limit == 0 && peak == 0 {
+		return "-"
+	}
+	limitString := "none"
+	if limited {
+		limitString = memoryString(limit)
+	}
+	return fmt.Sprintf("request %v, limit %v, peak use %v", memoryString(request), limitString, memoryString(peak))
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestComputePodQoSInitContainers(t *testing.T) {
+	app := &appmodel.App{Containers: []appmodel.AppContainer{containerWithResources("web", 1, 1, 512, 512)}}
+	if qos := computePodQoS(app); qos != appmodel.QOS_GUARANTEED {
+		t.Fatalf("expected %v, got %v", appmodel.QOS_GUARANTEED, qos)
+	}
+	app.InitContainers = []appmodel.AppContainer{containerWithResources("migrate", 0.5, 0, 0, 0)}
+	if qos := computePodQoS(app); qos != appmodel.QOS_BURSTABLE {
+		t.Errorf("expected an init container without limits to make the pod %v, got %v", appmodel.QOS_BURSTABLE, qos)
+	}
+}
+
+func TestPodEffectiveRequests(t *testing.T) {
+	app := &appmodel.App{
+		Containers: []appmodel.AppContainer{
+			containerWithResources("web", 0.5, 0, 256, 0),
+			containerWithResources("proxy", 0.1, 0, 64, 0),
+		},
+		InitContainers: []appmodel.AppContainer{
+			containerWithResources("migrate", 2, 0, 128, 0),
+			containerWithResources("fetch", 0.2, 0, 300, 0),
+		},
+	}
+	if cpu, memory, _ := app.ContainersRequests(); cpu != 0.6 || memory != 320 {
+		t.Errorf("expected containers' requests 0.6 cores/320 bytes, got %v/%v", cpu, memory)
+	}
+	if cpu, memory, _ := app.PodEffectiveRequests(); cpu != 2 || memory != 320 {
+		t.Errorf("expected effective requests 2 cores/320 bytes, got %v/%v", cpu, memory)
+	}
+
+	o := evaluateRule(t, "init-containers", app)
+	if len(o.Recommendations) != 1 {
+		t.Errorf("expected a recommendation to reduce the migrate CPU request, got %v", o.Recommendations)
+	}
+}
+
+func TestInitContainersRule(t *testing.T) {
+	tests := []struct {
+		name            string
+		init            []appmodel.AppContainer
+		recommendations int
+	}{
+		{"none", nil, 0},
+		{"below requests", []appmodel.AppContainer{containerWithResources("migrate", 0.4, 0, 200, 0)}, 0},
+		{"within excess", []appmodel.AppContainer{containerWithResources("migrate", 0.54, 0, 280, 0)}, 0},
+		{"cpu above", []appmodel.AppContainer{containerWithResources("migrate", 1, 0, 100, 0)}, 1},
+		{"cpu and memory above", []appmodel.AppContainer{containerWithResources("migrate", 1, 0, 512, 0)}, 2},
+		{"several above", []appmodel.AppContainer{containerWithResources("migrate", 1, 0, 0, 0), containerWithResources("fetch", 0, 0, 512, 0)}, 2},
+	}
+	for _, tt := range tests {
+		app := &appmodel.App{
+			Containers:     []appmodel.AppContainer{containerWithResources("web", 0.5, 0, 256, 0)},
+			InitContainers: tt.init,
+		}
+		o := evaluateRule(t, "init-containers", app)
+		if len(o.Recommendations) != tt.recommendations {
+			t.Errorf("%v: expected %v recommendation(s), got %v", tt.name, tt.recommendations, o.Recommendations)
+		}
+	}
+}
+
+func TestEphemeralStorageRule(t *testing.T) {
+	const Mi = 1024 * 1024
+	withStorage := func(request, limit float64, use ...float64) *appmodel.App {
+		c := containerWithResources("web", 1, 1, 0, 0)
+		c.EphemeralStorage.Request, c.EphemeralStorage.Limit = request, limit
+		c.Series.EphemeralStorage = hourlyValues(use...)
+		return &appmodel.App{Containers: []appmodel.AppContainer{c}}
+	}
+
+	tests := []struct {
+		name            string
+		app             *appmodel.App
+		risk            appmodel.RiskLevel
+		cautions        int
+		recommendations int
+	}{
+		{"no use", withStorage(0, 0), appmodel.RISK_UNKNOWN, 0, 0},
+		{"within request", withStorage(1024*Mi, 2048*Mi, 200*Mi, 300*Mi), appmodel.RISK_UNKNOWN, 0, 0},
+		{"close to limit", withStorage(2048*Mi, 1000*Mi, 500*Mi, 800*Mi), appmodel.RISK_MEDIUM, 1, 1},
+		{"at limit", withStorage(2048*Mi, 1000*Mi, 500*Mi, 950*Mi), appmodel.RISK_HIGH, 1, 1},
+		{"unrequested", withStorage(0, 0, 50*Mi, 400*Mi), appmodel.RISK_LOW, 0, 1},
+		{"small unrequested", withStorage(0, 0, 10*Mi, 20*Mi), appmodel.RISK_UNKNOWN, 0, 0},
+	}
+	for _, tt := range tests {
+		o := evaluateRule(t, "ephemeral-storage", tt.app)
+		if risk := o.ReliabilityRisk.SafeRiskLevel(); risk != tt.risk || len(o.Cautions) != tt.cautions || len(o.Recommendations) != tt.recommendations {
+			t.Errorf("%v: expected risk %v, %v caution(s), %v recommendation(s); got %v, %v, %v",
+				tt.name, tt.risk, tt.cautions, tt.recommendations, risk, o.Cautions, o.Recommendations)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+	opsmath "opsani-ignite/math"
+)
+
+// resourceBounds returns the range to right-size a container's resource within, derived from its observed use:
+// no less than the average use (or the peak use, if the resource cannot be throttled) and no more than the larger
+// of the current allocation and the allocation that puts the peak use at the target saturation. The range always
+// includes the current allocation, so that the running setting stays valid. Without usage data, it falls back
+// to a quarter to twice the current allocation. Returns zeros if neither is known.
+func resourceBounds(r *appmodel.AppContainerResourceInfo, series appmodel.TimeSeries, targetSaturation float64, peakFloor bool) (min, max float64) {
+	current := selectResourceValue(r)
+	if r.Usage == 0 {
+		return current / 4, current * 2
+	}
+	peak := r.Usage
+	if len(series) > 0 {
+		values := make([]float64, len(series))
+		for i, s := range series {
+			values[i] = s.Value
+		}
+		peak = math.Max(peak, opsmath.Max(values...))
+	}
+	min = r.Usage
+	if peakFloor {
+		min = peak
+	}
+	if current > 0 {
+		min = math.Min(min, current) // under-allocated
+	}
+	max = math.Max(current, peak/targetSaturation)
+	return min, math.Max(min, max)
+}
+
+// cpuBounds returns the range to right-size the container's CPU within, in cores
+func cpuBounds(c *appmodel.AppContainer) (min, max float64) {
+	return resourceBounds(&c.Cpu.AppContainerResourceInfo, c.Series.Cpu, PATCH_CPU_TARGET_SATURATION, false)
+}
+
+// memoryBounds returns the range to right-size the container's memory within, in bytes
+func memoryBounds(c *appmodel.AppContainer) (min, max float64) {
+	return resourceBounds(&c.Memory.AppContainerResourceInfo, c.Series.Memory, PATCH_MEMORY_TARGET_SATURATION, true)
+}
+
+// vpaControlledValues chooses the resource values the VPA recommends for, keeping the app's QoS class:
+// Guaranteed pods need limits kept equal to requests, while other pods keep their limits as safety caps
+func vpaControlledValues(qosClass string) string {
+	if qosClass == appmodel.QOS_GUARANTEED {
+		return "RequestsAndLimits"
+	}
+	return "RequestsOnly"
+}
+
+type vpaContainerPolicy struct {
+	ContainerName       string         `yaml:"containerName"`
+	MinAllowed          patchResources `yaml:"minAllowed,omitempty"`
+	MaxAllowed          patchResources `yaml:"maxAllowed,omitempty"`
+	ControlledResources []string       `yaml:"controlledResources"`
+	ControlledValues    string         `yaml:"controlledValues"`
+}
+
+// vpaManifest is an autoscaling.k8s.io/v1 VerticalPodAutoscaler manifest
+type vpaManifest struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		TargetRef struct {
+			ApiVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Name       string `yaml:"name"`
+		} `yaml:"targetRef"`
+		UpdatePolicy struct {
+			UpdateMode string `yaml:"updateMode"`
+		} `yaml:"updatePolicy"`
+		ResourcePolicy struct {
+			ContainerPolicies []vpaContainerPolicy `yaml:"containerPolicies"`
+		} `yaml:"resourcePolicy"`
+	} `yaml:"spec"`
+}
+
+// buildVpa builds a recommendation-only VPA for the app, bounding each container's recommendations to the range
+// derived from its use; containers with neither usage data nor resource settings are left to the VPA's defaults.
+// Returns nil if there are no bounds for any container.
+func buildVpa(app *appmodel.App) *vpaManifest {
+	v := &vpaManifest{ApiVersion: "autoscaling.k8s.io/v1", Kind: "VerticalPodAutoscaler"}
+	v.Metadata.Name = app.Metadata.Workload
+	v.Metadata.Namespace = app.Metadata.Namespace
+	v.Spec.TargetRef.ApiVersion = app.Metadata.WorkloadApiVersion
+	v.Spec.TargetRef.Kind = app.Metadata.WorkloadKind
+	v.Spec.TargetRef.Name = app.Metadata.Workload
+	v.Spec.UpdatePolicy.UpdateMode = "Off"
+	for i := range app.Containers {
+		c := &app.Containers[i]
+		if c.Sidecar != "" {
+			continue // injected, not in the pod template
+		}
+		p := vpaContainerPolicy{
+			ContainerName:       c.Name,
+			ControlledResources: []string{"cpu", "memory"},
+			ControlledValues:    vpaControlledValues(app.Settings.QosClass),
+		}
+		cpuMin, cpuMax := cpuBounds(c)
+		p.MinAllowed.Cpu, p.MaxAllowed.Cpu = patchCpuString(cpuMin), patchCpuString(cpuMax)
+		memMin, memMax := memoryBounds(c)
+		p.MinAllowed.Memory, p.MaxAllowed.Memory = patchMemoryString(memMin), patchMemoryString(memMax)
+		if p.MaxAllowed != (patchResources{}) {
+			v.Spec.ResourcePolicy.ContainerPolicies = append(v.Spec.ResourcePolicy.ContainerPolicies, p)
+		}
+	}
+	if len(v.Spec.ResourcePolicy.ContainerPolicies) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (table *AppTable) outputVpaApp(app *appmodel.App) {
+	v := buildVpa(app)
+	if v == nil {
+		log.Warnf("No VPA produced for application %v: no container usage data or resource settings", app.Metadata)
+		return
+	}
+
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		log.Errorf("Failed to marshal VPA for app %v to yaml: %v", app.Metadata, err)
+		return
+	}
+	node.HeadComment = fmt.Sprintf("Recommendation-only vertical pod autoscaler for %v/%v (%v QoS), bounded by the observed use\n"+
+		"Review, then apply with: kubectl apply -f <file>; read the recommendations with: kubectl describe vpa -n %v %v",
+		app.Metadata.Namespace, app.Metadata.Workload, app.Settings.QosClass, app.Metadata.Namespace, v.Metadata.Name)
+	if violations := policyViolationsComment(app); violations != "" {
+		node.HeadComment += "\n" + violations
+	}
+	if err := table.yaml.Encode(&node); err != nil {
+		log.Errorf("Failed to write VPA for app %v to yaml: %v", app.Metadata, err)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"testing"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestResourceBounds(t *testing.T) {
+	const Mi = 1024 * 1024
+	tests := []struct {
+		name      string
+		resource  appmodel.AppContainerResourceInfo
+		series    appmodel.TimeSeries
+		target    float64
+		peakFloor bool
+		min, max  float64
+	}{
+		{"within request", appmodel.AppContainerResourceInfo{Request: 1, Usage: 0.3}, hourlyValues(0.1, 0.7, 0.2), 0.7, false, 0.3, 1},
+		{"peak above target", appmodel.AppContainerResourceInfo{Request: 1, Usage: 0.3}, hourlyValues(0.1, 1.4, 0.2), 0.7, false, 0.3, 2},
+		{"memory floor at peak", appmodel.AppContainerResourceInfo{Request: 512 * Mi, Usage: 200 * Mi}, hourlyValues(100*Mi, 300*Mi), 0.8, true, 300 * Mi, 512 * Mi},
+		{"no series", appmodel.AppContainerResourceInfo{Limit: 2, Usage: 1.6}, nil, 0.8, false, 1.6, 2},
+		{"under-requested", appmodel.AppContainerResourceInfo{Request: 0.1, Usage: 0.3}, hourlyValues(0.2, 0.35), 0.7, false, 0.1, 0.5},
+		{"memory peak above request", appmodel.AppContainerResourceInfo{Request: 256 * Mi, Limit: 1024 * Mi, Usage: 200 * Mi}, hourlyValues(200*Mi, 320*Mi), 0.8, true, 256 * Mi, 400 * Mi},
+		{"no usage", appmodel.AppContainerResourceInfo{Request: 2}, nil, 0.7, false, 0.5, 4},
+		{"nothing known", appmodel.AppContainerResourceInfo{}, nil, 0.7, false, 0, 0},
+	}
+	for _, tt := range tests {
+		min, max := resourceBounds(&tt.resource, tt.series, tt.target, tt.peakFloor)
+		if min != tt.min || max != tt.max {
+			t.Errorf("%v: expected %v-%v, got %v-%v", tt.name, tt.min, tt.max, min, max)
+		}
+	}
+}
+
+func TestBuildVpa(t *testing.T) {
+	app := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "web", WorkloadKind: "Deployment", WorkloadApiVersion: "apps/v1"}}
+	app.Settings.QosClass = appmodel.QOS_GUARANTEED
+	web := containerWithResources("web", 1, 1, 512*1024*1024, 512*1024*1024)
+	web.Cpu.Usage, web.Memory.Usage = 0.3, 200*1024*1024
+	proxy := containerWithResources("istio-proxy", 0.1, 2, 128*1024*1024, 1024*1024*1024)
+	proxy.Cpu.Usage, proxy.Sidecar = 0.02, "istio-proxy"
+	app.Containers = []appmodel.AppContainer{web, {Name: "idle"}, proxy}
+
+	v := buildVpa(app)
+	if v == nil {
+		t.Fatal("expected a VPA")
+	}
+	if v.ApiVersion != "autoscaling.k8s.io/v1" || v.Kind != "VerticalPodAutoscaler" || v.Metadata.Name != "web" || v.Metadata.Namespace != "shop" {
+		t.Errorf("unexpected VPA identity: %+v", v)
+	}
+	if ref := v.Spec.TargetRef; ref.ApiVersion != "apps/v1" || ref.Kind != "Deployment" || ref.Name != "web" || v.Spec.UpdatePolicy.UpdateMode != "Off" {
+		t.Errorf("unexpected target or update mode: %+v", v.Spec)
+	}
+	policies := v.Spec.ResourcePolicy.ContainerPolicies
+	if len(policies) != 1 || policies[0].ContainerName != "web" {
+		t.Fatalf("expected a policy for the non-sidecar container with data only, got %+v", policies)
+	}
+	p := policies[0]
+	expected := []struct{ name, got, want string }{
+		{"cpu min", p.MinAllowed.Cpu, "300m"},
+		{"cpu max", p.MaxAllowed.Cpu, "1000m"},
+		{"memory min", p.MinAllowed.Memory, "200Mi"},
+		{"memory max", p.MaxAllowed.Memory, "512Mi"},
+		{"controlled values", p.ControlledValues, "RequestsAndLimits"},
+	}
+	for _, e := range expected {
+		if e.got != e.want {
+			t.Errorf("%v: expected %q, got %q", e.name, e.want, e.got)
+		}
+	}
+
+	app.Settings.QosClass = appmodel.QOS_BURSTABLE
+	if p := buildVpa(app).Spec.ResourcePolicy.ContainerPolicies[0]; p.ControlledValues != "RequestsOnly" {
+		t.Errorf("expected Burstable apps to keep their limits, got %q", p.ControlledValues)
+	}
+	if buildVpa(&appmodel.App{Containers: []appmodel.AppContainer{{Name: "idle"}}}) != nil {
+		t.Error("expected no VPA without usage data or resource settings")
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+const namespace = "ignite"
+
+var appLabels = []string{"cluster", "namespace", "workload"}
+
+var (
+	efficiencyRateDesc = prometheus.NewDesc(namespace+"_app_efficiency_rate",
+		"Efficiency rate of the application, in percent (0-100)", appLabels, nil)
+	reliabilityRiskDesc = prometheus.NewDesc(namespace+"_app_reliability_risk",
+		"Reliability risk of the application (1=none, 2=low, 3=medium, 4=high, 5=critical)", appLabels, nil)
+	ratingDesc = prometheus.NewDesc(namespace+"_app_rating",
+		"Optimization rating of the application (-100..100)", appLabels, nil)
+	confidenceDesc = prometheus.NewDesc(namespace+"_app_confidence",
+		"Confidence in the application's rating, in percent (0-100)", appLabels, nil)
+	monthlyCostDesc = prometheus.NewDesc(namespace+"_app_monthly_cost",
+		"Estimated monthly cost of the application's replicas", appLabels, nil)
+	flagDesc = prometheus.NewDesc(namespace+"_app_flag",
+		"Opsani flags of the application (1=set, 0=not set)", append(appLabels, "flag"), nil)
+	policyViolationDesc = prometheus.NewDesc(namespace+"_app_policy_violation",
+		"Policy rules violated by the application (always 1)", append(appLabels, "policy", "severity"), nil)
+
+	lastRunDesc = prometheus.NewDesc(namespace+"_last_run_timestamp_seconds",
+		"Time the last successful analysis run completed", nil, nil)
+	runDurationDesc = prometheus.NewDesc(namespace+"_last_run_duration_seconds",
+		"Duration of the last successful analysis run", nil, nil)
+	runsDesc = prometheus.NewDesc(namespace+"_runs_total",
+		"Number of analysis runs, by result", []string{"result"}, nil)
+)
+
+// Collector publishes the findings of the most recent analysis run as metrics
+type Collector struct {
+	lock         sync.RWMutex
+	apps         []*appmodel.App
+	lastRun      time.Time
+	lastDuration time.Duration
+	succeeded    int
+	failed       int
+}
+
+// NewCollector creates a collector with no findings yet
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Update replaces the published findings with the results of a completed run
+func (c *Collector) Update(apps []*appmodel.App, completed time.Time, duration time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.apps = apps
+	c.lastRun = completed
+	c.lastDuration = duration
+	c.succeeded++
+}
+
+// RecordFailure counts a failed run; the findings of the last successful run remain published
+func (c *Collector) RecordFailure() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.failed++
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{efficiencyRateDesc, reliabilityRiskDesc, ratingDesc, confidenceDesc,
+		monthlyCostDesc, flagDesc, policyViolationDesc, lastRunDesc, runDurationDesc, runsDesc} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(runsDesc, prometheus.CounterValue, float64(c.succeeded), "success")
+	ch <- prometheus.MustNewConstMetric(runsDesc, prometheus.CounterValue, float64(c.failed), "failure")
+	if c.lastRun.IsZero() {
+		return // no findings yet
+	}
+	ch <- prometheus.MustNewConstMetric(lastRunDesc, prometheus.GaugeValue, float64(c.lastRun.Unix()))
+	ch <- prometheus.MustNewConstMetric(runDurationDesc, prometheus.GaugeValue, c.lastDuration.Seconds())
+
+	for _, app := range c.apps {
+		labels := []string{app.Metadata.Cluster, app.Metadata.Namespace, app.Metadata.Workload}
+		gauge := func(desc *prometheus.Desc, value float64, extraLabels ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labels, extraLabels...)...)
+		}
+
+		// unknown values are not published, rather than published as 0
+		if app.Analysis.EfficiencyRate != nil {
+			gauge(efficiencyRateDesc, float64(*app.Analysis.EfficiencyRate))
+		}
+		if risk := app.Analysis.ReliabilityRisk.SafeRiskLevel(); risk != appmodel.RISK_UNKNOWN {
+			gauge(reliabilityRiskDesc, float64(risk))
+		}
+		gauge(ratingDesc, float64(app.Analysis.Rating))
+		gauge(confidenceDesc, float64(app.Analysis.Confidence))
+		gauge(monthlyCostDesc, app.MonthlyCost())
+		for flag, set := range app.Analysis.Flags {
+			value := 0.0
+			if set {
+				value = 1
+			}
+			gauge(flagDesc, value, flag.String())
+		}
+		for _, v := range app.Analysis.Violations {
+			gauge(policyViolationDesc, 1, v.Policy, v.Severity)
+		}
+	}
+}
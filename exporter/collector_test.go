@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+
+	// no findings before the first run
+	if n := testutil.CollectAndCount(c, "ignite_app_rating"); n != 0 {
+		t.Errorf("expected no app metrics before the first run, got %v", n)
+	}
+
+	rate := 45
+	risk := appmodel.RiskLevel(appmodel.RISK_MEDIUM)
+	app := &appmodel.App{Metadata: appmodel.AppMetadata{Cluster: "prod", Namespace: "shop", Workload: "web"}}
+	app.Analysis.Rating = 35
+	app.Analysis.EfficiencyRate = &rate
+	app.Analysis.ReliabilityRisk = &risk
+	app.Analysis.Flags = map[appmodel.AppFlag]bool{appmodel.F_WRITEABLE_VOLUME: true, appmodel.F_TRAFFIC: false}
+	unknown := &appmodel.App{Metadata: appmodel.AppMetadata{Cluster: "prod", Namespace: "shop", Workload: "db"}}
+	c.Update([]*appmodel.App{app, unknown}, time.Unix(1633046400, 0), 2*time.Second)
+
+	expected := `
+# HELP ignite_app_efficiency_rate Efficiency rate of the application, in percent (0-100)
+# TYPE ignite_app_efficiency_rate gauge
+ignite_app_efficiency_rate{cluster="prod",namespace="shop",workload="web"} 45
+# HELP ignite_app_flag Opsani flags of the application (1=set, 0=not set)
+# TYPE ignite_app_flag gauge
+ignite_app_flag{cluster="prod",flag="T",namespace="shop",workload="web"} 0
+ignite_app_flag{cluster="prod",flag="W",namespace="shop",workload="web"} 1
+# HELP ignite_app_rating Optimization rating of the application (-100..100)
+# TYPE ignite_app_rating gauge
+ignite_app_rating{cluster="prod",namespace="shop",workload="db"} 0
+ignite_app_rating{cluster="prod",namespace="shop",workload="web"} 35
+# HELP ignite_app_reliability_risk Reliability risk of the application (1=none, 2=low, 3=medium, 4=high, 5=critical)
+# TYPE ignite_app_reliability_risk gauge
+ignite_app_reliability_risk{cluster="prod",namespace="shop",workload="web"} 3
+# HELP ignite_last_run_timestamp_seconds Time the last successful analysis run completed
+# TYPE ignite_last_run_timestamp_seconds gauge
+ignite_last_run_timestamp_seconds 1.6330464e+09
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"ignite_app_efficiency_rate", "ignite_app_flag", "ignite_app_rating", "ignite_app_reliability_risk", "ignite_last_run_timestamp_seconds")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// failures keep the last findings
+	c.RecordFailure()
+	if n := testutil.CollectAndCount(c, "ignite_app_rating"); n != 2 {
+		t.Errorf("expected findings to be kept after a failed run, got %v metrics", n)
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+// Each run is kept in its own file in the store directory. The file contains two yaml documents:
+// the run info (so that runs can be listed without loading all apps) followed by the list of apps.
+
+const (
+	runFilePrefix = "run-"
+	runFileSuffix = ".yaml"
+	runIdFormat   = "20060102T150405Z"
+)
+
+// RunInfo describes a single recorded run
+type RunInfo struct {
+	Id         string        `yaml:"id"`
+	Timestamp  time.Time     `yaml:"timestamp"`
+	Prometheus string        `yaml:"prometheus"`
+	Namespace  string        `yaml:"namespace,omitempty"` // empty if all namespaces were analyzed
+	Workload   string        `yaml:"workload,omitempty"`  // empty if all workloads were analyzed
+	Start      time.Time     `yaml:"start"`
+	End        time.Time     `yaml:"end"`
+	Step       time.Duration `yaml:"step"`
+	AppCount   int           `yaml:"app_count"`
+}
+
+// Run is a recorded run, including the analyzed applications
+type Run struct {
+	Info RunInfo
+	Apps []*appmodel.App
+}
+
+// RetentionPolicy defines which runs are removed when the store is pruned. Zero values disable the respective limit.
+type RetentionPolicy struct {
+	MaxAge  time.Duration // remove runs older than this
+	MaxRuns int           // keep at most this many of the most recent runs
+}
+
+// AppTrendPoint holds the values tracked over time for a single app in a single run
+type AppTrendPoint struct {
+	Run             RunInfo
+	EfficiencyRate  *int
+	ReliabilityRisk *appmodel.RiskLevel
+	MonthlyCost     float64
+}
+
+// Store is a file-based store of runs
+type Store struct {
+	dir string
+}
+
+// Open opens the store in the given directory, creating the directory if needed
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory %q: %v", dir, err)
+	}
+	return &Store{dir}, nil
+}
+
+// Dir returns the store's directory
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) runPath(id string) string {
+	return filepath.Join(s.dir, runFilePrefix+id+runFileSuffix)
+}
+
+// Save records a run. The run's Id is assigned from its timestamp (set to now if not provided).
+func (s *Store) Save(run *Run) error {
+	if run.Info.Timestamp.IsZero() {
+		run.Info.Timestamp = time.Now()
+	}
+	run.Info.Timestamp = run.Info.Timestamp.UTC()
+	run.Info.AppCount = len(run.Apps)
+
+	// write to a temp file first, so that partial runs are never visible in the store
+	tmp, err := ioutil.TempFile(s.dir, ".tmp-run-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // the run is kept under its claimed name
+	encoder := yaml.NewEncoder(tmp)
+	if err := encoder.Encode(run.Info); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := encoder.Encode(run.Apps); err != nil {
+		tmp.Close()
+		return err
+	}
+	encoder.Close()
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// pick a unique id (runs started within the same second get a suffix), claiming it atomically,
+	// which fails if another run (e.g., from serve) has it
+	baseId := run.Info.Timestamp.Format(runIdFormat)
+	for n := 0; ; n++ {
+		id := baseId
+		if n > 0 {
+			id = fmt.Sprintf("%v-%v", baseId, n)
+		}
+		err := claimRunFile(tmp.Name(), s.runPath(id))
+		if os.IsExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		// the id is not part of the file contents (it is derived from the file name on load)
+		run.Info.Id = id
+		return nil
+	}
+}
+
+// linkFile creates a hard link (a variable, so that tests can simulate filesystems without hard links)
+var linkFile = os.Link
+
+// claimRunFile moves the temp file to path, failing with an "exists" error if path is already taken. It links
+// the temp file under path; on filesystems without hard links (e.g., some network and FUSE mounts) it claims
+// path by creating it exclusively, then renames the temp file onto it
+func claimRunFile(tmpPath string, path string) error {
+	err := linkFile(tmpPath, path)
+	if err == nil || os.IsExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Rename(tmpPath, path)
+}
+
+func (s *Store) runIds() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, runFilePrefix) || !strings.HasSuffix(name, runFileSuffix) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(strings.TrimPrefix(name, runFilePrefix), runFileSuffix))
+	}
+	return ids, nil
+}
+
+func (s *Store) loadRun(id string, withApps bool) (*Run, error) {
+	f, err := os.Open(s.runPath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	run := &Run{}
+	decoder := yaml.NewDecoder(f)
+	if err := decoder.Decode(&run.Info); err != nil {
+		return nil, fmt.Errorf("failed to read run %q: %v", id, err)
+	}
+	run.Info.Id = id
+	if withApps {
+		if err := decoder.Decode(&run.Apps); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read apps of run %q: %v", id, err)
+		}
+	}
+	return run, nil
+}
+
+// List returns the info of all recorded runs, oldest first
+func (s *Store) List() ([]RunInfo, error) {
+	ids, err := s.runIds()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]RunInfo, 0, len(ids))
+	for _, id := range ids {
+		run, err := s.loadRun(id, false)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run.Info)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Timestamp.Before(runs[j].Timestamp)
+	})
+	return runs, nil
+}
+
+// Load returns a recorded run, including its apps
+func (s *Store) Load(id string) (*Run, error) {
+	return s.loadRun(id, true)
+}
+
+// Prune removes the runs that fall outside of the retention policy and returns the number of removed runs
+func (s *Store) Prune(policy RetentionPolicy, now time.Time) (int, error) {
+	runs, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for i, r := range runs {
+		expired := policy.MaxAge > 0 && now.Sub(r.Timestamp) > policy.MaxAge
+		excess := policy.MaxRuns > 0 && len(runs)-i > policy.MaxRuns
+		if !expired && !excess {
+			continue
+		}
+		if err := os.Remove(s.runPath(r.Id)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// AppTrends returns the trend points for each app accepted by the filter (nil filter accepts all apps),
+// in run order (oldest first)
+func (s *Store) AppTrends(filter func(key appmodel.AppKey) bool) (map[appmodel.AppKey][]AppTrendPoint, error) {
+	runs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	trends := make(map[appmodel.AppKey][]AppTrendPoint)
+	for _, info := range runs {
+		run, err := s.Load(info.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range run.Apps {
+			key := app.Key()
+			if filter != nil && !filter(key) {
+				continue
+			}
+			trends[key] = append(trends[key], AppTrendPoint{
+				Run:             run.Info,
+				EfficiencyRate:  app.Analysis.EfficiencyRate,
+				ReliabilityRisk: app.Analysis.ReliabilityRisk,
+				MonthlyCost:     app.MonthlyCost(),
+			})
+		}
+	}
+	return trends, nil
+}
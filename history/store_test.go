@@ -0,0 +1,120 @@
+package history
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func testApp(workload string, rate int) *appmodel.App {
+	risk := appmodel.RiskLevel(appmodel.RISK_LOW)
+	app := &appmodel.App{Metadata: appmodel.AppMetadata{Namespace: "ns", Workload: workload, WorkloadKind: "Deployment"}}
+	app.Analysis.EfficiencyRate = &rate
+	app.Analysis.ReliabilityRisk = &risk
+	return app
+}
+
+func TestStore(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 3; day++ {
+		run := &Run{
+			Info: RunInfo{Timestamp: base.Add(time.Duration(day) * 24 * time.Hour), Prometheus: "http://localhost:9090"},
+			Apps: []*appmodel.App{testApp("web", 40+day*10), testApp("db", 70)},
+		}
+		if err := store.Save(run); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+
+	runs, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[0].AppCount != 2 || !runs[0].Timestamp.Equal(base) {
+		t.Fatalf("List() returned unexpected runs: %+v", runs)
+	}
+
+	trends, err := store.AppTrends(func(key appmodel.AppKey) bool { return key.Workload == "web" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := trends[appmodel.AppKey{Namespace: "ns", Workload: "web", WorkloadKind: "Deployment"}]
+	if len(trends) != 1 || len(web) != 3 {
+		t.Fatalf("AppTrends() returned unexpected trends: %+v", trends)
+	}
+	for i, p := range web {
+		if p.EfficiencyRate == nil || *p.EfficiencyRate != 40+i*10 {
+			t.Errorf("trend point %v: expected efficiency rate %v, got %v", i, 40+i*10, appmodel.Rate2String(p.EfficiencyRate))
+		}
+		if p.ReliabilityRisk.SafeRiskLevel() != appmodel.RISK_LOW {
+			t.Errorf("trend point %v: expected risk %v, got %v", i, appmodel.RiskLevel(appmodel.RISK_LOW), appmodel.Risk2String(p.ReliabilityRisk))
+		}
+	}
+
+	// max age removes the first run, max runs removes the second one
+	removed, err := store.Prune(RetentionPolicy{MaxAge: 36 * time.Hour, MaxRuns: 1}, base.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _ = store.List()
+	if removed != 2 || len(runs) != 1 || !runs[0].Timestamp.Equal(base.Add(48*time.Hour)) {
+		t.Errorf("Prune() removed %v run(s), left %+v", removed, runs)
+	}
+}
+
+func TestStoreConcurrentSaves(t *testing.T) {
+	defer func() { linkFile = os.Link }()
+	for _, hardLinks := range []bool{true, false} {
+		linkFile = os.Link
+		if !hardLinks {
+			linkFile = func(oldname, newname string) error {
+				return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EPERM}
+			}
+		}
+		store, err := Open(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// runs started within the same second (e.g., by serve and a CLI run) get distinct ids
+		at := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		const count = 8
+		ids := make([]string, count)
+		var wg sync.WaitGroup
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				run := &Run{Info: RunInfo{Timestamp: at}, Apps: []*appmodel.App{testApp("web", i)}}
+				if err := store.Save(run); err != nil {
+					t.Errorf("hard links %v: Save() failed: %v", hardLinks, err)
+				}
+				ids[i] = run.Info.Id
+			}(i)
+		}
+		wg.Wait()
+
+		seen := make(map[string]bool)
+		for _, id := range ids {
+			if seen[id] {
+				t.Errorf("hard links %v: duplicate run id %q in %v", hardLinks, id, ids)
+			}
+			seen[id] = true
+		}
+		if runs, err := store.List(); err != nil || len(runs) != count {
+			t.Errorf("hard links %v: expected %v runs to be kept, got %v (%v)", hardLinks, count, len(runs), err)
+		}
+		if entries, _ := os.ReadDir(store.dir); len(entries) != count {
+			t.Errorf("hard links %v: expected only the %v run files to be left, got %v file(s)", hardLinks, count, len(entries))
+		}
+	}
+}
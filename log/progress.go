@@ -15,10 +15,10 @@ import (
 
 // ProgressInfo holds the values used to show progress
 type ProgressInfo struct {
-	NamespacesTotal int
-	NamespacesDone  int
-	WorkloadsTotal  int
-	WorkloadsDone   int
+	NamespacesTotal int `json:"namespaces_total" yaml:"namespaces_total"`
+	NamespacesDone  int `json:"namespaces_done" yaml:"namespaces_done"`
+	WorkloadsTotal  int `json:"workloads_total" yaml:"workloads_total"`
+	WorkloadsDone   int `json:"workloads_done" yaml:"workloads_done"`
 }
 
 // ProgressUpdateFunc is the signature of the progress update callback function.
@@ -28,12 +28,14 @@ type ProgressUpdateFunc func(info ProgressInfo, relative bool)
 // RunnerFunc is the signature for the function to run with GoWithProgress
 type RunnerFunc func(infoCallback ProgressUpdateFunc) error
 
-type progressState struct {
+// ProgressTracker accumulates progress updates; it is safe for concurrent use
+type ProgressTracker struct {
 	lock sync.Mutex
 	info ProgressInfo
 }
 
-func (s *progressState) updateInfo(info ProgressInfo, relative bool) {
+// Update applies a progress update (see ProgressUpdateFunc)
+func (s *ProgressTracker) Update(info ProgressInfo, relative bool) {
 	s.lock.Lock()
 	if relative {
 		s.info.NamespacesTotal += info.NamespacesTotal
@@ -46,11 +48,16 @@ func (s *progressState) updateInfo(info ProgressInfo, relative bool) {
 	s.lock.Unlock()
 }
 
-func (s *progressState) renderProgress(startTime time.Time, final bool) {
-	// safely grab a copy of the info
+// Info returns a snapshot of the accumulated progress
+func (s *ProgressTracker) Info() ProgressInfo {
 	s.lock.Lock()
-	info := s.info
-	s.lock.Unlock()
+	defer s.lock.Unlock()
+	return s.info
+}
+
+func (s *ProgressTracker) renderProgress(startTime time.Time, final bool) {
+	// safely grab a copy of the info
+	info := s.Info()
 
 	// display info as progress
 	now := time.Now()
@@ -65,12 +72,12 @@ func (s *progressState) renderProgress(startTime time.Time, final bool) {
 // GoWithProgress executes the given runner function as a goroutine while showing progress
 func GoWithProgress(runner RunnerFunc) error {
 	done := make(chan error)
-	state := progressState{}
+	state := ProgressTracker{}
 	state.renderProgress(time.Now(), false)
 
 	// run the runner function and notify when done
 	go func() {
-		err := runner(func(info ProgressInfo, relative bool) { state.updateInfo(info, relative) })
+		err := runner(func(info ProgressInfo, relative bool) { state.Update(info, relative) })
 		done <- err
 	}()
 
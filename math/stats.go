@@ -7,6 +7,7 @@ package math
 
 import (
 	m "math"
+	"sort"
 )
 
 func Min(samples ...float64) float64 {
@@ -22,6 +23,19 @@ func Min(samples ...float64) float64 {
 	return min // will return NaN for empty slice or slice that has no valid values
 }
 
+func Max(samples ...float64) float64 {
+	max := m.NaN()
+	for _, val := range samples {
+		if m.IsNaN(val) || m.IsInf(val, 0) {
+			continue
+		}
+		if m.IsNaN(max) || val > max {
+			max = val
+		}
+	}
+	return max // will return NaN for empty slice or slice that has no valid values
+}
+
 func Sum(samples ...float64) float64 {
 	total := 0.0
 	for _, val := range samples {
@@ -49,3 +63,120 @@ func Avg(samples ...float64) float64 {
 	}
 	return total / float64(len(samples))
 }
+
+// validSamples returns the samples without NaN and infinite values
+func validSamples(samples []float64) []float64 {
+	res := make([]float64, 0, len(samples))
+	for _, val := range samples {
+		if valid(val) {
+			res = append(res, val)
+		}
+	}
+	return res
+}
+
+func Median(samples ...float64) float64 {
+	values := validSamples(samples)
+	n := len(values)
+	if n == 0 {
+		return m.NaN()
+	}
+	sort.Float64s(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// Percentile returns the p-th percentile (0-100) of the samples, by the nearest-rank method; NaN if there are no valid values
+func Percentile(p float64, samples ...float64) float64 {
+	values := validSamples(samples)
+	n := len(values)
+	if n == 0 {
+		return m.NaN()
+	}
+	sort.Float64s(values)
+	rank := int(m.Ceil(p / 100 * float64(n)))
+	return values[int(m.Max(0, m.Min(float64(n-1), float64(rank-1))))]
+}
+
+// StDev returns the (sample) standard deviation; NaN if there are less than 2 valid values
+func StDev(samples ...float64) float64 {
+	values := validSamples(samples)
+	n := len(values)
+	if n < 2 {
+		return m.NaN()
+	}
+	mean := Sum(values...) / float64(n)
+	acc := 0.0
+	for _, val := range values {
+		acc += (val - mean) * (val - mean)
+	}
+	return m.Sqrt(acc / float64(n-1))
+}
+
+// CoefficientOfVariation returns the standard deviation relative to the mean; NaN if the mean is 0
+func CoefficientOfVariation(samples ...float64) float64 {
+	values := validSamples(samples)
+	if len(values) == 0 {
+		return m.NaN()
+	}
+	mean := Sum(values...) / float64(len(values))
+	if mean == 0 {
+		return m.NaN()
+	}
+	return StDev(values...) / m.Abs(mean)
+}
+
+// Autocorrelation returns the correlation of the samples with themselves shifted by lag samples, from -1 to 1
+// (1 for a pattern repeating every lag samples). Invalid values are treated as the mean. Returns NaN if the
+// samples are constant or do not cover at least two lags.
+func Autocorrelation(samples []float64, lag int) float64 {
+	n := len(samples)
+	if lag <= 0 || n < 2*lag {
+		return m.NaN()
+	}
+	values := validSamples(samples)
+	if len(values) == 0 {
+		return m.NaN()
+	}
+	mean := Sum(values...) / float64(len(values))
+	dev := func(i int) float64 {
+		if !valid(samples[i]) {
+			return 0
+		}
+		return samples[i] - mean
+	}
+
+	variance := 0.0
+	for i := 0; i < n; i++ {
+		variance += dev(i) * dev(i)
+	}
+	if variance == 0 {
+		return m.NaN()
+	}
+	covariance := 0.0
+	for i := 0; i < n-lag; i++ {
+		covariance += dev(i) * dev(i+lag)
+	}
+	// scale to the number of products, so that a perfectly repeating pattern scores 1
+	return Max(-1, Min(1, covariance/float64(n-lag)/(variance/float64(n))))
+}
+
+// Gini returns the Gini coefficient of the samples, from 0 (all equal) to nearly 1 (all in one sample),
+// measuring how unevenly a total is spread across them; NaN if there are no valid positive values
+func Gini(samples ...float64) float64 {
+	values := validSamples(samples)
+	n := len(values)
+	total := Sum(values...)
+	if n == 0 || total <= 0 {
+		return m.NaN()
+	}
+	// with the values sorted, sum(|xi-xj|) over all pairs is 2*sum((2i-n+1)*xi)
+	sort.Float64s(values)
+	acc := 0.0
+	for i, val := range values {
+		acc += float64(2*i-n+1) * val
+	}
+	return acc / (float64(n) * total)
+}
@@ -0,0 +1,101 @@
+package math
+
+import (
+	m "math"
+	"testing"
+)
+
+func TestMedianStDev(t *testing.T) {
+	tests := []struct {
+		samples       []float64
+		median, stdev float64
+		cv            float64
+	}{
+		{[]float64{5}, 5, m.NaN(), m.NaN()},
+		{[]float64{1, 3, 2}, 2, 1, 0.5},
+		{[]float64{4, 1, 3, 2}, 2.5, 1.2910, 0.5164},
+		{[]float64{2, m.NaN(), 4, m.Inf(1)}, 3, 1.4142, 0.4714},
+		{[]float64{-1, 1}, 0, 1.4142, m.NaN()},
+	}
+	same := func(a, b float64) bool {
+		return m.IsNaN(a) && m.IsNaN(b) || m.Abs(a-b) < 1e-4
+	}
+	for _, tt := range tests {
+		median, stdev, cv := Median(tt.samples...), StDev(tt.samples...), CoefficientOfVariation(tt.samples...)
+		if !same(median, tt.median) || !same(stdev, tt.stdev) || !same(cv, tt.cv) {
+			t.Errorf("samples %v: expected median %v, stdev %v, cv %v; got %v, %v, %v", tt.samples, tt.median, tt.stdev, tt.cv, median, stdev, cv)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	samples := []float64{15, 20, 35, 40, 50, m.NaN()}
+	tests := []struct {
+		p, value float64
+	}{
+		{0, 15}, {5, 15}, {30, 20}, {40, 20}, {50, 35}, {90, 50}, {100, 50},
+	}
+	for _, tt := range tests {
+		if v := Percentile(tt.p, samples...); v != tt.value {
+			t.Errorf("p%v: expected %v, got %v", tt.p, tt.value, v)
+		}
+	}
+	if v := Percentile(50); !m.IsNaN(v) {
+		t.Errorf("expected NaN without samples, got %v", v)
+	}
+}
+
+func TestAutocorrelation(t *testing.T) {
+	daily := make([]float64, 24*7) // hourly samples over a week, peaking every day
+	for h := range daily {
+		daily[h] = 10 + 5*m.Sin(2*m.Pi*float64(h)/24)
+	}
+
+	tests := []struct {
+		name     string
+		samples  []float64
+		lag      int
+		min, max float64
+	}{
+		{"daily pattern, daily lag", daily, 24, 0.95, 1},
+		{"daily pattern, half day lag", daily, 12, -1, -0.95},
+		{"alternating", []float64{1, 2, 1, 2, 1, 2, 1, 2}, 2, 0.95, 1},
+		{"trend", []float64{1, 2, 3, 4, 5, 6, 7, 8}, 4, -1, 0},
+	}
+	for _, tt := range tests {
+		if ac := Autocorrelation(tt.samples, tt.lag); !(ac >= tt.min && ac <= tt.max) {
+			t.Errorf("%v: expected autocorrelation in [%v, %v], got %v", tt.name, tt.min, tt.max, ac)
+		}
+	}
+
+	// not enough data
+	for _, lag := range []int{0, 100} {
+		if ac := Autocorrelation(daily, lag); !m.IsNaN(ac) {
+			t.Errorf("lag %v: expected NaN, got %v", lag, ac)
+		}
+	}
+	if ac := Autocorrelation([]float64{3, 3, 3, 3}, 1); !m.IsNaN(ac) {
+		t.Errorf("constant samples: expected NaN, got %v", ac)
+	}
+}
+
+func TestGini(t *testing.T) {
+	tests := []struct {
+		samples []float64
+		gini    float64
+	}{
+		{[]float64{}, m.NaN()},
+		{[]float64{0, 0}, m.NaN()},
+		{[]float64{5}, 0},
+		{[]float64{3, 3, 3, 3}, 0},
+		{[]float64{1, 0}, 0.5},
+		{[]float64{0, 0, 0, 4}, 0.75},
+		{[]float64{1, 2, 3, 4}, 0.25},
+		{[]float64{4, m.NaN(), 1, 3, 2}, 0.25},
+	}
+	for _, tt := range tests {
+		if g := Gini(tt.samples...); !(m.IsNaN(g) && m.IsNaN(tt.gini) || m.Abs(g-tt.gini) < 1e-9) {
+			t.Errorf("samples %v: expected Gini %v, got %v", tt.samples, tt.gini, g)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package math
+
+import (
+	m "math"
+)
+
+// LinearFit is a line fitted to samples by least squares, y = Slope*x + Intercept
+type LinearFit struct {
+	Slope     float64
+	Intercept float64
+	R2        float64 // coefficient of determination (goodness of fit): 1 for a perfect fit, 0 for no linear relationship
+	Count     int     // number of samples fitted
+}
+
+func valid(val float64) bool {
+	return !m.IsNaN(val) && !m.IsInf(val, 0)
+}
+
+// LinearRegression fits a line to the (x[i], y[i]) samples, skipping samples with NaN or infinite values.
+// Slope, Intercept and R2 are NaN if there are less than 2 samples or all x values are the same.
+func LinearRegression(x, y []float64) LinearFit {
+	fit := LinearFit{Slope: m.NaN(), Intercept: m.NaN(), R2: m.NaN()}
+
+	// compute the means first and sum the deviations from them, rather than the raw squares,
+	// to keep precision with large x values (e.g., unix timestamps)
+	xs := make([]float64, 0, len(x))
+	ys := make([]float64, 0, len(y))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if valid(x[i]) && valid(y[i]) {
+			xs = append(xs, x[i])
+			ys = append(ys, y[i])
+		}
+	}
+	fit.Count = len(xs)
+	if fit.Count < 2 {
+		return fit
+	}
+	xMean := Sum(xs...) / float64(fit.Count)
+	yMean := Sum(ys...) / float64(fit.Count)
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-xMean, ys[i]-yMean
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return fit
+	}
+
+	fit.Slope = sxy / sxx
+	fit.Intercept = yMean - fit.Slope*xMean
+	if syy == 0 {
+		fit.R2 = 0 // constant y: fitted by a flat line, but y does not depend on x
+	} else {
+		fit.R2 = sxy * sxy / (sxx * syy)
+	}
+	return fit
+}
+
+// At returns the fitted value at x
+func (f LinearFit) At(x float64) float64 {
+	return f.Slope*x + f.Intercept
+}
+
+// Reaches returns the x at which the fitted line reaches y; NaN if the line is flat or not fitted
+func (f LinearFit) Reaches(y float64) float64 {
+	if f.Slope == 0 || m.IsNaN(f.Slope) {
+		return m.NaN()
+	}
+	return (y - f.Intercept) / f.Slope
+}
+
+// Drops returns the indices of the samples that are lower than the preceding valid sample by more than
+// the given fraction of it (e.g., 0.3 for drops of more than 30%), such as memory use falling on restart
+func Drops(samples []float64, fraction float64) []int {
+	drops := []int{}
+	prev := m.NaN()
+	for i, val := range samples {
+		if !valid(val) {
+			continue
+		}
+		if !m.IsNaN(prev) && prev > 0 && val < prev*(1-fraction) {
+			drops = append(drops, i)
+		}
+		prev = val
+	}
+	return drops
+}
@@ -0,0 +1,71 @@
+package math
+
+import (
+	m "math"
+	"reflect"
+	"testing"
+)
+
+func TestLinearRegression(t *testing.T) {
+	nan := m.NaN()
+	tests := []struct {
+		name          string
+		x, y          []float64
+		slope, offset float64
+		r2            float64
+	}{
+		{"perfect line", []float64{0, 1, 2, 3}, []float64{1, 3, 5, 7}, 2, 1, 1},
+		{"flat", []float64{0, 1, 2}, []float64{5, 5, 5}, 0, 5, 0}, // no relationship
+		{"decreasing", []float64{0, 1, 2}, []float64{4, 2, 0}, -2, 4, 1},
+		{"noisy", []float64{0, 1, 2, 3}, []float64{0, 2, 1, 3}, 0.8, 0.3, 0.64},
+		{"skips invalid", []float64{0, 1, nan, 2}, []float64{1, 2, 7, m.Inf(1)}, 1, 1, 1},
+		{"large x", []float64{1.6e9, 1.6e9 + 60, 1.6e9 + 120}, []float64{100, 160, 220}, 1, 100 - 1.6e9, 1},
+	}
+	for _, tt := range tests {
+		fit := LinearRegression(tt.x, tt.y)
+		if m.Abs(fit.Slope-tt.slope) > 1e-9 || m.Abs(fit.Intercept-tt.offset) > 1e-3 || m.Abs(fit.R2-tt.r2) > 1e-9 {
+			t.Errorf("%v: expected slope %v, intercept %v, r2 %v; got %+v", tt.name, tt.slope, tt.offset, tt.r2, fit)
+		}
+	}
+
+	// not enough data for a fit
+	for _, x := range [][]float64{{}, {1}, {2, 2, 2}} {
+		y := make([]float64, len(x))
+		if fit := LinearRegression(x, y); !m.IsNaN(fit.Slope) || !m.IsNaN(fit.R2) {
+			t.Errorf("x %v: expected no fit, got %+v", x, fit)
+		}
+	}
+}
+
+func TestLinearFitReaches(t *testing.T) {
+	fit := LinearRegression([]float64{0, 10}, []float64{100, 200})
+	if v := fit.At(20); v != 300 {
+		t.Errorf("expected 300 at 20, got %v", v)
+	}
+	if x := fit.Reaches(500); x != 40 {
+		t.Errorf("expected to reach 500 at 40, got %v", x)
+	}
+	flat := LinearRegression([]float64{0, 10}, []float64{100, 100})
+	if x := flat.Reaches(500); !m.IsNaN(x) {
+		t.Errorf("expected a flat line to never reach 500, got %v", x)
+	}
+}
+
+func TestDrops(t *testing.T) {
+	tests := []struct {
+		samples []float64
+		drops   []int
+	}{
+		{[]float64{}, []int{}},
+		{[]float64{1, 2, 3, 4}, []int{}},
+		{[]float64{1, 2, 3, 1, 2, 3, 1}, []int{3, 6}},
+		{[]float64{10, 9, 8}, []int{}},           // gradual decrease
+		{[]float64{10, m.NaN(), 5, 6}, []int{2}}, // compares with the last valid sample
+		{[]float64{0, 0, 5, 1}, []int{3}},        // no drop from 0
+	}
+	for _, tt := range tests {
+		if drops := Drops(tt.samples, 0.3); !reflect.DeepEqual(drops, tt.drops) {
+			t.Errorf("samples %v: expected drops at %v, got %v", tt.samples, tt.drops, drops)
+		}
+	}
+}
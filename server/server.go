@@ -0,0 +1,398 @@
+/*
+Copyright © 2021 Opsani <support@opsani.com>
+This file is part of https://github.com/opsani/opsani-ignite
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+// REST API (all responses are JSON unless yaml is requested with ?format=yaml or an Accept header):
+//   POST /api/v1/runs                                    start an analysis run (body: Request); returns the run status
+//   GET  /api/v1/runs                                    list the cached runs
+//   GET  /api/v1/runs/{id}                               get the run status, including progress
+//   GET  /api/v1/runs/{id}/apps                          get the analyzed apps of a completed run
+//   GET  /api/v1/runs/{id}/apps/{namespace}/{workload}   get a single analyzed app of a completed run
+//   GET  /healthz                                        liveness check
+
+const apiPrefix = "/api/v1/runs"
+
+// Request specifies the scope and time range of an analysis run. Empty values select the server defaults.
+type Request struct {
+	Cluster   string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Workload  string `json:"workload,omitempty" yaml:"workload,omitempty"`
+	Start     string `json:"start,omitempty" yaml:"start,omitempty"` // RFC3339 or relative, as the --start option
+	End       string `json:"end,omitempty" yaml:"end,omitempty"`     // RFC3339 or relative, as the --end option
+	Step      string `json:"step,omitempty" yaml:"step,omitempty"`   // relative, as the --step option
+}
+
+// Analyzer performs the analysis runs for the server
+type Analyzer interface {
+	// Validate checks the request before a run is started; errors are reported to the client as bad requests
+	Validate(req Request) error
+	// Analyze collects and analyzes the apps selected by the request, reporting progress via the callback
+	Analyze(ctx context.Context, req Request, progressCallback log.ProgressUpdateFunc) ([]*appmodel.App, error)
+}
+
+type RunState string
+
+const (
+	RUN_RUNNING RunState = "running"
+	RUN_DONE    RunState = "done"
+	RUN_FAILED  RunState = "failed"
+)
+
+// RunStatus is the externally visible state of a run
+type RunStatus struct {
+	Id       string           `json:"id" yaml:"id"`
+	Request  Request          `json:"request" yaml:"request"`
+	State    RunState         `json:"state" yaml:"state"`
+	Error    string           `json:"error,omitempty" yaml:"error,omitempty"`
+	Created  time.Time        `json:"created" yaml:"created"`
+	Finished *time.Time       `json:"finished,omitempty" yaml:"finished,omitempty"`
+	Progress log.ProgressInfo `json:"progress" yaml:"progress"`
+	AppCount int              `json:"app_count" yaml:"app_count"`
+}
+
+type run struct {
+	status   RunStatus // protected by the server lock
+	progress log.ProgressTracker
+	apps     []*appmodel.App // set once done
+}
+
+// Options controls the server's caching of runs
+type Options struct {
+	CacheSize int           // maximum number of completed runs to keep
+	CacheTTL  time.Duration // completed runs expire after this time; identical requests within it reuse the run
+}
+
+// Server manages analysis runs and serves the REST API
+type Server struct {
+	analyzer Analyzer
+	options  Options
+
+	lock   sync.Mutex
+	runs   map[string]*run
+	order  []string // run ids, oldest first
+	nextId int
+
+	ctx    context.Context // cancelled on shutdown, aborting active runs
+	cancel context.CancelFunc
+	active sync.WaitGroup
+}
+
+// New creates a server that uses the analyzer to perform runs
+func New(analyzer Analyzer, options Options) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		analyzer: analyzer,
+		options:  options,
+		runs:     make(map[string]*run),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Handler returns the http handler for the REST API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc(apiPrefix, s.handleRuns)
+	mux.HandleFunc(apiPrefix+"/", s.handleRun)
+	return mux
+}
+
+// Close aborts the active runs and waits for them to finish (or for the context to expire)
+func (s *Server) Close(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ListenAndServe serves the REST API on the given address until the context is cancelled,
+// then shuts down gracefully, allowing up to shutdownTimeout for in-flight requests and runs to finish
+func (s *Server) ListenAndServe(ctx context.Context, addr string, shutdownTimeout time.Duration) error {
+	httpServer := &http.Server{Addr: addr, Handler: s.Handler()}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		s.Close(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Infof("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := httpServer.Shutdown(shutdownCtx)
+	if closeErr := s.Close(shutdownCtx); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// --- Run management --------------------------------------------------------
+
+func (s *Server) statusLocked(r *run) RunStatus {
+	status := r.status
+	status.Progress = r.progress.Info()
+	return status
+}
+
+// expireLocked removes completed runs that are past their TTL or over the cache size
+func (s *Server) expireLocked(now time.Time) {
+	completed := 0
+	for _, id := range s.order {
+		if s.runs[id].status.Finished != nil {
+			completed++
+		}
+	}
+	kept := s.order[:0]
+	for _, id := range s.order {
+		r := s.runs[id]
+		if r.status.Finished != nil {
+			expired := s.options.CacheTTL > 0 && now.Sub(*r.status.Finished) > s.options.CacheTTL
+			excess := s.options.CacheSize > 0 && completed > s.options.CacheSize
+			if expired || excess {
+				delete(s.runs, id)
+				completed--
+				continue
+			}
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+// Start starts a new run for the request, or returns an existing run with an identical request that is
+// still active or cached. The returned flag indicates whether a new run was started.
+func (s *Server) Start(req Request) (RunStatus, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expireLocked(time.Now())
+	for i := len(s.order) - 1; i >= 0; i-- {
+		r := s.runs[s.order[i]]
+		if r.status.Request == req && r.status.State != RUN_FAILED {
+			return s.statusLocked(r), false, nil
+		}
+	}
+
+	if err := s.analyzer.Validate(req); err != nil {
+		return RunStatus{}, false, err
+	}
+
+	s.nextId++
+	r := &run{status: RunStatus{
+		Id:      fmt.Sprintf("%v-%v", time.Now().UTC().Format("20060102T150405Z"), s.nextId),
+		Request: req,
+		State:   RUN_RUNNING,
+		Created: time.Now(),
+	}}
+	s.runs[r.status.Id] = r
+	s.order = append(s.order, r.status.Id)
+
+	s.active.Add(1)
+	go func() {
+		defer s.active.Done()
+		apps, err := s.analyzer.Analyze(s.ctx, req, r.progress.Update)
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		finished := time.Now()
+		r.status.Finished = &finished
+		if err != nil {
+			r.status.State = RUN_FAILED
+			r.status.Error = err.Error()
+			log.Errorf("Run %v failed: %v", r.status.Id, err)
+			return
+		}
+		r.status.State = RUN_DONE
+		r.status.AppCount = len(apps)
+		r.apps = apps
+	}()
+
+	return s.statusLocked(r), true, nil
+}
+
+// Status returns the status of a run
+func (s *Server) Status(id string) (RunStatus, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r, ok := s.runs[id]
+	if !ok {
+		return RunStatus{}, false
+	}
+	return s.statusLocked(r), true
+}
+
+// List returns the status of all cached runs, oldest first
+func (s *Server) List() []RunStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.expireLocked(time.Now())
+	list := make([]RunStatus, 0, len(s.order))
+	for _, id := range s.order {
+		list = append(list, s.statusLocked(s.runs[id]))
+	}
+	return list
+}
+
+// Apps returns the analyzed apps of a run; the run status is returned as well, in case the run is not done
+func (s *Server) Apps(id string) ([]*appmodel.App, RunStatus, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r, ok := s.runs[id]
+	if !ok {
+		return nil, RunStatus{}, false
+	}
+	return r.apps, s.statusLocked(r), true
+}
+
+// --- HTTP handlers ---------------------------------------------------------
+
+type errorResponse struct {
+	Error string `json:"error" yaml:"error"`
+}
+
+func wantsYaml(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "yaml"
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "yaml") && !strings.Contains(accept, "json")
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	var buf []byte
+	var err error
+	if wantsYaml(r) {
+		w.Header().Set("Content-Type", "application/yaml")
+		buf, err = yaml.Marshal(v)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		buf, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		log.Errorf("Failed to encode response for %v: %v", r.URL, err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(buf)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	writeResponse(w, r, code, errorResponse{err.Error()})
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeResponse(w, r, http.StatusOK, s.List())
+	case http.MethodPost:
+		var req Request
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+				return
+			}
+		}
+		status, started, err := s.Start(req)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		code := http.StatusOK
+		if started {
+			code = http.StatusAccepted
+		}
+		w.Header().Set("Location", apiPrefix+"/"+status.Id)
+		writeResponse(w, r, code, status)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+	}
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+
+	// path: {id}[/apps[/{namespace}/{workload}]]
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix+"/"), "/"), "/")
+	id := parts[0]
+	if len(parts) == 1 {
+		status, ok := s.Status(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, fmt.Errorf("run %q not found", id))
+			return
+		}
+		writeResponse(w, r, http.StatusOK, status)
+		return
+	}
+	if parts[1] != "apps" || (len(parts) != 2 && len(parts) != 4) {
+		writeError(w, r, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	apps, status, ok := s.Apps(id)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, fmt.Errorf("run %q not found", id))
+		return
+	}
+	switch status.State {
+	case RUN_RUNNING:
+		writeError(w, r, http.StatusConflict, fmt.Errorf("run %q is still running", id))
+		return
+	case RUN_FAILED:
+		writeError(w, r, http.StatusConflict, fmt.Errorf("run %q failed: %v", id, status.Error))
+		return
+	}
+
+	if len(parts) == 2 {
+		writeResponse(w, r, http.StatusOK, apps)
+		return
+	}
+	namespace, workload := parts[2], parts[3]
+	for _, app := range apps {
+		if app.Metadata.Namespace == namespace && app.Metadata.Workload == workload {
+			writeResponse(w, r, http.StatusOK, app)
+			return
+		}
+	}
+	writeError(w, r, http.StatusNotFound, fmt.Errorf("app %v/%v not found in run %q", namespace, workload, id))
+}
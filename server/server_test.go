@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appmodel "opsani-ignite/app/model"
+	"opsani-ignite/log"
+)
+
+// testAnalyzer returns two apps, once released (if a release channel is set), or fails with err
+type testAnalyzer struct {
+	release chan struct{}
+	err     error
+	calls   int32
+}
+
+func (a *testAnalyzer) Validate(req Request) error {
+	if req.Step == "bad" {
+		return errors.New("bad step")
+	}
+	return nil
+}
+
+func (a *testAnalyzer) Analyze(ctx context.Context, req Request, progressCallback log.ProgressUpdateFunc) ([]*appmodel.App, error) {
+	atomic.AddInt32(&a.calls, 1)
+	if a.release != nil {
+		select {
+		case <-a.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+	return []*appmodel.App{
+		{Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "web"}},
+		{Metadata: appmodel.AppMetadata{Namespace: "shop", Workload: "cart"}},
+	}, nil
+}
+
+// waitDone waits for the run to complete and returns its status
+func waitDone(t *testing.T, s *Server, id string) RunStatus {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if status, ok := s.Status(id); !ok || status.State != RUN_RUNNING {
+			return status
+		}
+	}
+	t.Fatalf("run %q did not complete", id)
+	return RunStatus{}
+}
+
+func request(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandlers(t *testing.T) {
+	analyzer := &testAnalyzer{release: make(chan struct{})}
+	s := New(analyzer, Options{})
+	defer s.Close(context.Background())
+	h := s.Handler()
+
+	w := request(t, h, http.MethodPost, apiPrefix, `{"namespace": "shop"}`)
+	var status RunStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); w.Code != http.StatusAccepted || err != nil {
+		t.Fatalf("expected the run to be accepted, got %v: %s", w.Code, w.Body)
+	}
+	if status.State != RUN_RUNNING || status.Request.Namespace != "shop" || w.Header().Get("Location") != apiPrefix+"/"+status.Id {
+		t.Errorf("unexpected status %+v (location %q)", status, w.Header().Get("Location"))
+	}
+	if w := request(t, h, http.MethodGet, apiPrefix+"/"+status.Id+"/apps", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected the apps of a running run to conflict, got %v", w.Code)
+	}
+
+	close(analyzer.release)
+	waitDone(t, s, status.Id)
+
+	tests := []struct {
+		method, path, body string
+		code               int
+		contains           string
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK, "ok"},
+		{http.MethodGet, apiPrefix, "", http.StatusOK, `"state": "done"`},
+		{http.MethodGet, apiPrefix + "/" + status.Id, "", http.StatusOK, `"app_count": 2`},
+		{http.MethodGet, apiPrefix + "/" + status.Id + "/apps", "", http.StatusOK, `"cart"`},
+		{http.MethodGet, apiPrefix + "/" + status.Id + "/apps?format=yaml", "", http.StatusOK, "workload: cart"},
+		{http.MethodGet, apiPrefix + "/" + status.Id + "/apps/shop/web", "", http.StatusOK, `"web"`},
+		{http.MethodGet, apiPrefix + "/" + status.Id + "/apps/shop/none", "", http.StatusNotFound, "not found"},
+		{http.MethodGet, apiPrefix + "/" + status.Id + "/pods", "", http.StatusNotFound, "not found"},
+		{http.MethodGet, apiPrefix + "/no-such-run", "", http.StatusNotFound, "not found"},
+		{http.MethodGet, apiPrefix + "/no-such-run/apps", "", http.StatusNotFound, "not found"},
+		{http.MethodPost, apiPrefix, `{"step": "bad"}`, http.StatusBadRequest, "bad step"},
+		{http.MethodPost, apiPrefix, `{"step": `, http.StatusBadRequest, "invalid request body"},
+		{http.MethodDelete, apiPrefix, "", http.StatusMethodNotAllowed, "not allowed"},
+		{http.MethodPost, apiPrefix + "/" + status.Id, "", http.StatusMethodNotAllowed, "not allowed"},
+	}
+	for _, tt := range tests {
+		w := request(t, h, tt.method, tt.path, tt.body)
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.contains) {
+			t.Errorf("%v %v: expected %v with %q, got %v: %s", tt.method, tt.path, tt.code, tt.contains, w.Code, w.Body)
+		}
+	}
+}
+
+func TestFailedRun(t *testing.T) {
+	s := New(&testAnalyzer{err: errors.New("prometheus unreachable")}, Options{})
+	defer s.Close(context.Background())
+
+	status, started, err := s.Start(Request{})
+	if err != nil || !started {
+		t.Fatalf("expected a run to be started, got %v, %v", started, err)
+	}
+	if status = waitDone(t, s, status.Id); status.State != RUN_FAILED || status.Error != "prometheus unreachable" {
+		t.Errorf("expected the run to fail, got %+v", status)
+	}
+	w := request(t, s.Handler(), http.MethodGet, apiPrefix+"/"+status.Id+"/apps", "")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "prometheus unreachable") {
+		t.Errorf("expected the apps of a failed run to conflict with its error, got %v: %s", w.Code, w.Body)
+	}
+
+	// a failed run is not reused
+	if _, started, _ := s.Start(Request{}); !started {
+		t.Error("expected a new run to be started after a failed one")
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	analyzer := &testAnalyzer{}
+	s := New(analyzer, Options{CacheSize: 1, CacheTTL: time.Hour})
+	defer s.Close(context.Background())
+
+	first, _, _ := s.Start(Request{Namespace: "shop"})
+	waitDone(t, s, first.Id)
+	if again, started, _ := s.Start(Request{Namespace: "shop"}); started || again.Id != first.Id {
+		t.Errorf("expected the cached run %q to be reused, got %q (started %v)", first.Id, again.Id, started)
+	}
+	if calls := atomic.LoadInt32(&analyzer.calls); calls != 1 {
+		t.Errorf("expected a single analysis, got %v", calls)
+	}
+
+	// the cache keeps a single completed run: the oldest is dropped
+	second, started, _ := s.Start(Request{Namespace: "billing"})
+	if !started || second.Id == first.Id {
+		t.Fatalf("expected a new run for a different request, got %q", second.Id)
+	}
+	waitDone(t, s, second.Id)
+	if list := s.List(); len(list) != 1 || list[0].Id != second.Id {
+		t.Errorf("expected only %q to be cached, got %+v", second.Id, list)
+	}
+	if _, ok := s.Status(first.Id); ok {
+		t.Errorf("expected %q to be dropped from the cache", first.Id)
+	}
+
+	// completed runs expire after the TTL
+	s.lock.Lock()
+	s.expireLocked(time.Now().Add(2 * time.Hour))
+	s.lock.Unlock()
+	if list := s.List(); len(list) != 0 {
+		t.Errorf("expected the cached run to expire, got %+v", list)
+	}
+}
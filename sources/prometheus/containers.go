@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -21,10 +22,20 @@ import (
 	opsmath "opsani-ignite/math"
 )
 
-// get request or limit values for all resources of all containers of the specificed application
-func getContainersResources(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, resourceFieldName string) (v1.Warnings, error) {
+// constant table - container resource structures (AppContainer fields) by resource name, as exported by kube-state-metrics
+func getResourceFieldNames() map[string]string {
+	return map[string]string{
+		"cpu":               "Cpu",
+		"memory":            "Memory",
+		"ephemeral_storage": "EphemeralStorage",
+		"ephemeral-storage": "EphemeralStorage",
+	}
+}
+
+// getContainersResources collects the resource requests or limits (resourceFieldName) of the given containers of the app
+func getContainersResources(ctx context.Context, promApi v1.API, app *appmodel.App, containers []appmodel.AppContainer, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, resourceFieldName string, label string) (v1.Warnings, error) {
 	// set up query context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	// prepare query string by injecting selector data into the provided query template
@@ -51,6 +62,7 @@ func getContainersResources(ctx context.Context, promApi v1.API, app *appmodel.A
 	if !ok {
 		return warnings, fmt.Errorf("Query %q returned %T instead of Vector; assuming no data", query, result)
 	}
+	recordInstantSource(app, label, query, timeRange.End, len(list), warnings)
 	if len(list) == 0 {
 		//TODO: add warning that we didn't find this info (which is OK - it may not be set)
 		return warnings, nil
@@ -66,7 +78,8 @@ func getContainersResources(ctx context.Context, promApi v1.API, app *appmodel.A
 			return warnings, fmt.Errorf("Query %q returned labels %v, expected %v", query, labels, []string{"container", "resource"})
 		}
 		name, resource := string(nameLabel), string(resourceLabel)
-		if resource != "cpu" && resource != "memory" {
+		fieldName, ok := getResourceFieldNames()[resource]
+		if !ok {
 			log.Warnf("Query %q returned unrecognized resource type %q, ignoring", query, resource)
 			continue
 		}
@@ -74,20 +87,20 @@ func getContainersResources(ctx context.Context, promApi v1.API, app *appmodel.A
 
 		// update container info
 		found := false
-		for index, info := range app.Containers {
+		for index, info := range containers {
 			if name != info.Name {
 				continue
 			}
 			found = true
 			log.Tracef("App %v, %v.%v.%v = %v", app.Metadata, name, resource, resourceFieldName, value)
 
-			// update container info sub-structure (Cpu or Memory), relying on resource name match and setting request/limit
-			// essentially, info.{Cpu|Memory}.{Limit|Request} = value
+			// update container info sub-structure (Cpu, Memory or EphemeralStorage), setting request/limit
+			// essentially, info.{Cpu|Memory|EphemeralStorage}.{Limit|Request} = value
 			infoValue := reflect.ValueOf(&info).Elem()
-			resourceStruct := infoValue.FieldByName(strings.Title(resource))
+			resourceStruct := infoValue.FieldByName(fieldName)
 			resourceValue := resourceStruct.FieldByName(resourceFieldName)
 			resourceValue.Set(reflect.ValueOf(value))
-			app.Containers[index] = info
+			containers[index] = info
 			break
 		}
 		if !found {
@@ -99,9 +112,10 @@ func getContainersResources(ctx context.Context, promApi v1.API, app *appmodel.A
 	return nil, nil
 }
 
-func getContainersUseValueMap(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors) (map[string]float64, v1.Warnings, error) {
+// getContainersUseValueMap returns the per-container value (and the series it was computed from), by container name
+func getContainersUseValueMap(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, label string) (map[string]float64, map[string]appmodel.TimeSeries, v1.Warnings, error) {
 	// set up query context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	var allWarnings v1.Warnings
@@ -110,14 +124,14 @@ func getContainersUseValueMap(ctx context.Context, promApi v1.API, app *appmodel
 	var buf bytes.Buffer
 	err := queryTemplate.Execute(&buf, querySelectors)
 	if err != nil {
-		return nil, nil, fmt.Errorf("Error preparing query: %v\n", err)
+		return nil, nil, nil, fmt.Errorf("Error preparing query: %v\n", err)
 	}
 	query := buf.String()
 
 	// Collect values
 	result, warnings, err := promApi.QueryRange(ctx, query, timeRange)
 	if err != nil {
-		return nil, warnings, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+		return nil, nil, warnings, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
 	}
 	if len(warnings) > 0 {
 		log.Warnf("Warnings: %v\n", warnings)
@@ -127,14 +141,16 @@ func getContainersUseValueMap(ctx context.Context, promApi v1.API, app *appmodel
 	// Parse results as a list of series
 	series, ok := result.(model.Matrix)
 	if !ok {
-		return nil, warnings, fmt.Errorf("Query %q returned %T instead of Matrix; assuming no data", query, result)
+		return nil, nil, warnings, fmt.Errorf("Query %q returned %T instead of Matrix; assuming no data", query, result)
 	}
 	if len(series) == 0 {
-		return nil, warnings, nil
+		recordSource(app, label, "", query, timeRange, 0, nil, warnings)
+		return nil, nil, warnings, nil
 	}
 
 	// aggregate and distribute values by container name
 	valueMap := make(map[string]float64, len(app.Containers))
+	seriesMap := make(map[string]appmodel.TimeSeries, len(app.Containers))
 	for _, c := range series { // c is *model.SampleStream
 		if len(c.Metric) > 1 {
 			log.Warnf("metrics returned for query %q contain labels %v, expected %v, ignoring extras (app %v)", query, c.Metric, []string{"container"}, app.Metadata)
@@ -155,27 +171,29 @@ func getContainersUseValueMap(ctx context.Context, promApi v1.API, app *appmodel
 		}
 
 		// process statistics over the values
-		value, warnigns, err := valueFromSamplePairs(c.Values, fmt.Sprintf("app %v, container %q, query %q", app.Metadata, name, query))
+		value, statWarnings, err := valueFromSamplePairs(c.Values, fmt.Sprintf("app %v, container %q, query %q", app.Metadata, name, query))
+		recordSource(app, label, string(name), query, timeRange, len(series), c.Values, append(append(v1.Warnings{}, warnings...), statWarnings...))
 		if err != nil {
 			// convert to warning
 			msg := fmt.Sprintf("Failed statistical processing for app %v, container %q, query %q results: %v; skipping series", app.Metadata, name, query, err)
-			warnings = append(warnigns, msg)
-			allWarnings = append(allWarnings, warnigns...)
+			warnings = append(statWarnings, msg)
+			allWarnings = append(allWarnings, statWarnings...)
 			log.Errorf("%v", msg)
 			continue
 		}
-		if len(warnigns) > 0 {
+		if len(statWarnings) > 0 {
 			allWarnings = append(allWarnings, warnings...)
 		}
 		valueMap[string(name)] = value
+		seriesMap[string(name)] = seriesFromSamplePairs(c.Values)
 	}
 
-	return valueMap, warnings, nil
+	return valueMap, seriesMap, warnings, nil
 }
 
-func getContainersUse(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, resource string, field string) (v1.Warnings, error) {
+func getContainersUse(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, resource string, field string, label string) (v1.Warnings, error) {
 	// get container usage metric into valuemap by container name
-	valueMap, warnings, err := getContainersUseValueMap(ctx, promApi, app, timeRange, queryTemplate, querySelectors)
+	valueMap, seriesMap, warnings, err := getContainersUseValueMap(ctx, promApi, app, timeRange, queryTemplate, querySelectors, label)
 	if err != nil {
 		return warnings, err
 	}
@@ -185,7 +203,9 @@ func getContainersUse(ctx context.Context, promApi v1.API, app *appmodel.App, ti
 		contName := app.Containers[i].Name
 		v, ok := valueMap[contName]
 		if !ok {
-			if field != "SecondsThrottled" { // seconds throttled may be undefined if no throttling has occurred
+			// seconds throttled may be undefined if no throttling has occurred; ephemeral storage usage is not
+			// reported by some container runtimes (e.g., containerd)
+			if field != "SecondsThrottled" && resource != "EphemeralStorage" {
 				log.Warnf("Didn't find value of %v.%v for container %q of app %v; assuming 0", resource, field, contName, app.Metadata)
 			}
 		} else {
@@ -200,6 +220,12 @@ func getContainersUse(ctx context.Context, promApi v1.API, app *appmodel.App, ti
 			}
 			resourceValue.Set(reflect.ValueOf(v))
 
+			// keep the usage series, e.g., app.Containers[i].Series.<resource> = series
+			if resource != "" && field == "Usage" {
+				seriesValue := containerStruct.FieldByName("Series").FieldByName(strings.Title(resource))
+				seriesValue.Set(reflect.ValueOf(seriesMap[contName]))
+			}
+
 			delete(valueMap, contName)
 		}
 	}
@@ -212,6 +238,70 @@ func getContainersUse(ctx context.Context, promApi v1.API, app *appmodel.App, ti
 	return warnings, nil
 }
 
+// getSeriesByPod runs a range query returning a series per pod (and container, if the query is per container),
+// returning the series by container name (empty if not per container) and pod name
+func getSeriesByPod(ctx context.Context, promApi v1.API, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors) (map[string]map[string]appmodel.TimeSeries, string, v1.Warnings, error) {
+	// set up query context with timeout
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// prepare query string by injecting selector data into the provided query template
+	var buf bytes.Buffer
+	err := queryTemplate.Execute(&buf, querySelectors)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("Error preparing query: %v\n", err)
+	}
+	query := buf.String()
+
+	// Collect values
+	result, warnings, err := promApi.QueryRange(ctx, query, timeRange)
+	if err != nil {
+		return nil, query, warnings, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+	}
+
+	// Parse results as a list of series
+	series, ok := result.(model.Matrix)
+	if !ok {
+		return nil, query, warnings, fmt.Errorf("Query %q returned %T instead of Matrix; assuming no data", query, result)
+	}
+
+	// distribute series by container and pod name
+	seriesMap := make(map[string]map[string]appmodel.TimeSeries)
+	for _, s := range series { // s is *model.SampleStream
+		name, pod := string(s.Metric["container"]), string(s.Metric["pod"])
+		if pod == "" {
+			continue
+		}
+		if seriesMap[name] == nil {
+			seriesMap[name] = make(map[string]appmodel.TimeSeries)
+		}
+		seriesMap[name][pod] = seriesFromSamplePairs(s.Values)
+	}
+	return seriesMap, query, warnings, nil
+}
+
+// getContainersUseByPod collects each container's usage series per pod, which (unlike the average across
+// pods) show growth and restarts of the individual pods and how the load is spread across them
+func getContainersUseByPod(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, resource string, label string) (v1.Warnings, error) {
+	seriesMap, query, warnings, err := getSeriesByPod(ctx, promApi, timeRange, queryTemplate, querySelectors)
+	if err != nil {
+		return warnings, err
+	}
+
+	// distribute series, e.g., app.Containers[i].Series.<resource>ByPod = series
+	for i := range app.Containers {
+		pods := seriesMap[app.Containers[i].Name]
+		if len(pods) > 0 {
+			containerStruct := reflect.ValueOf(&app.Containers[i]).Elem()
+			seriesValue := containerStruct.FieldByName("Series").FieldByName(strings.Title(resource) + "ByPod")
+			seriesValue.Set(reflect.ValueOf(pods))
+		}
+		recordSource(app, label, app.Containers[i].Name, query, timeRange, len(pods), nil, warnings)
+	}
+
+	return warnings, nil
+}
+
 func handleWarnErr(allWarnings v1.Warnings, newWarnings v1.Warnings, newError error, app *appmodel.App, label string) v1.Warnings {
 	if newError != nil {
 		msg := fmt.Sprintf("Error querying Prometheus for %v on app %v: %v; skipping value", label, app.Metadata, newError)
@@ -224,37 +314,61 @@ func handleWarnErr(allWarnings v1.Warnings, newWarnings v1.Warnings, newError er
 	return allWarnings
 }
 
-func collectContainersInfo(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range) (v1.Warnings, error) {
+// getContainersPorts collects the ports of the containers that Prometheus scrapes, typically through a Service
+// (by the endpoint label set for ServiceMonitor and PodMonitor targets, or "scraped" if there is none)
+func getContainersPorts(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, selectors *QuerySelectors) (v1.Warnings, error) {
 	// set up query context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	var allWarnings v1.Warnings
-
-	// --- Get container info
-
-	// prepare query selectors (TODO: refactor to a helper)
-	// Note: pod naming template is <deployment_name>-<pod_spec_hash>-<pod_unique_id>
-	//       TODO: tighten RE to avoid unlikelyconflicts
-	podRegexp := fmt.Sprintf("%v-.*", app.Metadata.Workload)
-	podSelector := fmt.Sprintf("namespace=%q,pod=~%q", app.Metadata.Namespace, podRegexp)
-	selectors := QuerySelectors{
-		app.Metadata,
-		podSelector,
+	var buf bytes.Buffer
+	if err := containerPortsTemplate.Execute(&buf, selectors); err != nil {
+		return nil, fmt.Errorf("Error preparing query: %v\n", err)
+	}
+	query := buf.String()
+	result, warnings, err := promApi.Query(ctx, query, timeRange.End)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+	}
+	samples, ok := result.(model.Vector)
+	if !ok {
+		return warnings, fmt.Errorf("Query %q returned %T instead of Vector", query, result)
+	}
+	recordInstantSource(app, "container ports", query, timeRange.End, len(samples), warnings)
+	for _, sample := range samples {
+		index, ok := app.ContainerIndexByName(string(sample.Metric["container"]))
+		if !ok {
+			continue
+		}
+		port := string(sample.Metric["endpoint"])
+		if port == "" {
+			port = "scraped"
+		}
+		c := &app.Containers[index]
+		c.Ports = append(c.Ports, port)
+		sort.Strings(c.Ports)
 	}
+	return warnings, nil
+}
+
+// getContainersInfo returns the app's containers (or init containers) listed by the info query, with their images
+func getContainersInfo(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, label string) ([]appmodel.AppContainer, v1.Warnings, error) {
+	// set up query context with timeout
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
 
 	// prepare query string by injecting selector data into the provided query template
 	var buf bytes.Buffer
-	err := containerInfoTemplate.Execute(&buf, selectors)
+	err := queryTemplate.Execute(&buf, querySelectors)
 	if err != nil {
-		return nil, fmt.Errorf("Error preparing query: %v\n", err)
+		return nil, nil, fmt.Errorf("Error preparing query: %v\n", err)
 	}
 	query := buf.String()
 
 	// Collect values
 	result, warnings, err := promApi.Query(ctx, query, timeRange.End)
 	if err != nil {
-		return nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+		return nil, nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
 	}
 	if len(warnings) > 0 {
 		log.Warnf("Warnings: %v\n", warnings)
@@ -265,62 +379,124 @@ func collectContainersInfo(ctx context.Context, promApi v1.API, app *appmodel.Ap
 	// Parse results as a list of series
 	series, ok := result.(model.Vector)
 	if !ok {
-		return warnings, fmt.Errorf("Query %q returned %T instead of Vector; assuming no data", query, result)
-	}
-	if len(series) == 0 {
-		return warnings, nil
+		return nil, warnings, fmt.Errorf("Query %q returned %T instead of Vector; assuming no data", query, result)
 	}
+	recordInstantSource(app, label, query, timeRange.End, len(series), warnings)
+	var containers []appmodel.AppContainer
+	seen := make(map[string]bool)
 	for _, c := range series {
 		labels := c.Metric
 		name, ok := labels["container"]
-		if len(labels) != 1 || !ok {
-			return warnings, fmt.Errorf("Query %q returned labels %v, expected %v", query, labels, []string{"container"})
+		if len(labels) > 2 || !ok {
+			return nil, warnings, fmt.Errorf("Query %q returned labels %v, expected %v", query, labels, []string{"container", "image"})
 		}
-		container := appmodel.AppContainer{Name: string(name)}
+		if seen[string(name)] {
+			// image changed over time (e.g., during a rollout); keep the first one seen
+			log.Tracef("Application %v: container %v also runs image %v", app.Metadata, name, labels["image"])
+			continue
+		}
+		seen[string(name)] = true
+		container := appmodel.AppContainer{Name: string(name), Image: string(labels["image"])}
 		container.Cpu.Unit = "cores"
 		container.Memory.Unit = "bytes"
-		app.Containers = append(app.Containers, container)
+		container.EphemeralStorage.Unit = "bytes"
+		containers = append(containers, container)
+	}
+	return containers, warnings, nil
+}
+
+func collectContainersInfo(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range) (v1.Warnings, error) {
+	var allWarnings v1.Warnings
+
+	// --- Get container info
+
+	// prepare query selectors (TODO: refactor to a helper)
+	// Note: pod naming template is <deployment_name>-<pod_spec_hash>-<pod_unique_id>
+	//       TODO: tighten RE to avoid unlikelyconflicts
+	podRegexp := fmt.Sprintf("%v-.*", app.Metadata.Workload)
+	podSelector := fmt.Sprintf("namespace=%q,pod=~%q", app.Metadata.Namespace, podRegexp)
+	selectors := QuerySelectors{
+		app.Metadata,
+		podSelector,
+	}
+
+	containers, warnings, err := getContainersInfo(ctx, promApi, app, timeRange, containerInfoTemplate, &selectors, "containers")
+	if err != nil {
+		return warnings, err
 	}
+	if len(containers) == 0 {
+		return warnings, nil
+	}
+	app.Containers = append(app.Containers, containers...)
+
+	// Get init containers
+	app.InitContainers, warnings, err = getContainersInfo(ctx, promApi, app, timeRange, initContainerInfoTemplate, &selectors, "init containers")
+	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "init containers")
 
 	// Get restart counts
-	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerRestartsTemplate, &selectors, "", "RestartCount")
+	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerRestartsTemplate, &selectors, "", "RestartCount", "restart counts")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "restart counts")
 
+	// Get exposed ports
+	warnings, err = getContainersPorts(ctx, promApi, app, timeRange, &selectors)
+	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "container ports")
+
 	// --- Get resource specifications
 
 	// Get resource requests
-	warnings, err = getContainersResources(ctx, promApi, app, timeRange, containerResourceRequestsTemplate, &selectors, "Request")
+	warnings, err = getContainersResources(ctx, promApi, app, app.Containers, timeRange, containerResourceRequestsTemplate, &selectors, "Request", "resource requests")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "resource requests")
 
 	// Get resource limits
-	warnings, err = getContainersResources(ctx, promApi, app, timeRange, containerResourceLimitsTemplate, &selectors, "Limit")
+	warnings, err = getContainersResources(ctx, promApi, app, app.Containers, timeRange, containerResourceLimitsTemplate, &selectors, "Limit", "resource limits")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "resource limits")
 
+	// Get init container resource requests and limits
+	if len(app.InitContainers) > 0 {
+		warnings, err = getContainersResources(ctx, promApi, app, app.InitContainers, timeRange, initContainerResourceRequestsTemplate, &selectors, "Request", "init container resource requests")
+		allWarnings = handleWarnErr(allWarnings, warnings, err, app, "init container resource requests")
+		warnings, err = getContainersResources(ctx, promApi, app, app.InitContainers, timeRange, initContainerResourceLimitsTemplate, &selectors, "Limit", "init container resource limits")
+		allWarnings = handleWarnErr(allWarnings, warnings, err, app, "init container resource limits")
+	}
+
 	// --- Get usage metrics
 
 	// Get resource usage
-	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerCpuUseTemplate, &selectors, "Cpu", "Usage")
+	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerCpuUseTemplate, &selectors, "Cpu", "Usage", "CPU usage")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "CPU usage")
-	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerMemoryUseTemplate, &selectors, "Memory", "Usage")
+	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerMemoryUseTemplate, &selectors, "Memory", "Usage", "memory usage")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "memory usage")
+	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerEphemeralStorageUseTemplate, &selectors, "EphemeralStorage", "Usage", "ephemeral storage usage")
+	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "ephemeral storage usage")
+	warnings, err = getContainersUseByPod(ctx, promApi, app, timeRange, containerCpuUseByPodTemplate, &selectors, "Cpu", "CPU usage by pod")
+	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "CPU usage by pod")
+	warnings, err = getContainersUseByPod(ctx, promApi, app, timeRange, containerMemoryUseByPodTemplate, &selectors, "Memory", "memory usage by pod")
+	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "memory usage by pod")
 
 	// Get resource saturation
-	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerCpuSaturationTemplate, &selectors, "Cpu", "Saturation")
+	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerCpuSaturationTemplate, &selectors, "Cpu", "Saturation", "CPU saturation")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "CPU saturation")
-	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerMemorySaturationTemplate, &selectors, "Memory", "Saturation")
+	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerMemorySaturationTemplate, &selectors, "Memory", "Saturation", "memory saturation")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "memory saturation")
 
 	// Get CPU throttling stats
-	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerCpuSecondsThrottledTemplate, &selectors, "Cpu", "SecondsThrottled")
+	warnings, err = getContainersUse(ctx, promApi, app, timeRange, containerCpuSecondsThrottledTemplate, &selectors, "Cpu", "SecondsThrottled", "CPU throttling")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "CPU throttling")
 
 	// Get network traffic stats (pod-level, not container-level)
-	rxRate, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, containerRxPacketsTemplate, &selectors)
+	rxRate, rxSeries, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, containerRxPacketsTemplate, &selectors, "received packets rate")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "Received packets rate")
-	txRate, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, containerTxPacketsTemplate, &selectors)
+	txRate, _, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, containerTxPacketsTemplate, &selectors, "transmitted packets rate")
 	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "Transmitted packets rate")
 	if rxRate != nil {
 		app.Metrics.PacketReceiveRate = opsmath.MagicRound(*rxRate)
+		app.Series.RequestRate = rxSeries
+	}
+	rxSeriesByPod, query, warnings, err := getSeriesByPod(ctx, promApi, timeRange, podRxPacketsTemplate, &selectors)
+	allWarnings = handleWarnErr(allWarnings, warnings, err, app, "received packets rate by pod")
+	if err == nil {
+		app.Series.RequestRateByPod = rxSeriesByPod[""]
+		recordSource(app, "received packets rate by pod", "", query, timeRange, len(app.Series.RequestRateByPod), nil, warnings)
 	}
 	if txRate != nil {
 		app.Metrics.PacketTransmitRate = opsmath.MagicRound(*txRate)
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	//"net/http"
@@ -83,7 +84,7 @@ func collectNamespaces(ctx context.Context, promApi v1.API, timeRange v1.Range)
 
 func getAggregateMetric(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, metric string, aggrFunc string) (*float64, v1.Warnings, error) {
 	// set up query context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	// prepare query string
@@ -106,6 +107,9 @@ func getAggregateMetric(ctx context.Context, promApi v1.API, app *appmodel.App,
 	if !ok {
 		return nil, warnings, fmt.Errorf("Query %q returned %T instead of Matrix; assuming no data", query, result)
 	}
+	if len(series) != 1 {
+		recordSource(app, metric, "", query, timeRange, len(series), nil, warnings)
+	}
 	if len(series) == 0 {
 		return nil, warnings, nil
 	}
@@ -118,6 +122,8 @@ func getAggregateMetric(ctx context.Context, promApi v1.API, app *appmodel.App,
 		return nil, warnings, fmt.Errorf("Query %q returned non-empty labels (%v) for the single series; treating as if no data", query, series[0].Metric)
 	}
 
+	recordSource(app, metric, "", query, timeRange, len(series), series[0].Values, warnings)
+
 	// Aggregate across returned values
 	values := []float64{}
 	for _, v := range series[0].Values {
@@ -136,23 +142,24 @@ func getAggregateMetric(ctx context.Context, promApi v1.API, app *appmodel.App,
 	return &value, warnings, nil
 }
 
-func getRangedMetric(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors) (*float64, v1.Warnings, error) {
+// getRangedMetric returns the average of a single-series query over the time range, as well as the series itself
+func getRangedMetric(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range, queryTemplate *template.Template, querySelectors *QuerySelectors, label string) (*float64, appmodel.TimeSeries, v1.Warnings, error) {
 	// set up query context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	// prepare query string by injecting selector data into the provided query template
 	var buf bytes.Buffer
 	err := queryTemplate.Execute(&buf, querySelectors)
 	if err != nil {
-		return nil, nil, fmt.Errorf("Error preparing query: %v\n", err)
+		return nil, nil, nil, fmt.Errorf("Error preparing query: %v\n", err)
 	}
 	query := buf.String()
 
 	// Collect values
 	result, warnings, err := promApi.QueryRange(ctx, query, timeRange)
 	if err != nil {
-		return nil, nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+		return nil, nil, nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
 	}
 	if len(warnings) > 0 {
 		log.Warnf("Warnings: %v\n", warnings)
@@ -163,21 +170,26 @@ func getRangedMetric(ctx context.Context, promApi v1.API, app *appmodel.App, tim
 	// Parse results as a list of series
 	series, ok := result.(model.Matrix)
 	if !ok {
-		return nil, warnings, fmt.Errorf("Query %q returned %T instead of Matrix; assuming no data", query, result)
+		return nil, nil, warnings, fmt.Errorf("Query %q returned %T instead of Matrix; assuming no data", query, result)
+	}
+	if len(series) != 1 {
+		recordSource(app, label, "", query, timeRange, len(series), nil, warnings)
 	}
 	if len(series) == 0 {
-		return nil, warnings, nil
+		return nil, nil, warnings, nil
 	}
 	if len(series) != 1 {
-		return nil, warnings, fmt.Errorf("Query %q returned %v instead of a single series (%v); treating as if no data", query, len(series), series)
+		return nil, nil, warnings, fmt.Errorf("Query %q returned %v instead of a single series (%v); treating as if no data", query, len(series), series)
 	}
 
 	// evaluate the response by series label names -- here it should be empty
 	// TODO: some queries result in no labels (e.g., cpu utilization) but others have them (e.g., replica count)
 	//if len(series[0].Metric) != 0 {
-	//	return nil, warnings, fmt.Errorf("Query %q returned non-empty labels (%v) for the single series; treating as if no data", query, series[0].Metric)
+	//	return nil, nil, warnings, fmt.Errorf("Query %q returned non-empty labels (%v) for the single series; treating as if no data", query, series[0].Metric)
 	//}
 
+	recordSource(app, label, "", query, timeRange, len(series), series[0].Values, warnings)
+
 	// Aggregate across returned values
 	values := []float64{}
 	for _, v := range series[0].Values {
@@ -185,7 +197,121 @@ func getRangedMetric(ctx context.Context, promApi v1.API, app *appmodel.App, tim
 	}
 	value := opsmath.Avg(values...) // prepared for other aggregations
 
-	return &value, warnings, nil
+	return &value, seriesFromSamplePairs(series[0].Values), warnings, nil
+}
+
+// getNamespaceLabels returns the labels of the app's namespace, as exported by kube-state-metrics (label_xxx labels)
+func getNamespaceLabels(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range) (map[string]string, v1.Warnings, error) {
+	// set up query context with timeout
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf("kube_namespace_labels{namespace=%q}", app.Metadata.Namespace)
+	result, warnings, err := promApi.Query(ctx, query, timeRange.End)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+	}
+	samples, ok := result.(model.Vector)
+	if !ok {
+		return nil, warnings, fmt.Errorf("Query %q returned %T instead of Vector", query, result)
+	}
+	recordInstantSource(app, "namespace labels", query, timeRange.End, len(samples), warnings)
+
+	labels := make(map[string]string)
+	for _, sample := range samples {
+		for name, value := range sample.Metric {
+			if strings.HasPrefix(string(name), "label_") {
+				labels[strings.TrimPrefix(string(name), "label_")] = string(value)
+			}
+		}
+	}
+	return labels, warnings, nil
+}
+
+// getWorkloadAnnotations returns the annotations of the app's workload, as exported by kube-state-metrics
+// (annotation_xxx labels of kube_<kind>_annotations, for the annotations allowed by --metric-annotations-allowlist)
+func getWorkloadAnnotations(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range) (map[string]string, v1.Warnings, error) {
+	// set up query context with timeout
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	kind := strings.ToLower(app.Metadata.WorkloadKind)
+	query := fmt.Sprintf("kube_%v_annotations{namespace=%q,%v=%q}", kind, app.Metadata.Namespace, kind, app.Metadata.Workload)
+	result, warnings, err := promApi.Query(ctx, query, timeRange.End)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+	}
+	samples, ok := result.(model.Vector)
+	if !ok {
+		return nil, warnings, fmt.Errorf("Query %q returned %T instead of Vector", query, result)
+	}
+	recordInstantSource(app, "workload annotations", query, timeRange.End, len(samples), warnings)
+
+	annotations := make(map[string]string)
+	for _, sample := range samples {
+		for name, value := range sample.Metric {
+			if strings.HasPrefix(string(name), "annotation_") {
+				annotations[strings.TrimPrefix(string(name), "annotation_")] = string(value)
+			}
+		}
+	}
+	return annotations, warnings, nil
+}
+
+// getHpaSettings looks up the horizontal pod autoscaler targeting the app's workload and fills in its
+// settings, as exported by kube-state-metrics v2 (kube_horizontalpodautoscaler_xxx)
+func getHpaSettings(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range) (v1.Warnings, error) {
+	// set up query context with timeout
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// find the HPA by its scale target
+	query := fmt.Sprintf("kube_horizontalpodautoscaler_info{namespace=%q,scaletargetref_kind=%q,scaletargetref_name=%q}",
+		app.Metadata.Namespace, app.Metadata.WorkloadKind, app.Metadata.Workload)
+	result, warnings, err := promApi.Query(ctx, query, timeRange.End)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+	}
+	samples, ok := result.(model.Vector)
+	if !ok {
+		return warnings, fmt.Errorf("Query %q returned %T instead of Vector", query, result)
+	}
+	recordInstantSource(app, "HPA info", query, timeRange.End, len(samples), warnings)
+	if len(samples) == 0 {
+		return warnings, nil
+	}
+	if len(samples) > 1 {
+		log.Warnf("Application %v has %v HPAs; using %v", app.Metadata, len(samples), samples[0].Metric["horizontalpodautoscaler"])
+	}
+	app.Settings.HpaEnabled = true
+	app.Settings.HpaName = string(samples[0].Metric["horizontalpodautoscaler"])
+
+	// collect its replica bounds and CPU utilization target
+	query = fmt.Sprintf("{__name__=~\"kube_horizontalpodautoscaler_spec_(min_replicas|max_replicas|target_metric)\",namespace=%q,horizontalpodautoscaler=%q}",
+		app.Metadata.Namespace, app.Settings.HpaName)
+	result, moreWarnings, err := promApi.Query(ctx, query, timeRange.End)
+	warnings = append(warnings, moreWarnings...)
+	if err != nil {
+		return warnings, fmt.Errorf("Error querying Prometheus for %q: %v\n", query, err)
+	}
+	samples, ok = result.(model.Vector)
+	if !ok {
+		return warnings, fmt.Errorf("Query %q returned %T instead of Vector", query, result)
+	}
+	recordInstantSource(app, "HPA settings", query, timeRange.End, len(samples), moreWarnings)
+	for _, sample := range samples {
+		switch sample.Metric[model.MetricNameLabel] {
+		case "kube_horizontalpodautoscaler_spec_min_replicas":
+			app.Settings.HpaMinReplicas = int(sample.Value)
+		case "kube_horizontalpodautoscaler_spec_max_replicas":
+			app.Settings.HpaMaxReplicas = int(sample.Value)
+		case "kube_horizontalpodautoscaler_spec_target_metric":
+			if sample.Metric["metric_name"] == "cpu" && sample.Metric["metric_target_type"] == "utilization" {
+				app.Settings.HpaCpuTarget = float64(sample.Value)
+			}
+		}
+	}
+	return warnings, nil
 }
 
 func collectDeploymentDetails(ctx context.Context, promApi v1.API, app *appmodel.App, timeRange v1.Range) (v1.Warnings, error) {
@@ -216,8 +342,47 @@ func collectDeploymentDetails(ctx context.Context, promApi v1.API, app *appmodel
 		}
 	}
 
+	// collect namespace labels
+	nsLabels, warnings, err := getNamespaceLabels(ctx, promApi, app, timeRange)
+	if err != nil {
+		log.Errorf("Error querying Prometheus for namespace labels %v: %v\n", app.Metadata, err)
+	} else {
+		if len(warnings) > 0 {
+			allWarnings = append(allWarnings, warnings...)
+			log.Warnf("Warnings during namespace labels collection: %v\n", warnings)
+		}
+		if len(nsLabels) > 0 {
+			app.Metadata.NamespaceLabels = nsLabels
+		}
+	}
+
+	// collect workload annotations
+	annotations, warnings, err := getWorkloadAnnotations(ctx, promApi, app, timeRange)
+	if err != nil {
+		log.Errorf("Error querying Prometheus for workload annotations %v: %v\n", app.Metadata, err)
+	} else {
+		if len(warnings) > 0 {
+			allWarnings = append(allWarnings, warnings...)
+			log.Warnf("Warnings during workload annotations collection: %v\n", warnings)
+		}
+		if len(annotations) > 0 {
+			app.Metadata.Annotations = annotations
+		}
+	}
+
+	// collect autoscaler settings
+	warnings, err = getHpaSettings(ctx, promApi, app, timeRange)
+	if err != nil {
+		log.Errorf("Error querying Prometheus for HPA settings %v: %v\n", app.Metadata, err)
+	} else {
+		if len(warnings) > 0 {
+			allWarnings = append(allWarnings, warnings...)
+			log.Warnf("Warnings during HPA settings collection: %v\n", warnings)
+		}
+	}
+
 	// collect replicas
-	replicas, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, replicaCountTemplate, &selectors)
+	replicas, replicaSeries, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, replicaCountTemplate, &selectors, "replica count")
 	if err != nil {
 		log.Errorf("Error querying Prometheus for replica count %v: %v\n", app.Metadata, err)
 	} else {
@@ -227,6 +392,7 @@ func collectDeploymentDetails(ctx context.Context, promApi v1.API, app *appmodel
 		}
 		if replicas != nil {
 			app.Metrics.AverageReplicas = *replicas
+			app.Series.Replicas = replicaSeries
 		}
 	}
 
@@ -242,7 +408,7 @@ func collectDeploymentDetails(ctx context.Context, promApi v1.API, app *appmodel
 	}
 
 	// collect usage
-	cpuUsed, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, cpuUtilizationTemplate, &selectors)
+	cpuUsed, _, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, cpuUtilizationTemplate, &selectors, "CPU utilization")
 	if err != nil {
 		log.Errorf("Error querying Prometheus for CPU utilization %v: %v\n", app.Metadata, err)
 	} else {
@@ -254,7 +420,7 @@ func collectDeploymentDetails(ctx context.Context, promApi v1.API, app *appmodel
 			app.Metrics.CpuUtilization = *cpuUsed
 		}
 	}
-	memoryUsed, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, memoryUtilizationTemplate, &selectors)
+	memoryUsed, _, warnings, err := getRangedMetric(ctx, promApi, app, timeRange, memoryUtilizationTemplate, &selectors, "memory utilization")
 	if err != nil {
 		log.Errorf("Error querying Prometheus for memory utilization %v: %v\n", app.Metadata, err)
 	} else {
@@ -460,7 +626,7 @@ func PromGetAll(
 			progressCallback(log.ProgressInfo{WorkloadsTotal: 1}, true)
 		}
 		apps = []*appmodel.App{
-			collectSingleApp(ctx, promApi, namespace, timeRange, workload, workloadKind, workloadApiVersion),
+			collectSingleApp(ctx, promApi, namespace, timeRange, workload, workloadApiVersion, workloadKind),
 		}
 		if progressCallback != nil {
 			progressCallback(log.ProgressInfo{NamespacesDone: 1, WorkloadsDone: 1}, true)
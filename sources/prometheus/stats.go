@@ -9,25 +9,17 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"time"
 
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 
+	appmodel "opsani-ignite/app/model"
 	"opsani-ignite/log"
 	opsmath "opsani-ignite/math"
 )
 
-type ValueStats struct {
-	N       int
-	Min     float64
-	Max     float64
-	Sum     float64
-	Average float64
-	Median  float64
-	StDev   float64
-}
-
-func calcSamplePairStats(samples []model.SamplePair) (res ValueStats) {
+func calcSamplePairStats(samples []model.SamplePair) (res appmodel.ValueStats) {
 	// handle the no-data case (Min, Max are not valid if N==0)
 	if len(samples) == 0 {
 		return
@@ -66,7 +58,10 @@ func calcSamplePairStats(samples []model.SamplePair) (res ValueStats) {
 		res.Median = (values[res.N/2-1] + values[res.N/2]) / 2
 	}
 
-	// compute standard deviation
+	// compute standard deviation (sample stdev, undefined for a single sample: report 0)
+	if res.N < 2 {
+		return
+	}
 	acc := 0.0
 	for _, v := range values {
 		acc += math.Pow(v-res.Average, 2)
@@ -103,3 +98,36 @@ func valueFromSamplePairs(samples []model.SamplePair, logLabel string) (float64,
 
 	return v, warnings, nil
 }
+
+func seriesFromSamplePairs(samples []model.SamplePair) appmodel.TimeSeries {
+	series := make(appmodel.TimeSeries, 0, len(samples))
+	for _, s := range samples {
+		series = append(series, appmodel.Sample{Time: s.Timestamp.Time(), Value: float64(s.Value)})
+	}
+	return series
+}
+
+// recordSource adds the provenance of a metric collected by a range query to the app; samples and
+// container are for the series the app's value is computed from, if any
+func recordSource(app *appmodel.App, metric string, container string, query string, timeRange v1.Range, seriesCount int, samples []model.SamplePair, warnings v1.Warnings) {
+	source := appmodel.MetricSource{
+		Metric:    metric,
+		Container: container,
+		Query:     query,
+		Start:     timeRange.Start,
+		End:       timeRange.End,
+		Step:      timeRange.Step,
+		Series:    seriesCount,
+		Warnings:  append([]string{}, warnings...),
+	}
+	if len(samples) > 0 {
+		stats := calcSamplePairStats(samples)
+		source.Stats = &stats
+	}
+	app.Sources = append(app.Sources, source)
+}
+
+// recordInstantSource adds the provenance of a metric collected by an instant query to the app
+func recordInstantSource(app *appmodel.App, metric string, query string, at time.Time, valueCount int, warnings v1.Warnings) {
+	recordSource(app, metric, "", query, v1.Range{Start: at, End: at}, valueCount, nil, warnings)
+}
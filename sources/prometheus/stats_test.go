@@ -0,0 +1,23 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/common/model"
+
+	appmodel "opsani-ignite/app/model"
+)
+
+func TestCalcSamplePairStatsSingleSample(t *testing.T) {
+	stats := calcSamplePairStats([]model.SamplePair{{Timestamp: 1000, Value: 0.25}})
+	if stats.N != 1 || stats.Average != 0.25 || stats.Median != 0.25 || stats.StDev != 0 {
+		t.Errorf("unexpected stats for a single sample: %+v", stats)
+	}
+
+	// the stats are serialized with the app, e.g., by the REST API
+	app := appmodel.App{Sources: []appmodel.MetricSource{{Metric: "cpu", Stats: &stats}}}
+	if _, err := json.Marshal(app); err != nil {
+		t.Errorf("failed to encode app with single-sample stats: %v", err)
+	}
+}
@@ -9,15 +9,23 @@ var containerRestartsTemplate *template.Template
 var cpuUtilizationTemplate *template.Template
 var memoryUtilizationTemplate *template.Template
 var containerInfoTemplate *template.Template
+var containerPortsTemplate *template.Template
+var initContainerInfoTemplate *template.Template
+var initContainerResourceRequestsTemplate *template.Template
+var initContainerResourceLimitsTemplate *template.Template
+var containerEphemeralStorageUseTemplate *template.Template
 var containerResourceRequestsTemplate *template.Template
 var containerResourceLimitsTemplate *template.Template
 var containerCpuUseTemplate *template.Template
+var containerCpuUseByPodTemplate *template.Template
 var containerMemoryUseTemplate *template.Template
+var containerMemoryUseByPodTemplate *template.Template
 var containerCpuSaturationTemplate *template.Template
 var containerMemorySaturationTemplate *template.Template
 var containerCpuSecondsThrottledTemplate *template.Template
 var containerRxPacketsTemplate *template.Template
 var containerTxPacketsTemplate *template.Template
+var podRxPacketsTemplate *template.Template
 
 // Useful References:
 //
@@ -47,17 +55,35 @@ func initializeTemplates() {
 
 	// container info & settings
 	containerInfoTemplate = template.Must(template.New("prometheusContainerInfo").Parse(
-		`sum by (container) (kube_pod_container_info{ {{ .PodSelector }} })`))
+		`sum by (container, image) (kube_pod_container_info{ {{ .PodSelector }} })`))
+	containerPortsTemplate = template.Must(template.New("prometheusContainerPorts").Parse(
+		`count by (container, endpoint) (up{ {{ .PodSelector }},container!="" })`))
 	containerResourceRequestsTemplate = template.Must(template.New("prometheusContainerResourceRequests").Parse(
 		`avg by (container, resource) (kube_pod_container_resource_requests{ {{ .PodSelector }} })`))
 	containerResourceLimitsTemplate = template.Must(template.New("prometheusContainerResourceLimits").Parse(
 		`avg by (container, resource) (kube_pod_container_resource_limits{ {{ .PodSelector }} })`))
 
+	// init container info & settings
+	initContainerInfoTemplate = template.Must(template.New("prometheusInitContainerInfo").Parse(
+		`sum by (container, image) (kube_pod_init_container_info{ {{ .PodSelector }} })`))
+	initContainerResourceRequestsTemplate = template.Must(template.New("prometheusInitContainerResourceRequests").Parse(
+		`avg by (container, resource) (kube_pod_init_container_resource_requests{ {{ .PodSelector }} })`))
+	initContainerResourceLimitsTemplate = template.Must(template.New("prometheusInitContainerResourceLimits").Parse(
+		`avg by (container, resource) (kube_pod_init_container_resource_limits{ {{ .PodSelector }} })`))
+
 	// container use
 	containerCpuUseTemplate = template.Must(template.New("prometheusContainerCpuUseTemplate").Parse(
 		`avg by (container) (rate(container_cpu_usage_seconds_total{ {{ .PodSelector }} }[5m]))`))
+	containerCpuUseByPodTemplate = template.Must(template.New("prometheusContainerCpuUseByPodTemplate").Parse(
+		`sum by (pod, container) (rate(container_cpu_usage_seconds_total{ {{ .PodSelector }},container!~"|POD" }[5m]))`))
 	containerMemoryUseTemplate = template.Must(template.New("prometheusContainerMemoryUseTemplate").Parse(
 		`avg by (container) (container_memory_working_set_bytes{ {{ .PodSelector }} })`))
+	containerMemoryUseByPodTemplate = template.Must(template.New("prometheusContainerMemoryUseByPodTemplate").Parse(
+		`max by (pod, container) (container_memory_working_set_bytes{ {{ .PodSelector }},container!~"|POD" })`))
+
+	// ephemeral storage use of the fullest pod (writable layer; eviction is per pod)
+	containerEphemeralStorageUseTemplate = template.Must(template.New("prometheusContainerEphemeralStorageUseTemplate").Parse(
+		`max by (container) (container_fs_usage_bytes{ {{ .PodSelector }},container!~"|POD" })`))
 
 	// container utilization
 	containerCpuSaturationTemplate = template.Must(template.New("prometheusContainerCpuSaturationTemplate").Parse(
@@ -80,5 +106,7 @@ func initializeTemplates() {
 		`avg (rate(container_network_receive_packets_total{ {{ .PodSelector }} }[5m]))`))
 	containerTxPacketsTemplate = template.Must(template.New("prometheusContainerTxPacketsTemplate").Parse(
 		`avg (rate(container_network_transmit_packets_total{ {{ .PodSelector }} }[5m]))`))
+	podRxPacketsTemplate = template.Must(template.New("prometheusPodRxPacketsTemplate").Parse(
+		`sum by (pod) (rate(container_network_receive_packets_total{ {{ .PodSelector }} }[5m]))`))
 
 }